@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
@@ -54,6 +55,22 @@ func (c *TaskCache) Set(path string, tasks []*Task) {
 	c.files[path] = &CachedFile{ModTime: info.ModTime(), Tasks: tasks}
 }
 
+// Changed lists the cached files modified or removed since they were cached
+func (c *TaskCache) Changed() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var changed []string
+	for path, cached := range c.files {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cached.ModTime) {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
 // Invalidate removes a file from the cache
 func (c *TaskCache) Invalidate(path string) {
 	c.mu.Lock()
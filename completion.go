@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnknownShell is returned for completion scripts of unsupported shells
+var ErrUnknownShell = errors.New("unknown shell (supported: bash, zsh)")
+
+// writeProfileNames prints one profile name per line for --complete profiles
+func writeProfileNames(cfg Config, w io.Writer) {
+	for _, name := range profileNames(cfg) {
+		fmt.Fprintln(w, name)
+	}
+}
+
+// completionFlags lists the flags of fs as "--name" for completion scripts,
+// leaving out the hidden --complete
+func completionFlags(fs *flag.FlagSet) string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "complete" {
+			names = append(names, "--"+f.Name)
+		}
+	})
+	return strings.Join(names, " ")
+}
+
+// completionScript returns the completion script for shell. Profile names
+// are looked up at completion time with ot --complete profiles.
+func completionScript(shell string, fs *flag.FlagSet) (string, error) {
+	flags := completionFlags(fs)
+
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(`# ot bash completion, load with: source <(ot completion bash)
+_ot() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+	case "$prev" in
+	--profile|-profile)
+		COMPREPLY=($(compgen -W "$(ot --complete profiles 2>/dev/null)" -- "$cur"))
+		return
+		;;
+	--editor|-editor)
+		COMPREPLY=($(compgen -W "inline external" -- "$cur"))
+		return
+		;;
+	esac
+
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+
+	COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o filenames -F _ot ot
+`, flags), nil
+	case "zsh":
+		return fmt.Sprintf(`#compdef ot
+# ot zsh completion, load with: source <(ot completion zsh)
+_ot() {
+	case "$words[CURRENT-1]" in
+	--profile|-profile)
+		local -a profiles
+		profiles=(${(f)"$(ot --complete profiles 2>/dev/null)"})
+		compadd -a profiles
+		return
+		;;
+	--editor|-editor)
+		compadd inline external
+		return
+		;;
+	esac
+
+	if [[ "$PREFIX" == -* ]]; then
+		compadd -- %s
+		return
+	fi
+
+	_files
+}
+compdef _ot ot
+`, flags), nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnknownShell, shell)
+}
@@ -3,25 +3,52 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/BurntSushi/toml"
 )
 
 type Config struct {
-	DefaultProfile string             `toml:"default_profile"`
-	Profiles       map[string]Profile `toml:"profiles"`
-	Tabs           bool               `toml:"tabs"`
-	Theme          string             `toml:"theme"`
-	baseDir        string             // Directory containing the config file (not serialized)
+	DefaultProfile  string             `toml:"default_profile"`
+	DefaultQuery    string             `toml:"default_query"`
+	Profiles        map[string]Profile `toml:"profiles"`
+	Tabs            bool               `toml:"tabs"`
+	Theme           string             `toml:"theme"`
+	Palette         string             `toml:"palette"`
+	Short           bool               `toml:"short"`
+	Wrap            bool               `toml:"wrap"`
+	Keybindings     map[string]string  `toml:"keybindings"`
+	InboxFile       string             `toml:"inbox_file"`
+	FollowSymlinks  bool               `toml:"follow_symlinks"`
+	AbsolutePaths   bool               `toml:"show_absolute_paths"`
+	StatusBar       bool               `toml:"status_bar"`
+	DateFormat      string             `toml:"date_format"`
+	AddCreatedDate  bool               `toml:"add_created_date"`
+	QueryBlock      string             `toml:"query_block"`
+	CheckboxTodo    string             `toml:"checkbox_todo"`
+	CheckboxDone    string             `toml:"checkbox_done"`
+	ConfirmDelete   *bool              `toml:"confirm_delete"` // Unset means true
+	Extensions      []string           `toml:"extensions"`
+	DailyNoteFormat string             `toml:"daily_note_format"`
+	DailyNoteHeader string             `toml:"daily_note_header"`
+	RefreshDebounce int                `toml:"refresh_debounce_ms"`
+	UppercaseDone   bool               `toml:"uppercase_done"`
+	PollInterval    int                `toml:"poll_interval"`
+	SectionOrder    []string           `toml:"section_order"`
+	baseDir         string             // Directory containing the config file (not serialized)
 }
 
 type Profile struct {
 	Vault  string `toml:"vault"`
 	Query  string `toml:"query"`
 	Editor string `toml:"editor"`
+	Group  string `toml:"group"`
+	Sort   string `toml:"sort"`
 }
 
 type ResolvedProfile struct {
@@ -30,6 +57,8 @@ type ResolvedProfile struct {
 	Query       string
 	QueryIsFile bool
 	EditorMode  string
+	Group       string
+	Sort        string
 }
 
 type ProfileError struct {
@@ -60,6 +89,21 @@ var (
 	ErrNotDirectory = errors.New("path is not a directory")
 )
 
+// ErrInvalidEditor is returned for editor modes other than inline or external
+var ErrInvalidEditor = errors.New(`editor must be "inline" or "external"`)
+
+// resolveEditorMode gives the --editor value precedence over the profile's
+// editor, rejecting unknown values
+func resolveEditorMode(flagValue, profileValue string) (string, error) {
+	if flagValue == "" {
+		return profileValue, nil
+	}
+	if flagValue != "inline" && flagValue != "external" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEditor, flagValue)
+	}
+	return flagValue, nil
+}
+
 func validateProfile(name string, p Profile) error {
 	if strings.TrimSpace(p.Vault) == "" {
 		return &ProfileError{Profile: name, Field: "vault", Err: ErrEmptyPath}
@@ -97,6 +141,106 @@ func validateConfig(cfg Config) error {
 	return nil
 }
 
+// checkProfile resolves a profile and parses its query the way a run would,
+// with blocks fenced with label, returning the first problem found
+func checkProfile(name string, p Profile, baseDir, label string) error {
+	resolved, err := resolveProfilePaths(name, p, baseDir)
+	if err != nil {
+		return err
+	}
+
+	if resolved.QueryIsFile {
+		if _, err := parseAllQueryBlocks(resolved.Query, label); err != nil {
+			return &ProfileError{Profile: name, Field: "query", Err: err}
+		}
+	} else if strings.HasSuffix(resolved.Query, ".md") {
+		// Looks like a query file but didn't resolve to one
+		return &ProfileError{Profile: name, Field: "query", Err: fmt.Errorf("%w: %s", ErrPathNotExist, resolved.Query)}
+	}
+
+	return nil
+}
+
+// checkConfig validates the config and every profile in it, writing an
+// OK/FAIL line per profile to w. It reports whether everything passed.
+func checkConfig(cfg Config, w io.Writer) bool {
+	ok := true
+
+	if err := validateConfig(cfg); err != nil {
+		fmt.Fprintf(w, "FAIL  %v\n", err)
+		ok = false
+	}
+
+	if err := checkCheckboxGlyphs(cfg.CheckboxTodo, cfg.CheckboxDone); err != nil {
+		fmt.Fprintf(w, "WARN  %v\n", err)
+	}
+
+	if _, err := refreshDebounce(cfg.RefreshDebounce); err != nil {
+		fmt.Fprintf(w, "WARN  %v\n", err)
+	}
+
+	for _, conflict := range keybindingConflicts(cfg.Keybindings) {
+		fmt.Fprintf(w, "WARN  %s\n", conflict)
+	}
+
+	if _, err := pollInterval(cfg.PollInterval); err != nil {
+		fmt.Fprintf(w, "WARN  %v\n", err)
+	}
+
+	if len(cfg.Profiles) == 0 {
+		fmt.Fprintln(w, "no profiles defined")
+		return ok
+	}
+
+	names := profileNames(cfg)
+
+	for _, name := range names {
+		if err := checkProfile(name, cfg.Profiles[name], cfg.baseDir, cfg.QueryBlock); err != nil {
+			fmt.Fprintf(w, "FAIL  %v\n", err)
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "OK    %s\n", name)
+	}
+
+	return ok
+}
+
+// profileNames returns the configured profile names in alphabetical order
+func profileNames(cfg Config) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listProfiles writes one line per profile with its resolved vault, marking
+// the default with "*" and flagging vaults that don't exist
+func listProfiles(cfg Config, w io.Writer) {
+	names := profileNames(cfg)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, name := range names {
+		marker := " "
+		if name == cfg.DefaultProfile {
+			marker = "*"
+		}
+
+		vault, err := resolveVaultPath(cfg.Profiles[name].Vault, cfg.baseDir)
+		if err != nil || validateVaultExists(name, vault) != nil {
+			fmt.Fprintf(tw, "%s %s\t%s\t(missing)\n", marker, name, vault)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s %s\t%s\n", marker, name, vault)
+	}
+
+	tw.Flush()
+}
+
 func selectProfile(profileFlag string, cfg Config) (string, *Profile, error) {
 	if profileFlag != "" {
 		if cfg.Profiles == nil {
@@ -168,7 +312,15 @@ func resolveProfilePaths(name string, p Profile, baseDir string) (*ResolvedProfi
 		// If not a file, query remains as inline query string
 	}
 
-	return &ResolvedProfile{Name: name, VaultPath: vaultPath, Query: query, QueryIsFile: queryIsFile, EditorMode: p.Editor}, nil
+	return &ResolvedProfile{
+		Name:        name,
+		VaultPath:   vaultPath,
+		Query:       query,
+		QueryIsFile: queryIsFile,
+		EditorMode:  p.Editor,
+		Group:       strings.TrimSpace(p.Group),
+		Sort:        strings.TrimSpace(p.Sort),
+	}, nil
 }
 
 func configPath() (string, error) {
@@ -183,6 +335,62 @@ func configPath() (string, error) {
 	return filepath.Join(configDir, "ot", "config.toml"), nil
 }
 
+// ErrConfigExists is returned by initConfig when it would overwrite a config
+var ErrConfigExists = errors.New("config already exists")
+
+const configTemplate = `# ot configuration
+# See https://github.com/elcuervo/ot for all options.
+
+default_profile = "notes"
+# default_query = "not done\nsort by due"  # For profiles without a query
+# tabs = true                  # Show every profile as a tab
+# theme = "dracula"            # Glamour theme
+# palette = "default"          # UI colors: "default" or "colorblind"
+# short = false                # Compact lines without file:line
+# wrap = false                 # Soft-wrap long task lines
+# inbox_file = "inbox.md"      # Default file for new tasks (N)
+# follow_symlinks = false      # Scan symlinked directories in vaults
+# show_absolute_paths = false  # Full paths in (file:line) and --list
+# status_bar = false           # Show the selected task's path above the help line
+# date_format = "2006-01-02"   # Go layout or iso, datetime, rfc3339, us, eu
+# add_created_date = false     # Stamp new tasks with ➕ and today's date
+# query_block = "tasks"        # Fence label of query blocks, e.g. "dataview"
+# checkbox_todo = "☐"          # Display glyphs for open and done tasks,
+# checkbox_done = "☑"          # of equal width (files keep [ ] and [x])
+# uppercase_done = false       # Write done tasks as [X] instead of [x]
+# confirm_delete = true        # false: d deletes at once, u undoes it
+# extensions = [".md"]         # File types scanned for tasks, e.g. ".markdown"
+# daily_note_format = "Daily/{YYYY}-{MM}-{DD}.md"  # Target of D, in the vault
+# daily_note_header = "# {YYYY}-{MM}-{DD}"        # First line of new daily notes
+# refresh_debounce_ms = 300    # Wait for file changes to settle; raise on network drives
+# poll_interval = 0            # Seconds between checks for changes the watcher misses
+# section_order = ["Overdue"]  # Sections shown first, in this order
+
+# [keybindings]                # Comma-separated keys per action
+# down = "j,down"
+
+[profiles.notes]
+vault = "~/notes"              # Directory (or file) to scan for tasks
+query = "not done"             # Inline query or path to a query file
+editor = "inline"              # "inline" or "external"
+# group = "folder"             # Group by for query blocks without their own
+# sort = "due"                 # Sort by for query blocks without their own
+`
+
+// initConfig writes a commented config template to path, creating parent
+// directories. An existing file is only replaced when force is set.
+func initConfig(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%w: %s (use --force to overwrite)", ErrConfigExists, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(configTemplate), 0644)
+}
+
 func loadConfig() (Config, string, error) {
 	return loadConfigFrom("")
 }
@@ -223,6 +431,10 @@ func loadConfigFrom(customPath string) (Config, string, error) {
 		return Config{}, path, err
 	}
 
+	if err := validateKeybindings(cfg.Keybindings); err != nil {
+		return Config{}, path, err
+	}
+
 	// Store the config file's directory for resolving relative paths
 	cfg.baseDir = filepath.Dir(path)
 
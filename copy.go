@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrNoClipboard is returned when no clipboard utility is available
+var ErrNoClipboard = errors.New("clipboard is not available (install pbcopy, xclip, xsel or wl-clipboard)")
+
+// copyOption is an entry of the Y copy menu
+type copyOption struct {
+	key    string
+	label  string
+	format func(task *Task, vaultPath string) string
+}
+
+// copyOptions are the copy menu entries, selected by their key
+var copyOptions = []copyOption{
+	{"d", "description", func(task *Task, _ string) string { return task.Description }},
+	{"f", "file:line", copyFileLine},
+	{"m", "markdown link", copyMarkdownLink},
+	{"o", "obsidian:// URL", copyObsidianURL},
+	{"c", "markdown checkbox", func(task *Task, _ string) string { return toMarkdown(task, false) }},
+}
+
+// copyFinishedMsg reports the result of writing to the clipboard
+type copyFinishedMsg struct {
+	err error
+}
+
+// copyFileLine is the task location relative to the vault
+func copyFileLine(task *Task, vaultPath string) string {
+	return fmt.Sprintf("%s:%d", relPath(vaultPath, task.FilePath), task.LineNumber)
+}
+
+// copyMarkdownLink links to the task's note, titled by its heading or name
+func copyMarkdownLink(task *Task, vaultPath string) string {
+	rel := filepath.ToSlash(relPath(vaultPath, task.FilePath))
+
+	title := task.NoteTitle
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	}
+
+	return fmt.Sprintf("[%s](%s)", title, strings.ReplaceAll(rel, " ", "%20"))
+}
+
+// copyObsidianURL opens the task's note in Obsidian, naming the vault after
+// its folder
+func copyObsidianURL(task *Task, vaultPath string) string {
+	rel := filepath.ToSlash(relPath(vaultPath, task.FilePath))
+	rel = strings.TrimSuffix(rel, ".md")
+	vault := filepath.Base(filepath.Clean(vaultPath))
+
+	return "obsidian://open?vault=" + queryEscape(vault) + "&file=" + queryEscape(rel)
+}
+
+// queryEscape escapes s for a URL query value, spaces as %20 like Obsidian
+// writes them rather than "+"
+func queryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// toMarkdown renders the task as a plain "- [ ] description" checkbox for
+// pasting elsewhere. stripMeta drops the date markers and priority emoji.
+func toMarkdown(task *Task, stripMeta bool) string {
+	checkbox := "[ ]"
+	if task.Done {
+		checkbox = "[x]"
+	}
+
+	description := task.Description
+	if stripMeta {
+		for _, re := range append(task.lineFormat().dateMarkerRes(), priorityRe) {
+			description = re.ReplaceAllString(description, "")
+		}
+		description = strings.Join(strings.Fields(description), " ")
+	}
+
+	return "- " + checkbox + " " + description
+}
+
+// copyToClipboard writes text to the system clipboard
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		if clipboard.Unsupported {
+			return copyFinishedMsg{err: ErrNoClipboard}
+		}
+		return copyFinishedMsg{err: clipboard.WriteAll(text)}
+	}
+}
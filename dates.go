@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueShorthandRe matches "due: <when>" typed in the add and edit inputs
+var dueShorthandRe = regexp.MustCompile(`(?i)\bdue:\s*(today|tomorrow|(?:next\s+)?(?:mon|tues|wednes|thurs|fri|satur|sun)day|\+\d+[dw])\b`)
+
+// defaultDateFormat is the Obsidian Tasks date layout
+const defaultDateFormat = "2006-01-02"
+
+// namedDateFormats are the shorthands accepted by the date_format option
+var namedDateFormats = map[string]string{
+	"iso":      defaultDateFormat,
+	"date":     defaultDateFormat,
+	"datetime": "2006-01-02 15:04",
+	"rfc3339":  time.RFC3339,
+	"us":       "01/02/2006",
+	"eu":       "02/01/2006",
+}
+
+// layoutTokens maps Go layout elements to the text they produce, longest first
+var layoutTokens = []struct {
+	token   string
+	pattern string
+}{
+	{"January", `[A-Z][a-z]+`},
+	{"Monday", `[A-Z][a-z]+`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`},
+	{"-07:00", `[+-]\d{2}:\d{2}`},
+	{"-0700", `[+-]\d{4}`},
+	{"2006", `\d{4}`},
+	{"Jan", `[A-Z][a-z]{2}`},
+	{"Mon", `[A-Z][a-z]{2}`},
+	{"MST", `[A-Z]{3,4}`},
+	{"_2", `[ \d]\d`},
+	{"01", `\d{2}`},
+	{"02", `\d{2}`},
+	{"03", `\d{2}`},
+	{"04", `\d{2}`},
+	{"05", `\d{2}`},
+	{"06", `\d{2}`},
+	{"15", `\d{2}`},
+	{"PM", `[AP]M`},
+	{"pm", `[ap]m`},
+	{"1", `\d{1,2}`},
+	{"2", `\d{1,2}`},
+	{"3", `\d{1,2}`},
+	{"4", `\d{1,2}`},
+	{"5", `\d{1,2}`},
+}
+
+// resolveDateFormat turns a date_format value into a Go layout, falling back
+// to the default for empty or invalid layouts
+func resolveDateFormat(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultDateFormat
+	}
+	if named, ok := namedDateFormats[strings.ToLower(value)]; ok {
+		return named
+	}
+	if !validDateLayout(value) {
+		return defaultDateFormat
+	}
+	return value
+}
+
+// validDateLayout reports whether layout round-trips a date with its year,
+// month and day intact
+func validDateLayout(layout string) bool {
+	ref := time.Date(2024, time.March, 15, 13, 45, 30, 0, time.UTC)
+	formatted := ref.Format(layout)
+	if formatted == layout {
+		return false
+	}
+
+	parsed, err := time.Parse(layout, formatted)
+	if err != nil {
+		return false
+	}
+
+	y, m, d := parsed.Date()
+	return y == 2024 && m == time.March && d == 15
+}
+
+// layoutPattern translates a Go date layout into a regular expression
+// matching the dates it formats
+func layoutPattern(layout string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.pattern)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// newTaskFormat builds the format for the date_format option, whose regexps
+// recognize both the configured layout and the default
+func newTaskFormat(dateFormat string) *taskFormat {
+	layout := resolveDateFormat(dateFormat)
+
+	dates := layoutPattern(defaultDateFormat)
+	if layout != defaultDateFormat {
+		dates = layoutPattern(layout) + "|" + dates
+	}
+
+	return &taskFormat{
+		dateLayout:  layout,
+		doneRe:      regexp.MustCompile(`\s*✅\s*(?:` + dates + `)`),
+		dueDateRe:   regexp.MustCompile(`📅\s*(` + dates + `)`),
+		doneDateRe:  regexp.MustCompile(`✅\s*(` + dates + `)`),
+		createdRe:   regexp.MustCompile(`➕\s*(` + dates + `)`),
+		scheduledRe: regexp.MustCompile(`⏳\s*(` + dates + `)`),
+		startRe:     regexp.MustCompile(`🛫\s*(` + dates + `)`),
+	}
+}
+
+// parseDate parses value with the format's layout or the default, keeping
+// only the calendar date
+func (f *taskFormat) parseDate(value string) (time.Time, error) {
+	date, err := time.Parse(f.dateLayout, value)
+	if err != nil {
+		date, err = time.Parse(defaultDateFormat, value)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// formatDate renders a date with the format's layout
+func (f *taskFormat) formatDate(t time.Time) string {
+	return t.Format(f.dateLayout)
+}
+
+// humanizeDate describes date relative to now: "today", "tomorrow", "in 3d",
+// "2d ago"
+func humanizeDate(date, now time.Time) string {
+	days := daysBetween(now, date)
+
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case days > 0:
+		return fmt.Sprintf("in %dd", days)
+	default:
+		return fmt.Sprintf("%dd ago", -days)
+	}
+}
+
+// daysBetween counts the calendar days from one date to another, ignoring
+// the time of day
+func daysBetween(from, to time.Time) int {
+	y, m, d := from.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = to.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(start).Hours() / 24)
+}
+
+// taskAge describes how long ago an open task was created, "12d old", or
+// "" for done tasks and tasks without a created date
+func taskAge(task *Task, now time.Time) string {
+	if task.Done || task.CreatedDate == nil {
+		return ""
+	}
+	return fmt.Sprintf("%dd old", max(daysBetween(*task.CreatedDate, now), 0))
+}
+
+// resolveNaturalDate turns "today", "tomorrow", a weekday name (optionally
+// after "next") or "+Nd"/"+Nw" into a date. Weekdays mean the next one after
+// today.
+func resolveNaturalDate(value string, now time.Time) (time.Time, bool) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch value {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+
+	if n, ok := strings.CutPrefix(value, "+"); ok && len(n) > 1 {
+		count, err := strconv.Atoi(n[:len(n)-1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		switch n[len(n)-1] {
+		case 'd':
+			return today.AddDate(0, 0, count), true
+		case 'w':
+			return today.AddDate(0, 0, 7*count), true
+		}
+		return time.Time{}, false
+	}
+
+	name := strings.Join(strings.Fields(strings.TrimPrefix(value, "next")), "")
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.ToLower(wd.String()) == name {
+			days := (int(wd) - int(today.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// expandDueShorthand replaces "due: <when>" with a 📅 marker written in
+// format, leaving the rest of the text as typed
+func expandDueShorthand(description string, now time.Time, format *taskFormat) string {
+	return dueShorthandRe.ReplaceAllStringFunc(description, func(match string) string {
+		when := dueShorthandRe.FindStringSubmatch(match)[1]
+		date, ok := resolveNaturalDate(when, now)
+		if !ok {
+			return match
+		}
+		return "📅 " + format.orDefault().formatDate(date)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DuplicateGroup is a task description found at more than one location
+type DuplicateGroup struct {
+	Text  string // Normalized description shared by the tasks
+	Tasks []*Task
+}
+
+// normalizeDescription reduces a description whose dates are written in
+// format to its core text for duplicate detection: no dates, priority or
+// tags, lowercase, single spaces
+func normalizeDescription(description string, format *taskFormat) string {
+	for _, re := range append(format.dateMarkerRes(), priorityRe) {
+		description = re.ReplaceAllString(description, "")
+	}
+	description = tagRe.ReplaceAllString(description, "$1")
+
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// findDuplicates groups tasks whose normalized descriptions match, keeping
+// only texts found at more than one file and line, in order of first sight
+func findDuplicates(tasks []*Task) []DuplicateGroup {
+	byText := NewOrderedMap[string, []*Task]()
+	for _, task := range tasks {
+		text := normalizeDescription(task.Description, task.lineFormat())
+		if text == "" {
+			continue
+		}
+		existing, _ := byText.Get(text)
+		byText.Set(text, append(existing, task))
+	}
+
+	var groups []DuplicateGroup
+	byText.Each(func(text string, tasks []*Task) bool {
+		if len(tasks) > 1 {
+			groups = append(groups, DuplicateGroup{Text: text, Tasks: tasks})
+		}
+		return true
+	})
+
+	return groups
+}
+
+// writeDuplicates prints each duplicated text with its locations for
+// --duplicates, absolute ones when absolute is set
+func writeDuplicates(w io.Writer, groups []DuplicateGroup, vaultPath string, absolute bool) {
+	if len(groups) == 0 {
+		fmt.Fprintln(w, "No duplicate tasks found.")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d duplicated task(s):\n\n", len(groups))
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s (%d)\n", group.Text, len(group.Tasks))
+		for _, task := range group.Tasks {
+			fmt.Fprintf(w, "  %s (%s:%d)\n", task.Description, displayPath(vaultPath, task.FilePath, absolute), task.LineNumber)
+		}
+		fmt.Fprintln(w)
+	}
+}
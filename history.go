@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSearchHistory caps the remembered search queries; older ones drop off
+const maxSearchHistory = 50
+
+// searchHistoryPath returns where search queries are persisted under the XDG
+// state directory
+func searchHistoryPath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "ot", "search_history"), nil
+}
+
+// loadSearchHistory reads one query per line, oldest first, returning none
+// if the file doesn't exist
+func loadSearchHistory(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		history = addSearchHistory(history, line)
+	}
+
+	return history, nil
+}
+
+// saveSearchHistory writes history to path, creating the parent directory if
+// needed
+func saveSearchHistory(path string, history []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, []byte(strings.Join(history, "\n")+"\n"))
+}
+
+// addSearchHistory appends query unless it is blank or repeats the newest
+// entry, dropping the oldest entries past maxSearchHistory
+func addSearchHistory(history []string, query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return history
+	}
+
+	if len(history) > 0 && history[len(history)-1] == query {
+		return history
+	}
+
+	history = append(history, query)
+	if len(history) > maxSearchHistory {
+		history = history[len(history)-maxSearchHistory:]
+	}
+
+	return history
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is read from the vault root to exclude paths from scans
+const ignoreFileName = ".otignore"
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches vault-relative paths against gitignore-style rules
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadIgnoreFile parses the ignore file at path, returning nil if it doesn't exist
+func loadIgnoreFile(path string) (*ignoreMatcher, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return parseIgnore(string(content)), nil
+}
+
+// parseIgnore compiles gitignore-style patterns: "#" comments, "!" negation,
+// a trailing "/" for directories only, a leading or inner "/" to anchor the
+// pattern at the vault root, and "*", "?" and "**" wildcards
+func parseIgnore(content string) *ignoreMatcher {
+	matcher := &ignoreMatcher{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		pattern := globToRegexp(line)
+		if !anchored {
+			pattern = "(?:.*/)?" + pattern
+		}
+
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+
+		matcher.rules = append(matcher.rules, rule)
+	}
+
+	return matcher
+}
+
+// globToRegexp translates a gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
+
+// match reports whether the vault-relative path is ignored. The last
+// matching rule wins, so "!" rules can re-include paths.
+func (m *ignoreMatcher) match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	ignored := false
+
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(relPath) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
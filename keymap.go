@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Remappable actions, configured under [keybindings]
+const (
+	actionUp      = "up"
+	actionDown    = "down"
+	actionToggle  = "toggle"
+	actionEdit    = "edit"
+	actionAdd     = "add"
+	actionDelete  = "delete"
+	actionSearch  = "search"
+	actionRefresh = "refresh"
+	actionQuit    = "quit"
+	actionHelp    = "help"
+)
+
+// keymapActions lists the actions in lookup order
+var keymapActions = []string{
+	actionUp, actionDown, actionToggle, actionEdit, actionAdd,
+	actionDelete, actionSearch, actionRefresh, actionQuit, actionHelp,
+}
+
+// fixedKeys are the bindings that can't be remapped; a remapped action bound
+// to one of them shadows it
+var fixedKeys = []string{
+	"ctrl+c", "ctrl+e", "ctrl+n", "ctrl+y", "tab", "shift+tab",
+	"g", "G", "h", "l", "f", "s", "u", "D", "F", "H", "N", "R", "T", "W", "X", "Y",
+	"%", "{", "}", "*", "=", ">", "<", "]", "[", "+", "-", "!", "0",
+}
+
+// Keymap maps action names to the keys that trigger them
+type Keymap map[string][]string
+
+// defaultKeymap matches the built-in bindings
+func defaultKeymap() Keymap {
+	return Keymap{
+		actionUp:      {"up", "k"},
+		actionDown:    {"down", "j"},
+		actionToggle:  {"enter", " ", "x"},
+		actionEdit:    {"e"},
+		actionAdd:     {"a", "n"},
+		actionDelete:  {"d"},
+		actionSearch:  {"/"},
+		actionRefresh: {"r"},
+		actionQuit:    {"q", "ctrl+c"},
+		actionHelp:    {"?"},
+	}
+}
+
+// validateKeybindings rejects bindings for actions that don't exist
+func validateKeybindings(bindings map[string]string) error {
+	for action := range bindings {
+		if !slices.Contains(keymapActions, action) {
+			return fmt.Errorf("config: keybindings: unknown action %q", action)
+		}
+	}
+
+	return nil
+}
+
+// keybindingConflicts describes configured keys that are also bound to another
+// action or shadow a fixed binding
+func keybindingConflicts(bindings map[string]string) []string {
+	keymap := newKeymap(bindings)
+	var conflicts []string
+
+	for _, action := range keymapActions {
+		if _, ok := bindings[action]; !ok {
+			continue
+		}
+		for _, key := range keymap[action] {
+			for _, other := range keymapActions {
+				if other != action && keymap.is(key, other) {
+					conflicts = append(conflicts, fmt.Sprintf("keybindings: %q for %s is also bound to %s", key, action, other))
+				}
+			}
+			if slices.Contains(fixedKeys, key) {
+				conflicts = append(conflicts, fmt.Sprintf("keybindings: %q for %s shadows a built-in key", key, action))
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// newKeymap overlays configured bindings on the defaults. Each value is a
+// comma-separated list of keys, with "space" standing for the space bar.
+func newKeymap(bindings map[string]string) Keymap {
+	keymap := defaultKeymap()
+
+	for action, value := range bindings {
+		var keys []string
+		for _, key := range strings.Split(value, ",") {
+			key = strings.TrimSpace(key)
+			if key == "space" {
+				key = " "
+			}
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			keymap[action] = keys
+		}
+	}
+
+	return keymap
+}
+
+// action returns the action bound to key, or "" if there is none
+func (k Keymap) action(key string) string {
+	for _, action := range keymapActions {
+		if slices.Contains(k[action], key) {
+			return action
+		}
+	}
+	return ""
+}
+
+// is reports whether key triggers action
+func (k Keymap) is(key, action string) bool {
+	return slices.Contains(k[action], key)
+}
+
+// label renders an action's keys for the help modal
+func (k Keymap) label(action string) string {
+	labels := make([]string, 0, len(k[action]))
+
+	for _, key := range k[action] {
+		switch key {
+		case "up":
+			key = "↑"
+		case "down":
+			key = "↓"
+		case " ":
+			key = "space"
+		}
+		labels = append(labels, key)
+	}
+
+	return strings.Join(labels, "/")
+}
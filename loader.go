@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -14,14 +16,28 @@ import (
 const (
 	// Minimum time before showing the loading screen
 	loadingDelay = 200 * time.Millisecond
+
+	// Narrower windows show the parsing progress as text only
+	minProgressBarWidth = 40
+	maxProgressBarWidth = 60
 )
 
+// ErrScanCancelled is returned when the user quits the loader before the scan finishes
+var ErrScanCancelled = errors.New("scan cancelled")
+
 // ScanResult holds the final scan results
 type ScanResult struct {
-	Files []string
-	Tasks []*Task
-	Cache *TaskCache
-	Error error
+	Files    []string
+	Tasks    []*Task
+	Cache    *TaskCache
+	Warnings []ParseWarning
+	Error    error
+}
+
+// ParseWarning records a file that couldn't be parsed during a scan
+type ParseWarning struct {
+	File string
+	Err  error
 }
 
 // ScanProgress represents progress during vault scanning
@@ -42,21 +58,26 @@ type scanCompleteMsg struct{}
 // loaderModel handles the loading screen
 type loaderModel struct {
 	spinner      spinner.Model
+	bar          progress.Model // Animated parsing progress
 	progress     ScanProgress
 	windowWidth  int
 	windowHeight int
 	startTime    time.Time
 	showLoader   bool
+	cancel       context.CancelFunc
+	cancelled    bool
 }
 
-func newLoaderModel() loaderModel {
+func newLoaderModel(cancel context.CancelFunc) loaderModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = loaderTitleStyle
 
 	return loaderModel{
 		spinner:   s,
+		bar:       progress.New(progress.WithSolidFill(string(theme.Primary)), progress.WithoutPercentage()),
 		startTime: time.Now(),
+		cancel:    cancel,
 	}
 }
 
@@ -72,11 +93,16 @@ func (m loaderModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.windowWidth = msg.Width
 		m.windowHeight = msg.Height
+		m.bar.Width = min(msg.Width-20, maxProgressBarWidth)
 		return m, nil
 
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c":
+			if m.cancel != nil {
+				m.cancel()
+			}
+			m.cancelled = true
 			return m, tea.Quit
 		}
 
@@ -93,8 +119,16 @@ func (m loaderModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if !m.showLoader && time.Since(m.startTime) > loadingDelay {
 			m.showLoader = true
 		}
+		if m.progress.Phase == "parsing" && m.progress.FilesFound > 0 {
+			return m, m.bar.SetPercent(float64(m.progress.FilesParsed) / float64(m.progress.FilesFound))
+		}
 		return m, nil
 
+	case progress.FrameMsg:
+		bar, cmd := m.bar.Update(msg)
+		m.bar = bar.(progress.Model)
+		return m, cmd
+
 	case scanCompleteMsg:
 		return m, tea.Quit
 	}
@@ -120,7 +154,10 @@ func (m loaderModel) View() string {
 		}
 	case "parsing":
 		b.WriteString("Parsing files...")
-		if m.progress.FilesParsed > 0 && m.progress.FilesFound > 0 {
+		if m.windowWidth >= minProgressBarWidth && m.progress.FilesFound > 0 {
+			b.WriteString("\n" + m.bar.View())
+			b.WriteString(loaderCountStyle.Render(fmt.Sprintf(" %d/%d", m.progress.FilesParsed, m.progress.FilesFound)))
+		} else if m.progress.FilesParsed > 0 && m.progress.FilesFound > 0 {
 			pct := float64(m.progress.FilesParsed) / float64(m.progress.FilesFound) * 100
 			b.WriteString(loaderCountStyle.Render(fmt.Sprintf(" %d/%d", m.progress.FilesParsed, m.progress.FilesFound)))
 			b.WriteString(dimTextStyle.Render(fmt.Sprintf(" (%.0f%%)", pct)))
@@ -148,62 +185,122 @@ func (m loaderModel) View() string {
 	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, content)
 }
 
-// RunWithLoader runs the scan with a loading screen if it takes too long
-func RunWithLoader(vaultPath string, useCache bool) ([]string, []*Task, *TaskCache, error) {
-	var result ScanResult
-	var mu sync.Mutex
-	done := make(chan struct{})
+// parseFiles parses each file with format, stopping early when ctx is
+// cancelled. The progress callback, if set, is invoked before each file is
+// parsed. Files
+// unchanged since they were cached are not read again; files that fail to
+// parse are skipped and reported as warnings.
+func parseFiles(ctx context.Context, files []string, format *taskFormat, cache *TaskCache, progress func(parsed int, file string, tasksFound int)) ([]*Task, []ParseWarning, error) {
+	var allTasks []*Task
+	var warnings []ParseWarning
+
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return allTasks, warnings, err
+		}
 
-	// Start scanning in background
-	go func() {
-		defer close(done)
+		if progress != nil {
+			progress(i, file, len(allTasks))
+		}
 
-		files, err := scanVault(vaultPath)
+		if cache != nil {
+			if tasks, ok := cache.Get(file); ok {
+				allTasks = append(allTasks, tasks...)
+				continue
+			}
+		}
+
+		tasks, err := parseFile(file, format)
 		if err != nil {
-			mu.Lock()
-			result.Error = err
-			mu.Unlock()
-			return
+			warnings = append(warnings, ParseWarning{File: file, Err: err})
+			continue
+		}
+		if cache != nil {
+			cache.Set(file, tasks)
 		}
+		allTasks = append(allTasks, tasks...)
+	}
 
-		mu.Lock()
-		result.Files = files
-		mu.Unlock()
+	return allTasks, warnings, nil
+}
 
-		var cache *TaskCache
-		if useCache {
-			cache = NewTaskCache()
-		}
+// scanError maps context cancellation to ErrScanCancelled
+func scanError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return ErrScanCancelled
+	}
+	return err
+}
 
-		var allTasks []*Task
-		for _, file := range files {
-			tasks, err := parseFile(file)
-			if err != nil {
-				continue
-			}
-			if cache != nil {
-				cache.Set(file, tasks)
-			}
-			allTasks = append(allTasks, tasks...)
+// scanAndParse finds the vault's files and parses their tasks, reusing
+// cached tasks for unchanged files. It is the pipeline the loaders run in the
+// background; progress, if set, receives each step.
+func scanAndParse(ctx context.Context, vaultPath string, opts scanOptions, cache *TaskCache, progress func(ScanProgress)) ScanResult {
+	report := func(p ScanProgress) {
+		if progress != nil {
+			progress(p)
 		}
+	}
+
+	// Phase 1: Scan for files
+	report(ScanProgress{Phase: "scanning"})
+
+	files, err := scanVaultContext(ctx, vaultPath, opts)
+	if err != nil {
+		return ScanResult{Cache: cache, Error: scanError(err)}
+	}
 
-		mu.Lock()
-		result.Tasks = allTasks
-		result.Cache = cache
-		mu.Unlock()
+	report(ScanProgress{Phase: "scanning", FilesFound: len(files)})
+
+	// Phase 2: Parse files
+	allTasks, warnings, err := parseFiles(ctx, files, opts.format, cache, func(parsed int, file string, tasksFound int) {
+		report(ScanProgress{
+			Phase:       "parsing",
+			FilesFound:  len(files),
+			FilesParsed: parsed,
+			TasksFound:  tasksFound,
+			CurrentFile: file,
+		})
+	})
+
+	return ScanResult{
+		Files:    files,
+		Tasks:    allTasks,
+		Cache:    cache,
+		Warnings: warnings,
+		Error:    scanError(err),
+	}
+}
+
+// RunWithLoader runs the scan with a loading screen if it takes too long
+func RunWithLoader(vaultPath string, opts scanOptions, useCache bool) ScanResult {
+	var result ScanResult
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cache *TaskCache
+	if useCache {
+		cache = NewTaskCache()
+	}
+
+	// Start scanning in background; result is read only after done closes
+	go func() {
+		defer close(done)
+		result = scanAndParse(ctx, vaultPath, opts, cache, nil)
 	}()
 
 	// Wait a bit to see if scanning finishes quickly
 	select {
 	case <-done:
 		// Fast path: scanning finished before delay
-		return result.Files, result.Tasks, result.Cache, result.Error
+		return result
 	case <-time.After(loadingDelay):
 		// Slow path: show loader
 	}
 
 	// Start the loader TUI
-	m := newLoaderModel()
+	m := newLoaderModel(cancel)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	// Monitor for completion and quit the TUI
@@ -213,78 +310,55 @@ func RunWithLoader(vaultPath string, useCache bool) ([]string, []*Task, *TaskCac
 	}()
 
 	// Run the TUI (blocks until quit)
-	p.Run()
+	final, _ := p.Run()
+	if lm, ok := final.(loaderModel); ok && lm.cancelled {
+		cancel()
+		<-done
+		return ScanResult{Error: ErrScanCancelled}
+	}
 
-	return result.Files, result.Tasks, result.Cache, result.Error
+	<-done
+
+	return result
 }
 
 // RunWithLoaderProgress runs the scan with detailed progress updates
-func RunWithLoaderProgress(vaultPath string, useCache bool) ([]string, []*Task, *TaskCache, error) {
+func RunWithLoaderProgress(vaultPath string, opts scanOptions, useCache bool) ScanResult {
 	var result ScanResult
 	done := make(chan struct{})
 	progress := make(chan ScanProgress, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cache *TaskCache
+	if useCache {
+		cache = NewTaskCache()
+	}
 
 	// Start scanning in background with progress reporting
 	go func() {
 		defer close(done)
 		defer close(progress)
 
-		// Phase 1: Scan for files
-		progress <- ScanProgress{Phase: "scanning"}
-
-		files, err := scanVault(vaultPath)
-		if err != nil {
-			result.Error = err
-			return
-		}
-
-		result.Files = files
-		progress <- ScanProgress{Phase: "scanning", FilesFound: len(files)}
-
-		// Phase 2: Parse files
-		var cache *TaskCache
-		if useCache {
-			cache = NewTaskCache()
-		}
-
-		var allTasks []*Task
-		for i, file := range files {
+		result = scanAndParse(ctx, vaultPath, opts, cache, func(p ScanProgress) {
 			select {
-			case progress <- ScanProgress{
-				Phase:       "parsing",
-				FilesFound:  len(files),
-				FilesParsed: i,
-				TasksFound:  len(allTasks),
-				CurrentFile: file,
-			}:
+			case progress <- p:
 			default:
 				// Don't block if channel is full
 			}
-
-			tasks, err := parseFile(file)
-			if err != nil {
-				continue
-			}
-			if cache != nil {
-				cache.Set(file, tasks)
-			}
-			allTasks = append(allTasks, tasks...)
-		}
-
-		result.Tasks = allTasks
-		result.Cache = cache
+		})
 	}()
 
 	// Wait a bit to see if scanning finishes quickly
 	select {
 	case <-done:
-		return result.Files, result.Tasks, result.Cache, result.Error
+		return result
 	case <-time.After(loadingDelay):
 		// Continue to show loader
 	}
 
 	// Start the loader TUI
-	m := newLoaderModel()
+	m := newLoaderModel(cancel)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	// Forward progress to TUI
@@ -300,7 +374,16 @@ func RunWithLoaderProgress(vaultPath string, useCache bool) ([]string, []*Task,
 		p.Send(scanCompleteMsg{})
 	}()
 
-	p.Run()
+	final, _ := p.Run()
+
+	// Wait for the scan goroutine so result is safe to read
+	if lm, ok := final.(loaderModel); ok && lm.cancelled {
+		cancel()
+		<-done
+		return ScanResult{Error: ErrScanCancelled}
+	}
+
+	<-done
 
-	return result.Files, result.Tasks, result.Cache, result.Error
+	return result
 }
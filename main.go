@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,28 +14,318 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 //go:embed VERSION
 var version string
-var buildSHA string
+
+// Set at build time with -ldflags "-X main.buildSHA=... -X main.buildDate=..."
+var (
+	buildSHA  string
+	buildDate string
+)
+
+// versionString returns the version with the build commit and date
+func versionString() string {
+	info := strings.TrimSpace(buildSHA)
+	if info == "" {
+		info = "unknown"
+	}
+	if date := strings.TrimSpace(buildDate); date != "" {
+		info += ", built " + date
+	}
+	return fmt.Sprintf("ot version v%s (%s)", strings.TrimSpace(version), info)
+}
 
 // containsGlob checks if a path contains glob pattern characters
 func containsGlob(path string) bool {
 	return strings.ContainsAny(path, "*?[")
 }
 
+// writeStats prints task totals for --stats: by status, priority and due
+// date over every matched task, then per section
+func writeStats(w io.Writer, sections []QuerySection, now time.Time) {
+	seen := make(map[*Task]bool)
+	var tasks []*Task
+	for _, section := range sections {
+		for _, task := range section.Tasks {
+			if !seen[task] {
+				seen[task] = true
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	today := startOfDay(now)
+	weekEnd := today.AddDate(0, 0, 7)
+	var open, done, overdue, dueThisWeek int
+	byPriority := make(map[int]int)
+
+	for _, task := range tasks {
+		if task.Done {
+			done++
+			continue
+		}
+		open++
+		byPriority[task.Priority]++
+		if task.DueDate == nil {
+			continue
+		}
+		due := startOfDay(*task.DueDate)
+		if due.Before(today) {
+			overdue++
+		} else if due.Before(weekEnd) {
+			dueThisWeek++
+		}
+	}
+
+	// Rows without a count are headings
+	type statRow struct {
+		label string
+		count int
+	}
+	var rows []statRow
+	heading := func(title string) { rows = append(rows, statRow{label: title, count: -1}) }
+	row := func(label string, n int) { rows = append(rows, statRow{label: "  " + label, count: n}) }
+
+	heading("Status")
+	row("open", open)
+	row("done", done)
+	row("total", len(tasks))
+
+	heading("Open by priority")
+	for p := PriorityHighest; p <= PriorityLowest; p++ {
+		row(priorityNames[p], byPriority[p])
+	}
+
+	heading("Due")
+	row("overdue", overdue)
+	row("next 7 days", dueThisWeek)
+
+	heading("Sections")
+	for _, section := range sections {
+		name := section.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		row(name, len(section.Tasks))
+	}
+
+	width := 0
+	for _, r := range rows {
+		if r.count >= 0 {
+			width = max(width, lipgloss.Width(r.label))
+		}
+	}
+	for _, r := range rows {
+		if r.count < 0 {
+			fmt.Fprintln(w, r.label)
+			continue
+		}
+		padding := strings.Repeat(" ", width-lipgloss.Width(r.label))
+		fmt.Fprintf(w, "%s%s  %d\n", r.label, padding, r.count)
+	}
+}
+
+// parseInterspersed keeps parsing flags that follow positional arguments,
+// which flag.Parse stops at, and returns the positional arguments
+func parseInterspersed(fs *flag.FlagSet) []string {
+	var positional []string
+	for fs.NArg() > 0 {
+		positional = append(positional, fs.Arg(0))
+		_ = fs.Parse(fs.Args()[1:])
+	}
+	return positional
+}
+
+// firstTask returns the first task in display order, or nil when every
+// section is empty
+func firstTask(sections []QuerySection) *Task {
+	for _, section := range sections {
+		for _, group := range section.Groups {
+			if len(group.Tasks) > 0 {
+				return group.Tasks[0]
+			}
+		}
+	}
+	return nil
+}
+
+// writeList prints sections as plain text for --list, with absolute task
+// locations when absolute is set
+func writeList(w io.Writer, sections []QuerySection, vaultPath string, totalTasks int, absolute bool) {
+	fmt.Fprintf(w, "Found %d task(s):\n\n", totalTasks)
+	for _, section := range sections {
+		if len(section.Tasks) == 0 {
+			continue
+		}
+
+		if section.Name != "" {
+			fmt.Fprintf(w, "## %s (%d)\n", section.Name, len(section.Tasks))
+		}
+
+		for i, group := range section.Groups {
+			if len(group.Tasks) == 0 {
+				continue
+			}
+
+			if len(section.Query.GroupBy) > 0 {
+				for _, header := range groupHeaders(section.Groups, i) {
+					fmt.Fprintf(w, "%s %s\n", strings.Repeat("#", header.Level+3), header.Name)
+				}
+			}
+
+			for _, task := range group.Tasks {
+				checkbox := "[ ]"
+
+				if task.Done {
+					checkbox = "[x]"
+				}
+
+				fmt.Fprintf(w, "%s %s (%s:%d)%s\n", checkbox, task.Description, displayPath(vaultPath, task.FilePath, absolute), task.LineNumber, listTokens(task))
+			}
+		}
+		if section.Hidden > 0 {
+			fmt.Fprintf(w, "… %d more\n", section.Hidden)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// toggleTaskRef toggles the task at a file:line reference for --toggle and
+// prints its new line, or with dryRun the change it would make. Dates are
+// written in format; absolute shows the change's absolute path.
+func toggleTaskRef(w io.Writer, ref, vaultPath string, format *taskFormat, dryRun, absolute bool) error {
+	path, line, err := parseTaskRef(ref, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	task, err := taskAtLine(path, line, format)
+	if err != nil {
+		return err
+	}
+	task.Toggle()
+
+	if dryRun {
+		changes, err := planTaskEdits([]taskEdit{{Task: task}})
+		if err != nil {
+			return err
+		}
+		writeLineChanges(w, changes, vaultPath, absolute)
+		return nil
+	}
+
+	if err := saveTask(task); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, task.RawLine)
+	return nil
+}
+
+// writeLineChanges prints planned line changes as file:line headers with
+// "-" old and "+" new lines, for --dry-run
+func writeLineChanges(w io.Writer, changes []LineChange, vaultPath string, absolute bool) {
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s:%d\n", displayPath(vaultPath, change.Path, absolute), change.Line)
+		if !change.Inserted {
+			fmt.Fprintf(w, "- %s\n", change.Old)
+		}
+		if !change.Deleted {
+			fmt.Fprintf(w, "+ %s\n", change.New)
+		}
+	}
+}
+
+// listTokens are the " due:YYYY-MM-DD priority:name" fields after a --list
+// line, for scripts. Dates ignore date_format; normal priority is omitted.
+func listTokens(task *Task) string {
+	var tokens string
+	if task.DueDate != nil {
+		tokens += " due:" + task.DueDate.Format("2006-01-02")
+	}
+	if name := priorityNames[task.Priority]; name != "" && task.Priority != PriorityNormal {
+		tokens += " priority:" + name
+	}
+	return tokens
+}
+
+// configureModel applies the config shared by the tabbed and single profile
+// TUIs and loads the saved pins and search history
+func configureModel(m *model, cfg Config, scan scanOptions, glyphs checkboxGlyphs, absolute, reverse bool, poll time.Duration) {
+	m.scan = scan
+	m.queryBlock = cfg.QueryBlock
+	m.checkboxes = glyphs
+	m.fullPaths = absolute
+	m.short = cfg.Short
+	m.wrap = cfg.Wrap
+	m.inboxFile = cfg.InboxFile
+	m.dailyNoteFormat = cfg.DailyNoteFormat
+	m.dailyNoteHeader = cfg.DailyNoteHeader
+	m.addCreatedDate = cfg.AddCreatedDate
+	m.quickDelete = cfg.ConfirmDelete != nil && !*cfg.ConfirmDelete
+	m.statusBar = cfg.StatusBar
+	m.keys = newKeymap(cfg.Keybindings)
+	m.pollInterval = poll
+	m.sectionOrder = cfg.SectionOrder
+	m.reversed = reverse
+	m.loadPins()
+	m.loadSearchHistory()
+}
+
 func main() {
 	queryInput := flag.String("query", "", "Query file path or inline query string")
 	queryInputShort := flag.String("q", "", "Query file path or inline query string (short)")
 	listOnly := flag.Bool("list", false, "List tasks without TUI (non-interactive)")
+	showStats := flag.Bool("stats", false, "Print task totals without TUI (non-interactive)")
+	showDuplicates := flag.Bool("duplicates", false, "List tasks that appear in more than one place (non-interactive)")
+	absolutePathsFlag := flag.Bool("absolute-paths", false, "Show absolute file paths instead of vault-relative ones")
+	noColorFlag := flag.Bool("no-color", false, "Render without colors or styling (also NO_COLOR, CLICOLOR=0)")
+	reverse := flag.Bool("reverse", false, "Reverse the task order within each group")
+	editorFlag := flag.String("editor", "", `Edit tasks "inline" or "external" ($EDITOR), overriding the profile`)
+	openFirst := flag.Bool("open", false, "Open the first matching task in $EDITOR and exit")
 	profileName := flag.String("profile", "", "Profile name from config (optional)")
 	configFile := flag.String("config", "", "Path to config file (optional)")
 	configFileShort := flag.String("c", "", "Path to config file (short)")
 	showVersion := flag.Bool("version", false, "Show version and exit")
-	initTasks := flag.Bool("init", false, "Create a tasks.md file with an empty task")
+	shortVersion := flag.Bool("short", false, "With --version, print only the version number")
+	initCfg := flag.Bool("init", false, "Create a config file from a template")
+	force := flag.Bool("force", false, "With --init, overwrite an existing config")
+	initTasks := flag.Bool("init-tasks", false, "Create a tasks.md file with an empty task")
+	checkCfg := flag.Bool("check", false, "Validate the config and every profile, then exit")
+	showProfiles := flag.Bool("profiles", false, "List profiles from the config and exit")
+	toggleRef := flag.String("toggle", "", "Toggle the task at file:line (relative to the vault) and exit")
+	dryRun := flag.Bool("dry-run", false, "With --toggle, print the line change without writing it")
+	complete := flag.String("complete", "", "Print completion candidates (profiles) for shell scripts")
 
 	flag.Parse()
+	args := parseInterspersed(flag.CommandLine)
+
+	// --stats scans like --list and only differs in its output
+	plain := *listOnly || *showStats || *showDuplicates
+
+	// Get config path from -c or --config flags
+	cfgFile := *configFile
+	if cfgFile == "" {
+		cfgFile = *configFileShort
+	}
+
+	if *initCfg {
+		path, err := configPath()
+		if cfgFile != "" {
+			path, err = expandPath(cfgFile)
+		}
+		if err == nil {
+			err = initConfig(path, *force)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s\n", path)
+		os.Exit(0)
+	}
 
 	if *initTasks {
 		if err := createTasksFile(); err != nil {
@@ -44,47 +337,126 @@ func main() {
 	}
 
 	if *showVersion {
-		sha := strings.TrimSpace(buildSHA)
-
-		if sha == "" {
-			sha = "unknown"
+		if *shortVersion {
+			fmt.Println(strings.TrimSpace(version))
+		} else {
+			fmt.Println(versionString())
 		}
-
-		fmt.Printf("ot version v%s (%s)\n", strings.TrimSpace(version), sha)
 		os.Exit(0)
 	}
 
-	args := flag.Args()
-
-	// Get config path from -c or --config flags
-	cfgFile := *configFile
-	if cfgFile == "" {
-		cfgFile = *configFileShort
+	if len(args) > 0 && args[0] == "completion" {
+		shell := ""
+		if len(args) > 1 {
+			shell = args[1]
+		}
+		script, err := completionScript(shell, flag.CommandLine)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
 	}
 
 	cfg, cfgPath, err := loadConfigFrom(cfgFile)
 
+	// Completion runs inside the shell, so a broken config completes nothing
+	if *complete != "" {
+		if *complete != "profiles" {
+			os.Exit(1)
+		}
+		if err == nil {
+			writeProfileNames(cfg, os.Stdout)
+		}
+		os.Exit(0)
+	}
+
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *showProfiles {
+		if len(cfg.Profiles) == 0 {
+			fmt.Printf("No profiles defined in %s\n", cfgPath)
+			os.Exit(0)
+		}
+		listProfiles(cfg, os.Stdout)
+		os.Exit(0)
+	}
+
+	if *checkCfg {
+		fmt.Printf("Checking %s\n", cfgPath)
+		if !checkConfig(cfg, os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	format := newTaskFormat(cfg.DateFormat)
+	format.uppercaseDone = cfg.UppercaseDone
+	scan := scanOptions{
+		followSymlinks: cfg.FollowSymlinks,
+		format:         format,
+		extensions:     normalizeExtensions(cfg.Extensions),
+	}
+	absolutePaths := cfg.AbsolutePaths || *absolutePathsFlag
+	glyphs := checkboxGlyphs{todo: cfg.CheckboxTodo, done: cfg.CheckboxDone}
+	if err := checkCheckboxGlyphs(glyphs.todo, glyphs.done); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	debounce, err := refreshDebounce(cfg.RefreshDebounce)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	poll, err := pollInterval(cfg.PollInterval)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
 	// Initialize renderer with theme from config
 	if cfg.Theme != "" {
 		initRenderer(cfg.Theme)
 	}
 
+	if palette, ok := palettes[strings.ToLower(cfg.Palette)]; ok {
+		applyTheme(palette)
+	} else if cfg.Palette != "" {
+		fmt.Printf("Warning: unknown palette %q\n", cfg.Palette)
+	}
+
+	if noColorRequested(*noColorFlag, os.Getenv) {
+		setNoColor()
+	}
+
 	// Check for tabs mode: enabled in config, no args, no specific profile flag, not list mode
-	if cfg.Tabs && len(args) == 0 && *profileName == "" && !*listOnly && len(cfg.Profiles) > 1 {
-		tabs, err := loadAllProfileTabs(cfg)
+	// and not --open, which edits one task without the TUI
+	if cfg.Tabs && len(args) == 0 && *profileName == "" && !plain && !*openFirst && *toggleRef == "" && len(cfg.Profiles) > 1 {
+		tabs, err := loadAllProfileTabs(cfg, scan, *reverse, debounce)
+		if errors.Is(err, ErrScanCancelled) {
+			os.Exit(0)
+		}
 		if err != nil {
 			fmt.Printf("Error loading profiles: %v\n", err)
 			os.Exit(1)
 		}
 
+		for _, tab := range tabs {
+			tab.Profile.EditorMode, err = resolveEditorMode(*editorFlag, tab.Profile.EditorMode)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
 		if len(tabs) > 0 {
 			m := newModelWithTabs(tabs)
-			p := tea.NewProgram(m, tea.WithAltScreen())
+			configureModel(&m, cfg, scan, glyphs, absolutePaths, *reverse, poll)
+			if len(m.pins) > 0 {
+				m.refresh()
+			}
+			p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 			// Set program for all debouncers
 			for _, tab := range tabs {
@@ -111,6 +483,7 @@ func main() {
 	}
 
 	var resolvedVault, queryFile, titleName, editorMode string
+	var profileGroup, profileSort string
 	var queries []*Query
 	var globFiles []string // Files matched by glob pattern
 
@@ -139,9 +512,9 @@ func main() {
 			os.Exit(1)
 		}
 
-		// Filter to only .md files
+		// Filter to only task files
 		for _, match := range matches {
-			if strings.HasSuffix(match, ".md") {
+			if scan.hasTaskExtension(match) {
 				if info, err := os.Stat(match); err == nil && !info.IsDir() {
 					globFiles = append(globFiles, match)
 				}
@@ -200,6 +573,7 @@ func main() {
 			resolvedVault = resolved.VaultPath
 			titleName = name
 			editorMode = resolved.EditorMode
+			profileGroup, profileSort = resolved.Group, resolved.Sort
 
 			if resolved.QueryIsFile {
 				queryFile = resolved.Query
@@ -216,6 +590,7 @@ func main() {
 		fmt.Println("  ot <vault-path>                Show 'not done' tasks from vault")
 		fmt.Println("  ot <glob-pattern>              Show tasks from files matching pattern")
 		fmt.Println("  ot <vault-path> -q <query>     Query file or inline query string")
+		fmt.Println("  ot <vault-path> <file>...      Merge the blocks of several query files")
 		fmt.Println("  ot                             Use default profile from config")
 		fmt.Println("  ot --profile <name>            Use named profile from config")
 		fmt.Println("\nOptions:")
@@ -223,8 +598,20 @@ func main() {
 		fmt.Println("  --profile <name>      Use profile from config")
 		fmt.Println("  -c, --config <path>   Path to config file")
 		fmt.Println("  --list                List tasks without TUI")
-		fmt.Println("  --init                Create tasks.md with an empty task")
-		fmt.Println("  --version             Show version")
+		fmt.Println("  --stats               Print totals by status, priority, due date and section")
+		fmt.Println("  --duplicates          List tasks found in more than one place")
+		fmt.Println("  --no-color            Plain output without colors (also NO_COLOR)")
+		fmt.Println("  --absolute-paths      Show absolute file paths")
+		fmt.Println("  --init [--force]      Create a config file from a template")
+		fmt.Println("  --init-tasks          Create tasks.md with an empty task")
+		fmt.Println("  --check               Validate the config and all profiles")
+		fmt.Println("  --profiles            List profiles (* marks the default)")
+		fmt.Println("  completion bash|zsh   Print a shell completion script")
+		fmt.Println("  --reverse             Reverse the task order within each group")
+		fmt.Println("  --open                Edit the first matching task in $EDITOR, no TUI")
+		fmt.Println("  --editor <mode>       Edit inline or external ($EDITOR), overriding the profile")
+		fmt.Println("  --toggle <file:line>  Toggle one task and print its new line (--dry-run: only show it)")
+		fmt.Println("  --version [--short]   Show version (--short: number only)")
 		fmt.Println("\nSupported query filters:")
 		fmt.Println("  not done              Show only incomplete tasks")
 		fmt.Println("  due today             Tasks due today")
@@ -247,6 +634,7 @@ func main() {
 		fmt.Println("  ot ~/vault")
 		fmt.Println("  ot ~/vault -q 'due today'")
 		fmt.Println("  ot ~/vault -q queries/work.md")
+		fmt.Println("  ot ~/vault daily.md weekly.md")
 		fmt.Println("  ot 'projects/*/todo.md'")
 
 		if cfgPath != "" {
@@ -258,40 +646,75 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Resolve query: from flag, from profile, or default
-	if queryStr != "" {
-		queries, err = resolveQuery(queryStr, resolvedVault)
+	if *toggleRef != "" {
+		if err := toggleTaskRef(os.Stdout, *toggleRef, resolvedVault, scan.format, *dryRun, absolutePaths); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	editorMode, err = resolveEditorMode(*editorFlag, editorMode)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Query files after the vault, in argument order
+	var queryFiles []string
+	if len(args) > 1 {
+		if queryStr != "" {
+			fmt.Println("Error: use either -q or query file arguments")
+			os.Exit(1)
+		}
+		queryFiles, err = queryFileArgs(args[1:], resolvedVault)
 		if err != nil {
 			fmt.Printf("Error resolving query: %v\n", err)
 			os.Exit(1)
 		}
-	} else if queryFile != "" {
-		queries, err = parseAllQueryBlocks(queryFile)
-	} else {
-		// Default: show "not done" tasks sorted by priority
-		queries = []*Query{{NotDone: true, SortBy: "priority"}}
 	}
 
+	var queryWarnings []ParseWarning
+
+	// Resolve query: from query file arguments, flag, profile, or default
+	if len(queryFiles) > 0 {
+		queries, queryWarnings, err = parseQueryFiles(queryFiles, cfg.QueryBlock)
+		queryFile = queryFiles[0]
+	} else {
+		queries, err = selectQueries(queryStr, queryFile, resolvedVault, cfg.DefaultQuery, cfg.QueryBlock)
+	}
 	if err != nil {
-		fmt.Printf("Error parsing query file: %v\n", err)
+		fmt.Printf("Error resolving query: %v\n", err)
 		os.Exit(1)
 	}
+	if plain {
+		for _, w := range queryWarnings {
+			fmt.Printf("Warning: skipping %s: %v\n", w.File, w.Err)
+		}
+		if w := queriesWarning(queries); w != "" {
+			fmt.Printf("Warning: %s\n", w)
+		}
+	}
 
 	// Get files to parse: from glob matches or vault scan
 	var files []string
 	var allTasks []*Task
+	var warnings []ParseWarning
 	var cache *TaskCache
 
 	if len(globFiles) > 0 {
 		// Glob mode: parse files directly (typically small set)
 		files = globFiles
-		if !*listOnly {
+		if !plain {
 			cache = NewTaskCache()
 		}
 		for _, file := range files {
-			tasks, err := parseFile(file)
+			tasks, err := parseFile(file, scan.format)
 			if err != nil {
-				fmt.Printf("Warning: could not parse %s: %v\n", file, err)
+				if plain {
+					fmt.Printf("Warning: could not parse %s: %v\n", file, err)
+				}
+				warnings = append(warnings, ParseWarning{File: file, Err: err})
 				continue
 			}
 			if cache != nil {
@@ -301,26 +724,28 @@ func main() {
 		}
 	} else {
 		// Vault mode: scan recursively
-		useCache := !*listOnly
+		useCache := !plain
 		var scanErr error
 
-		if *listOnly {
+		if plain {
 			// Non-interactive mode: scan without loader TUI
-			files, scanErr = scanVault(resolvedVault)
+			files, scanErr = scanVault(resolvedVault, scan)
 			if scanErr != nil {
 				fmt.Printf("Error scanning vault: %v\n", scanErr)
 				os.Exit(1)
 			}
-			for _, file := range files {
-				tasks, err := parseFile(file)
-				if err != nil {
-					continue
-				}
-				allTasks = append(allTasks, tasks...)
+			allTasks, warnings, _ = parseFiles(context.Background(), files, scan.format, nil, nil)
+			for _, w := range warnings {
+				fmt.Printf("Warning: could not parse %s: %v\n", w.File, w.Err)
 			}
 		} else {
-			// Interactive mode: use loader for potentially large vaults
-			files, allTasks, cache, scanErr = RunWithLoaderProgress(resolvedVault, useCache)
+			// Interactive mode: use loader for potentially large vaults.
+			// Fast vaults finish within loadingDelay and never show it.
+			result := RunWithLoaderProgress(resolvedVault, scan, useCache)
+			files, allTasks, cache, warnings, scanErr = result.Files, result.Tasks, result.Cache, result.Warnings, result.Error
+			if errors.Is(scanErr, ErrScanCancelled) {
+				os.Exit(0)
+			}
 			if scanErr != nil {
 				fmt.Printf("Error scanning vault: %v\n", scanErr)
 				os.Exit(1)
@@ -328,62 +753,72 @@ func main() {
 		}
 	}
 
+	warnings = append(queryWarnings, warnings...)
+
+	// Duplicates are looked for across the whole vault, not just the matches
+	if *showDuplicates {
+		writeDuplicates(os.Stdout, findDuplicates(allTasks), resolvedVault, absolutePaths)
+		os.Exit(0)
+	}
+
 	var sections []QuerySection
 
-	totalTasks := 0
+	var matched []*Task
 
-	for _, query := range queries {
+	for i, query := range queries {
+		query = withProfileDefaults(query, profileGroup, profileSort)
 		filtered := filterTasks(allTasks, query)
-		groups := groupTasks(filtered, query.GroupBy, query.SortBy, resolvedVault)
+		groups := groupTasksBy(filtered, query.GroupBy, query.SortBy, resolvedVault)
+		if *reverse {
+			groups = reverseGroups(groups)
+		}
+		if query.CompletedLast {
+			groups = completedLast(groups)
+		}
+		groups, hidden := limitGroups(groups, query.Limit)
 
 		sections = append(sections, QuerySection{
 			Name:   query.Name,
 			Query:  query,
+			Index:  i,
 			Groups: groups,
 			Tasks:  filtered,
+			Hidden: hidden,
 		})
 
-		totalTasks += len(filtered)
+		matched = append(matched, filtered...)
 	}
+	sections = orderSections(sections, cfg.SectionOrder)
+
+	// A task matching several queries is listed under each but counted once
+	totalTasks := countUniqueTasks(matched)
 
-	if totalTasks == 0 {
+	// The TUI explains an empty list itself, the other modes just say so
+	if totalTasks == 0 && (*openFirst || *showStats || plain) {
 		fmt.Println("No tasks found matching any query.")
+		if *openFirst {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
-	if *listOnly {
-		fmt.Printf("Found %d task(s):\n\n", totalTasks)
-		for _, section := range sections {
-			if len(section.Tasks) == 0 {
-				continue
-			}
-
-			if section.Name != "" {
-				fmt.Printf("## %s (%d)\n", section.Name, len(section.Tasks))
-			}
-
-			for _, group := range section.Groups {
-				if len(group.Tasks) == 0 {
-					continue
-				}
-
-				if section.Query.GroupBy != "" && group.Name != "" {
-					fmt.Printf("### %s\n", group.Name)
-				}
-
-				for _, task := range group.Tasks {
-					checkbox := "[ ]"
-
-					if task.Done {
-						checkbox = "[x]"
-					}
-
-					fmt.Printf("%s %s (%s:%d)\n", checkbox, task.Description, relPath(resolvedVault, task.FilePath), task.LineNumber)
-				}
-			}
-			fmt.Println()
+	if task := firstTask(sections); *openFirst && task != nil {
+		c := editorCommand(task)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Printf("Error running editor: %v\n", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
+	}
 
+	if *showStats {
+		writeStats(os.Stdout, sections, time.Now())
+		os.Exit(0)
+	}
+
+	if plain {
+		writeList(os.Stdout, sections, resolvedVault, totalTasks, absolutePaths)
 		os.Exit(0)
 	}
 
@@ -391,14 +826,24 @@ func main() {
 	var watcher *Watcher
 	var debouncer *Debouncer
 	if len(globFiles) == 0 {
-		watcher, _ = NewWatcher(resolvedVault)
+		watcher, _ = NewWatcher(resolvedVault, queryFile, scan)
 		if watcher != nil {
-			debouncer = NewDebouncer(150 * time.Millisecond)
+			debouncer = NewDebouncer(debounce)
 		}
 	}
 
 	m := newModel(sections, resolvedVault, titleName, queryFile, queries, editorMode, cache, watcher, debouncer)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	configureModel(&m, cfg, scan, glyphs, absolutePaths, *reverse, poll)
+	m.defaultGroup, m.defaultSort = profileGroup, profileSort
+	m.parseWarnings = warnings
+	if len(queryFiles) > 1 {
+		m.queryFiles = queryFiles[1:]
+	}
+	m.allTasks = allTasks
+	if len(m.pins) > 0 {
+		m.rebuildSections()
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	// Set program for debouncer to send messages
 	if debouncer != nil {
@@ -419,7 +864,7 @@ func main() {
 }
 
 // loadAllProfileTabs loads all profiles as tabs for tabbed mode
-func loadAllProfileTabs(cfg Config) ([]ProfileTab, error) {
+func loadAllProfileTabs(cfg Config, scan scanOptions, reverse bool, debounce time.Duration) ([]ProfileTab, error) {
 	if len(cfg.Profiles) == 0 {
 		return nil, nil
 	}
@@ -451,17 +896,26 @@ func loadAllProfileTabs(cfg Config) ([]ProfileTab, error) {
 		}
 
 		// Scan vault
-		_, allTasks, cache, scanErr := RunWithLoaderProgress(resolved.VaultPath, true)
-		if scanErr != nil {
-			fmt.Printf("Warning: skipping profile %q: %v\n", name, scanErr)
+		result := RunWithLoaderProgress(resolved.VaultPath, scan, true)
+		if errors.Is(result.Error, ErrScanCancelled) {
+			return nil, result.Error
+		}
+		if result.Error != nil {
+			fmt.Printf("Warning: skipping profile %q: %v\n", name, result.Error)
 			continue
 		}
+		allTasks, cache := result.Tasks, result.Cache
 
-		// Resolve queries
+		// Resolve queries; a broken query file falls back to the default
+		// query and is reported with the profile's warnings
 		var queries []*Query
+		var warnings []ParseWarning
 		if resolved.QueryIsFile {
-			queries, err = parseAllQueryBlocks(resolved.Query)
+			queries, warnings, err = parseQueryFiles([]string{resolved.Query}, cfg.QueryBlock)
 			if err != nil {
+				if len(warnings) == 0 {
+					warnings = append(warnings, ParseWarning{File: resolved.Query, Err: err})
+				}
 				queries = []*Query{{NotDone: true, SortBy: "priority"}}
 			}
 		} else if resolved.Query != "" {
@@ -470,21 +924,32 @@ func loadAllProfileTabs(cfg Config) ([]ProfileTab, error) {
 				queries = []*Query{{NotDone: true, SortBy: "priority"}}
 			}
 		} else {
-			queries = []*Query{{NotDone: true, SortBy: "priority"}}
+			queries = defaultQueries(cfg.DefaultQuery)
 		}
 
 		// Build sections
 		var sections []QuerySection
-		for _, query := range queries {
+		for i, query := range queries {
+			query = withProfileDefaults(query, resolved.Group, resolved.Sort)
 			filtered := filterTasks(allTasks, query)
-			groups := groupTasks(filtered, query.GroupBy, query.SortBy, resolved.VaultPath)
+			groups := groupTasksBy(filtered, query.GroupBy, query.SortBy, resolved.VaultPath)
+			if reverse {
+				groups = reverseGroups(groups)
+			}
+			if query.CompletedLast {
+				groups = completedLast(groups)
+			}
+			groups, hidden := limitGroups(groups, query.Limit)
 			sections = append(sections, QuerySection{
 				Name:   query.Name,
 				Query:  query,
+				Index:  i,
 				Groups: groups,
 				Tasks:  filtered,
+				Hidden: hidden,
 			})
 		}
+		sections = orderSections(sections, cfg.SectionOrder)
 
 		// Build tasks list from groups to match View iteration order
 		var tasks []*Task
@@ -495,21 +960,27 @@ func loadAllProfileTabs(cfg Config) ([]ProfileTab, error) {
 		}
 
 		// Create watcher
-		watcher, _ := NewWatcher(resolved.VaultPath)
+		queryFile := ""
+		if resolved.QueryIsFile {
+			queryFile = resolved.Query
+		}
+		watcher, _ := NewWatcher(resolved.VaultPath, queryFile, scan)
 		var debouncer *Debouncer
 		if watcher != nil {
-			debouncer = NewDebouncer(150 * time.Millisecond)
+			debouncer = NewDebouncer(debounce)
 		}
 
 		tabs = append(tabs, ProfileTab{
 			Profile:   resolved,
 			Sections:  sections,
 			Tasks:     tasks,
+			AllTasks:  allTasks,
 			Cursor:    0,
 			Cache:     cache,
 			Watcher:   watcher,
 			Debouncer: debouncer,
 			Queries:   queries,
+			Warnings:  append(warnings, result.Warnings...),
 		})
 	}
 
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fsnotify/fsnotify"
+	"github.com/muesli/termenv"
 )
 
 func TestTaskToggle(t *testing.T) {
@@ -102,7 +112,7 @@ Some text here.
 		t.Fatalf("Failed to create test file: %v", err)
 	}
 
-	tasks, err := parseFile(testFile)
+	tasks, err := parseFile(testFile, nil)
 	if err != nil {
 		t.Fatalf("parseFile failed: %v", err)
 	}
@@ -203,7 +213,7 @@ func TestScanVault(t *testing.T) {
 	os.WriteFile(filepath.Join(tmpDir, ".obsidian", "config.md"), []byte("config"), 0644) // Should be skipped
 	os.WriteFile(filepath.Join(tmpDir, "readme.txt"), []byte("text file"), 0644)          // Should be skipped
 
-	files, err := scanVault(tmpDir)
+	files, err := scanVault(tmpDir, scanOptions{})
 	if err != nil {
 		t.Fatalf("scanVault failed: %v", err)
 	}
@@ -239,7 +249,7 @@ func TestSaveTask(t *testing.T) {
 	}
 
 	// Parse and modify a task
-	tasks, _ := parseFile(testFile)
+	tasks, _ := parseFile(testFile, nil)
 	tasks[1].Toggle() // Toggle "Task two"
 
 	// Save the task
@@ -271,6 +281,110 @@ func TestSaveTask(t *testing.T) {
 	}
 }
 
+func TestSaveTaskLineMoved(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.md")
+
+	content := "- [ ] Task one\n- [ ] Task two\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tasks, _ := parseFile(testFile, nil)
+
+	// Lines inserted above the task after it was parsed
+	content = "# Heading\n\n" + content
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to rewrite test file: %v", err)
+	}
+
+	tasks[1].Toggle()
+	if err := saveTask(tasks[1]); err != nil {
+		t.Fatalf("saveTask failed: %v", err)
+	}
+
+	saved, _ := os.ReadFile(testFile)
+	lines := strings.Split(string(saved), "\n")
+
+	if lines[0] != "# Heading" {
+		t.Errorf("Expected heading to be untouched, got: %s", lines[0])
+	}
+	if lines[2] != "- [ ] Task one" {
+		t.Errorf("Expected Task one to be untouched, got: %s", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "- [x] Task two") {
+		t.Errorf("Expected Task two to be toggled, got: %s", lines[3])
+	}
+	if tasks[1].LineNumber != 4 {
+		t.Errorf("Expected LineNumber to follow the task to 4, got %d", tasks[1].LineNumber)
+	}
+}
+
+func TestSaveTaskLineChanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.md")
+
+	if err := os.WriteFile(testFile, []byte("- [ ] Task one\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tasks, _ := parseFile(testFile, nil)
+
+	if err := os.WriteFile(testFile, []byte("- [ ] Rewritten elsewhere\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite test file: %v", err)
+	}
+
+	tasks[0].Toggle()
+	err := saveTask(tasks[0])
+	if !errors.Is(err, ErrTaskLineChanged) {
+		t.Fatalf("Expected ErrTaskLineChanged, got %v", err)
+	}
+
+	saved, _ := os.ReadFile(testFile)
+	if string(saved) != "- [ ] Rewritten elsewhere\n" {
+		t.Errorf("Expected file to be left untouched, got: %q", string(saved))
+	}
+}
+
+func TestWriteFileAtomicCleansUpOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Renaming a file over a directory fails after the temp file is written
+	target := filepath.Join(tmpDir, "target.md")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "keep.md"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if err := writeFileAtomic(target, []byte("- [ ] Task\n")); err == nil {
+		t.Fatal("Expected writeFileAtomic to fail")
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(tmpDir, "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Errorf("Expected temp file to be removed, found %v", leftovers)
+	}
+}
+
+func TestWriteFileAtomicPermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "tasks.md")
+
+	if err := writeFileAtomic(target, []byte("- [ ] Task\n")); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected permissions 0644, got %v", info.Mode().Perm())
+	}
+}
+
 func TestParseQueryFileGroupBy(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -319,8 +433,8 @@ func TestParseQueryFileGroupBy(t *testing.T) {
 				t.Fatalf("parseQueryFileExtended failed: %v", err)
 			}
 
-			if query.GroupBy != tt.wantGroupBy {
-				t.Errorf("GroupBy = %q, want %q", query.GroupBy, tt.wantGroupBy)
+			if got := strings.Join(query.GroupBy, ", "); got != tt.wantGroupBy {
+				t.Errorf("GroupBy = %q, want %q", got, tt.wantGroupBy)
 			}
 		})
 	}
@@ -401,7 +515,7 @@ func TestParseDueDate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseDueDate(tt.description)
+			got := defaultTaskFormat.parseDueDate(tt.description)
 			if tt.wantNil {
 				if got != nil {
 					t.Errorf("Expected nil, got %v", got)
@@ -554,6 +668,57 @@ func TestSortTasksByPriority(t *testing.T) {
 	}
 }
 
+func TestSortTasksByCreatedAndScheduled(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return &d
+	}
+
+	tasks := []*Task{
+		{Description: "none"},
+		{Description: "late", CreatedDate: date("2025-03-01"), ScheduledDate: date("2025-03-01")},
+		{Description: "none too"},
+		{Description: "early", CreatedDate: date("2025-01-01"), ScheduledDate: date("2025-01-01")},
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{"created", []string{"early", "late", "none", "none too"}},
+		{"scheduled", []string{"early", "late", "none", "none too"}},
+		{"created reverse", []string{"late", "early", "none", "none too"}},
+		{"scheduled reverse", []string{"late", "early", "none", "none too"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			sorted := sortTasks(tasks, tt.sortBy)
+			for i, task := range sorted {
+				if task.Description != tt.want[i] {
+					t.Errorf("At index %d: expected %q, got %q", i, tt.want[i], task.Description)
+				}
+			}
+		})
+	}
+
+	if q := parseQueryContent("sort by scheduled reverse"); q.SortBy != "scheduled reverse" {
+		t.Errorf("Expected sort clause to keep reverse, got %q", q.SortBy)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] Plan ⏳ 2025-02-03 ➕ 2025-01-30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parsedTasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := parsedTasks[0]
+	if parsed.ScheduledDate == nil || parsed.ScheduledDate.Day() != 3 {
+		t.Errorf("Expected scheduled date to be parsed, got %v", parsed.ScheduledDate)
+	}
+}
+
 func TestParseQueryFileDateFilters(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -1080,7 +1245,7 @@ func TestResolveQuery(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			queries, err := resolveQuery(tt.input, tt.vaultPath)
+			queries, err := resolveQuery(tt.input, tt.vaultPath, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("resolveQuery() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1138,8 +1303,66 @@ func TestParseInlineQuery(t *testing.T) {
 			if q.NotDone != tt.wantNotDone {
 				t.Errorf("NotDone = %v, want %v", q.NotDone, tt.wantNotDone)
 			}
-			if q.GroupBy != tt.wantGroupBy {
-				t.Errorf("GroupBy = %q, want %q", q.GroupBy, tt.wantGroupBy)
+			if got := strings.Join(q.GroupBy, ", "); got != tt.wantGroupBy {
+				t.Errorf("GroupBy = %q, want %q", got, tt.wantGroupBy)
+			}
+		})
+	}
+}
+
+func TestParseQueryContentDone(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantNotDone  bool
+		wantDoneOnly bool
+		wantFilters  int
+	}{
+		{name: "done", input: "done", wantDoneOnly: true},
+		{name: "done with whitespace", input: "  done  \nsort by due", wantDoneOnly: true},
+		{name: "not done", input: "not done", wantNotDone: true},
+		{name: "done before date", input: "done before 2025-01-01", wantFilters: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := parseQueryContent(tt.input)
+			if q.NotDone != tt.wantNotDone {
+				t.Errorf("NotDone = %v, want %v", q.NotDone, tt.wantNotDone)
+			}
+			if q.DoneOnly != tt.wantDoneOnly {
+				t.Errorf("DoneOnly = %v, want %v", q.DoneOnly, tt.wantDoneOnly)
+			}
+			if len(q.DateFilters) != tt.wantFilters {
+				t.Errorf("DateFilters = %d, want %d", len(q.DateFilters), tt.wantFilters)
+			}
+		})
+	}
+
+	tasks := []*Task{{Description: "open"}, {Description: "closed", Done: true}}
+	filtered := filterTasks(tasks, &Query{DoneOnly: true})
+	if len(filtered) != 1 || filtered[0].Description != "closed" {
+		t.Errorf("Expected only the completed task, got %d tasks", len(filtered))
+	}
+}
+
+func TestParseQueryContentNotDoneWholeLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "plain", input: "not done", want: true},
+		{name: "padded", input: "\t not done  \ngroup by folder", want: true},
+		{name: "in sentence", input: "this is not done yet", want: false},
+		{name: "prefixed", input: "# not done\nsort by due", want: false},
+		{name: "suffixed", input: "not done please", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQueryContent(tt.input).NotDone; got != tt.want {
+				t.Errorf("NotDone = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -1346,7 +1569,7 @@ func TestDeleteAndRestoreTask(t *testing.T) {
 	}
 
 	// Parse tasks
-	tasks, err := parseFile(testFile)
+	tasks, err := parseFile(testFile, nil)
 	if err != nil {
 		t.Fatalf("parseFile failed: %v", err)
 	}
@@ -1367,13 +1590,13 @@ func TestDeleteAndRestoreTask(t *testing.T) {
 	savedLineNumber := taskTwo.LineNumber
 
 	// Delete the task
-	err = deleteTask(taskTwo)
+	_, err = deleteTask(taskTwo)
 	if err != nil {
 		t.Fatalf("deleteTask failed: %v", err)
 	}
 
 	// Verify it's deleted
-	tasksAfterDelete, _ := parseFile(testFile)
+	tasksAfterDelete, _ := parseFile(testFile, nil)
 	for _, task := range tasksAfterDelete {
 		if task.Description == "Task two" {
 			t.Error("Task two should have been deleted")
@@ -1387,7 +1610,7 @@ func TestDeleteAndRestoreTask(t *testing.T) {
 	}
 
 	// Verify it's restored
-	tasksAfterRestore, _ := parseFile(testFile)
+	tasksAfterRestore, _ := parseFile(testFile, nil)
 	found := false
 	for _, task := range tasksAfterRestore {
 		if task.Description == "Task two" {
@@ -1476,3 +1699,4421 @@ func TestIsRecentlyToggledIgnoresDeleteEntries(t *testing.T) {
 		t.Error("Priority change entries should not cause isRecentlyToggled to return true")
 	}
 }
+
+func newTestModel(t *testing.T, tasks []*Task) model {
+	t.Helper()
+
+	query := &Query{}
+	sections := []QuerySection{{
+		Query:  query,
+		Groups: groupTasks(tasks, "", "", ""),
+		Tasks:  tasks,
+	}}
+
+	return newModel(sections, "", "test", "", []*Query{query}, "", nil, nil, nil)
+}
+
+func TestMouseClickSelectsAndToggles(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.md")
+
+	content := "- [ ] Task one\n- [ ] Task two\n- [ ] Task three\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tasks, err := parseFile(testFile, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+
+	m := newTestModel(t, tasks)
+
+	// Row 0 is the header, so the second task is rendered on row 2
+	updated, _ := m.Update(tea.MouseMsg{X: 30, Y: 2, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	m = updated.(model)
+
+	if m.cursor != 1 {
+		t.Errorf("Expected cursor on task 1, got %d", m.cursor)
+	}
+	if tasks[1].Done {
+		t.Error("Clicking the description should not toggle the task")
+	}
+
+	updated, _ = m.Update(tea.MouseMsg{X: 3, Y: 3, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	m = updated.(model)
+
+	if m.cursor != 2 {
+		t.Errorf("Expected cursor on task 2, got %d", m.cursor)
+	}
+	if !tasks[2].Done {
+		t.Error("Clicking the checkbox should toggle the task")
+	}
+
+	updated, _ = m.Update(tea.MouseMsg{Button: tea.MouseButtonWheelUp, Action: tea.MouseActionPress})
+	m = updated.(model)
+
+	if m.cursor != 1 {
+		t.Errorf("Expected wheel up to move cursor to 1, got %d", m.cursor)
+	}
+}
+
+func TestRefreshKeepsSectionsWhenQueryFileMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+	queryFile := filepath.Join(tmpDir, "query.md")
+
+	if err := os.WriteFile(taskFile, []byte("- [ ] Task one\n- [ ] Task two\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+	if err := os.WriteFile(queryFile, []byte("## Open\n\n```tasks\nnot done\n```\n"), 0644); err != nil {
+		t.Fatalf("Failed to create query file: %v", err)
+	}
+
+	queries, err := parseAllQueryBlocks(queryFile, "")
+	if err != nil {
+		t.Fatalf("parseAllQueryBlocks failed: %v", err)
+	}
+
+	m := newModel(nil, tmpDir, "test", queryFile, queries, "", nil, nil, nil)
+	m.refresh()
+
+	if len(m.tasks) != 2 {
+		t.Fatalf("Expected 2 tasks after first refresh, got %d", len(m.tasks))
+	}
+
+	if err := os.Remove(queryFile); err != nil {
+		t.Fatalf("Failed to remove query file: %v", err)
+	}
+
+	m.refresh()
+
+	if m.err != nil {
+		t.Errorf("Expected no hard error, got %v", m.err)
+	}
+	if m.warning == "" {
+		t.Error("Expected a warning about the missing query file")
+	}
+	if len(m.sections) != 1 || m.sections[0].Name != "Open" || len(m.tasks) != 2 {
+		t.Errorf("Expected previous sections to be kept, got %d sections and %d tasks", len(m.sections), len(m.tasks))
+	}
+
+	// Restoring the file clears the warning on the next refresh
+	if err := os.WriteFile(queryFile, []byte("```tasks\nnot done\n```\n"), 0644); err != nil {
+		t.Fatalf("Failed to recreate query file: %v", err)
+	}
+
+	m.refresh()
+
+	if m.warning != "" {
+		t.Errorf("Expected warning to clear, got %q", m.warning)
+	}
+}
+
+func TestFileChangeFromEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       fsnotify.Event
+		wantOK      bool
+		wantDeleted bool
+	}{
+		{"write markdown", fsnotify.Event{Name: "/v/a.md", Op: fsnotify.Write}, true, false},
+		{"create markdown", fsnotify.Event{Name: "/v/a.md", Op: fsnotify.Create}, true, false},
+		{"remove markdown", fsnotify.Event{Name: "/v/a.md", Op: fsnotify.Remove}, true, true},
+		{"rename markdown", fsnotify.Event{Name: "/v/a.md", Op: fsnotify.Rename}, true, true},
+		{"uppercase extension", fsnotify.Event{Name: "/v/A.MD", Op: fsnotify.Write}, true, false},
+		{"chmod only", fsnotify.Event{Name: "/v/a.md", Op: fsnotify.Chmod}, false, false},
+		{"temp file", fsnotify.Event{Name: "/v/a.md.123.tmp", Op: fsnotify.Create}, false, false},
+		{"legacy temp file", fsnotify.Event{Name: "/v/a.md.tmp", Op: fsnotify.Write}, false, false},
+		{"other extension", fsnotify.Event{Name: "/v/a.txt", Op: fsnotify.Write}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := fileChangeFromEvent(tt.event, scanOptions{})
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && msg.Deleted != tt.wantDeleted {
+				t.Errorf("Deleted = %v, want %v", msg.Deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestFileChangeFromAtomicSaveSequence(t *testing.T) {
+	// The events an atomic save produces: temp file created, written and
+	// renamed, then the real file appears
+	events := []fsnotify.Event{
+		{Name: "/v/a.md.42.tmp", Op: fsnotify.Create},
+		{Name: "/v/a.md.42.tmp", Op: fsnotify.Write},
+		{Name: "/v/a.md.42.tmp", Op: fsnotify.Chmod},
+		{Name: "/v/a.md.42.tmp", Op: fsnotify.Rename},
+		{Name: "/v/a.md", Op: fsnotify.Create},
+	}
+
+	var msgs []FileChangeMsg
+	for _, event := range events {
+		if msg, ok := fileChangeFromEvent(event, scanOptions{}); ok {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("Expected a single FileChangeMsg, got %d: %v", len(msgs), msgs)
+	}
+	if msgs[0].Path != "/v/a.md" || msgs[0].Deleted {
+		t.Errorf("Unexpected message: %+v", msgs[0])
+	}
+}
+
+func TestWatcherPicksUpNewDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	w, err := NewWatcher(tmpDir, "", scanOptions{})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Close()
+
+	msgs := make(chan tea.Msg, 10)
+	go func() {
+		cmd := w.WatchCmd()
+		for {
+			msg := cmd()
+			if msg == nil {
+				return
+			}
+			msgs <- msg
+		}
+	}()
+
+	waitFor := func(path string) {
+		t.Helper()
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case msg := <-msgs:
+				if change, ok := msg.(FileChangeMsg); ok && change.Path == path {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Timed out waiting for change on %s", path)
+			}
+		}
+	}
+
+	subDir := filepath.Join(tmpDir, "new-folder")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	waitFor(subDir)
+
+	taskFile := filepath.Join(subDir, "tasks.md")
+	if err := os.WriteFile(taskFile, []byte("- [ ] Task\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+	waitFor(taskFile)
+}
+
+func TestWatcherQueryFileOutsideVault(t *testing.T) {
+	vaultDir := t.TempDir()
+	queryDir := t.TempDir()
+	queryFile := filepath.Join(queryDir, "query.md")
+
+	if err := os.WriteFile(queryFile, []byte("```tasks\nnot done\n```\n"), 0644); err != nil {
+		t.Fatalf("Failed to create query file: %v", err)
+	}
+
+	w, err := NewWatcher(vaultDir, queryFile, scanOptions{})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Close()
+
+	if _, ok := w.fileChange(fsnotify.Event{Name: queryFile, Op: fsnotify.Write}); !ok {
+		t.Error("Expected query file writes to trigger a change")
+	}
+	if _, ok := w.fileChange(fsnotify.Event{Name: filepath.Join(queryDir, "other.md"), Op: fsnotify.Write}); ok {
+		t.Error("Expected other files next to the query file to be ignored")
+	}
+	if _, ok := w.fileChange(fsnotify.Event{Name: filepath.Join(vaultDir, "notes.md"), Op: fsnotify.Write}); !ok {
+		t.Error("Expected vault files to still trigger a change")
+	}
+}
+
+func TestWatcherQueryFileInsideVault(t *testing.T) {
+	vaultDir := t.TempDir()
+	queryFile := filepath.Join(vaultDir, "query.md")
+
+	w, err := NewWatcher(vaultDir, queryFile, scanOptions{})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Close()
+
+	if w.queryDir != "" {
+		t.Errorf("Expected no extra watch for a query file inside the vault, got %s", w.queryDir)
+	}
+
+	msg, ok := w.fileChange(fsnotify.Event{Name: queryFile, Op: fsnotify.Write})
+	if !ok || msg.Path != queryFile {
+		t.Errorf("Expected a single change for the query file, got %+v (%v)", msg, ok)
+	}
+}
+
+func TestScanVaultContextCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.md"), []byte("- [ ] Task\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := scanVaultContext(ctx, tmpDir, scanOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseFilesStopsWhenCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var files []string
+	for i := 0; i < 5; i++ {
+		file := filepath.Join(tmpDir, fmt.Sprintf("note%d.md", i))
+		os.WriteFile(file, []byte("- [ ] Task\n"), 0644)
+		files = append(files, file)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	parsed := 0
+	tasks, _, err := parseFiles(ctx, files, nil, nil, func(i int, file string, tasksFound int) {
+		parsed++
+		if i == 1 {
+			cancel()
+		}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if parsed != 2 || len(tasks) != 2 {
+		t.Errorf("Expected parsing to stop after 2 files, parsed %d with %d tasks", parsed, len(tasks))
+	}
+	if scanError(err) != ErrScanCancelled {
+		t.Errorf("Expected cancellation to map to ErrScanCancelled, got %v", scanError(err))
+	}
+}
+
+func TestLoaderCancelsOnQuit(t *testing.T) {
+	cancelled := false
+	m := newLoaderModel(func() { cancelled = true })
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	lm := updated.(loaderModel)
+
+	if !cancelled || !lm.cancelled {
+		t.Error("Expected ctrl+c to cancel the scan")
+	}
+}
+
+func TestRefreshRunsInBackground(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+
+	if err := os.WriteFile(taskFile, []byte("- [ ] Task one\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	query := &Query{NotDone: true}
+	m := newModel(nil, tmpDir, "test", "", []*Query{query}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+
+	if err := os.WriteFile(taskFile, []byte("- [ ] Task one\n- [ ] Task two\n"), 0644); err != nil {
+		t.Fatalf("Failed to update task file: %v", err)
+	}
+	m.cache.Invalidate(taskFile)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = updated.(model)
+
+	if cmd == nil || !m.refreshing {
+		t.Fatal("Expected r to start a background refresh")
+	}
+	if len(m.tasks) != 1 {
+		t.Errorf("Expected tasks to be unchanged until the refresh completes, got %d", len(m.tasks))
+	}
+
+	msg := cmd()
+	if _, ok := msg.(refreshDoneMsg); !ok {
+		t.Fatalf("Expected refreshDoneMsg, got %T", msg)
+	}
+
+	updated, _ = m.Update(msg)
+	m = updated.(model)
+
+	if m.refreshing {
+		t.Error("Expected refreshing indicator to clear")
+	}
+	if len(m.tasks) != 2 {
+		t.Errorf("Expected 2 tasks after refresh, got %d", len(m.tasks))
+	}
+}
+
+func TestToggleShowDone(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+
+	if err := os.WriteFile(taskFile, []byte("- [ ] Open task\n- [x] Done task\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	query := &Query{NotDone: true}
+	m := newModel(nil, tmpDir, "test", "", []*Query{query}, "", nil, nil, nil)
+	m.refresh()
+
+	if len(m.tasks) != 1 {
+		t.Fatalf("Expected 1 open task, got %d", len(m.tasks))
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	m = updated.(model)
+
+	if !m.showDone || len(m.tasks) != 2 {
+		t.Errorf("Expected done tasks to be shown, got %d tasks", len(m.tasks))
+	}
+	if !query.NotDone {
+		t.Error("Expected the stored query to be left untouched")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	m = updated.(model)
+
+	if m.showDone || len(m.tasks) != 1 {
+		t.Errorf("Expected done tasks to be hidden again, got %d tasks", len(m.tasks))
+	}
+}
+
+func TestCycleRuntimeSort(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+
+	content := "- [ ] Charlie 📅 2025-01-01\n- [ ] Alpha ⏬\n- [ ] Bravo 🔺 📅 2025-02-01\n"
+	if err := os.WriteFile(taskFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	query := &Query{}
+	m := newModel(nil, tmpDir, "test", "", []*Query{query}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+
+	first := func() string {
+		return m.tasks[0].Description
+	}
+
+	expected := []struct {
+		sort  string
+		first string
+	}{
+		{"due", "Charlie 📅 2025-01-01"},
+		{"priority", "Bravo 🔺 📅 2025-02-01"},
+		{"description", "Alpha ⏬"},
+		{"", "Charlie 📅 2025-01-01"},
+	}
+
+	for _, want := range expected {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+		m = updated.(model)
+
+		if m.runtimeSort != want.sort {
+			t.Errorf("runtimeSort = %q, want %q", m.runtimeSort, want.sort)
+		}
+		if first() != want.first {
+			t.Errorf("sort %q: first task = %q, want %q", want.sort, first(), want.first)
+		}
+	}
+
+	// The runtime sort survives a refresh
+	m.cycleRuntimeSort()
+	m.refresh()
+	if m.runtimeSort != "due" || first() != "Charlie 📅 2025-01-01" {
+		t.Errorf("Expected runtime sort to persist across refresh, got %q", m.runtimeSort)
+	}
+	if query.SortBy != "" {
+		t.Errorf("Expected stored query to keep its sort, got %q", query.SortBy)
+	}
+}
+
+func TestRefreshKeepsSearchSelection(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+
+	if err := os.WriteFile(taskFile, []byte("- [ ] Buy milk\n- [ ] Buy bread\n- [ ] Call mom\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	query := &Query{NotDone: true}
+	m := newModel(nil, tmpDir, "test", "", []*Query{query}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+
+	m.searching = true
+	m.searchQuery = "buy"
+	m.filterBySearch()
+	m.searchNavigating = true
+	m.cursor = 1
+
+	if got := m.activeTasks()[m.cursor].Description; got != "Buy bread" {
+		t.Fatalf("Expected Buy bread selected, got %q", got)
+	}
+
+	// An external edit inserts a matching task above the selection
+	if err := os.WriteFile(taskFile, []byte("- [ ] Buy eggs\n- [ ] Buy milk\n- [ ] Buy bread\n- [ ] Call mom\n"), 0644); err != nil {
+		t.Fatalf("Failed to update task file: %v", err)
+	}
+	m.cache.Invalidate(taskFile)
+
+	m.refresh()
+
+	if !m.searchNavigating {
+		t.Error("Expected to stay in search navigation")
+	}
+	if len(m.filteredTasks) != 3 {
+		t.Fatalf("Expected 3 search results, got %d", len(m.filteredTasks))
+	}
+	if got := m.activeTasks()[m.cursor].Description; got != "Buy bread" {
+		t.Errorf("Expected cursor to stay on Buy bread, got %q", got)
+	}
+}
+
+func TestPinsRoundTrip(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	path, err := pinsPath()
+	if err != nil {
+		t.Fatalf("pinsPath failed: %v", err)
+	}
+
+	pins, err := loadPins(path)
+	if err != nil || pins != nil {
+		t.Fatalf("Expected no pins before saving, got %v (%v)", pins, err)
+	}
+
+	want := []Pin{{FilePath: "/vault/a.md", LineNumber: 3}, {FilePath: "/vault/b.md", LineNumber: 1}}
+	if err := savePins(path, want); err != nil {
+		t.Fatalf("savePins failed: %v", err)
+	}
+
+	got, err := loadPins(path)
+	if err != nil {
+		t.Fatalf("loadPins failed: %v", err)
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPrunePins(t *testing.T) {
+	vault := t.TempDir()
+	other := t.TempDir()
+
+	taskFile := filepath.Join(vault, "tasks.md")
+	otherFile := filepath.Join(other, "tasks.md")
+	for _, path := range []string{taskFile, otherFile} {
+		if err := os.WriteFile(path, []byte("- [ ] Task\n"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	tasks := []*Task{{FilePath: taskFile, LineNumber: 1}}
+	pins := []Pin{
+		{FilePath: taskFile, LineNumber: 1},
+		{FilePath: taskFile, LineNumber: 5},                        // no task on that line
+		{FilePath: filepath.Join(vault, "gone.md"), LineNumber: 1}, // file removed
+		{FilePath: otherFile, LineNumber: 1},                       // another vault
+	}
+
+	got, changed := prunePins(pins, vault, tasks)
+	if !changed {
+		t.Error("Expected dropped pins to be reported as a change")
+	}
+	if len(got) != 2 || got[0] != pins[0] || got[1] != pins[3] {
+		t.Errorf("Expected pins %v and %v to be kept, got %v", pins[0], pins[3], got)
+	}
+}
+
+func TestPinFollowsTaskWhenLinesMove(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+	if err := os.WriteFile(taskFile, []byte("- [ ] First\n- [ ] Second\n- [ ] Third\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	m := newModel(nil, tmpDir, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.loadPins()
+	m.refresh()
+
+	m.cursor = 2
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'*'}})
+	m = updated.(model)
+
+	pinnedDescription := func() string {
+		if len(m.sections) != 2 || len(m.sections[0].Tasks) != 1 {
+			t.Fatalf("Expected one pinned task, got %d sections", len(m.sections))
+		}
+		return m.sections[0].Tasks[0].Description
+	}
+
+	// An external edit removes a line above the pinned task
+	if err := os.WriteFile(taskFile, []byte("- [ ] Second\n- [ ] Third\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite task file: %v", err)
+	}
+	m.cache.Invalidate(taskFile)
+	m.refresh()
+	if got := pinnedDescription(); got != "Third" {
+		t.Errorf("Expected the pin to follow Third after a removed line, got %q", got)
+	}
+
+	// A task added above the pinned one pushes it down
+	if _, err := addTask(m.allTasks[0], "Inserted"); err != nil {
+		t.Fatalf("addTask failed: %v", err)
+	}
+	m.cache.Invalidate(taskFile)
+	m.refresh()
+	if got := pinnedDescription(); got != "Third" {
+		t.Errorf("Expected the pin to follow Third after an added line, got %q", got)
+	}
+	if m.pins[0].LineNumber != 3 {
+		t.Errorf("Expected the pin to move to line 3, got %d", m.pins[0].LineNumber)
+	}
+}
+
+func TestTogglePinShowsPinnedSection(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	tmpDir := t.TempDir()
+	taskFile := filepath.Join(tmpDir, "tasks.md")
+	if err := os.WriteFile(taskFile, []byte("- [ ] First\n- [ ] Second\n"), 0644); err != nil {
+		t.Fatalf("Failed to create task file: %v", err)
+	}
+
+	m := newModel(nil, tmpDir, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.loadPins()
+	m.refresh()
+
+	m.cursor = 1
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'*'}})
+	m = updated.(model)
+
+	if len(m.sections) != 2 || m.sections[0].Name != pinnedSectionName {
+		t.Fatalf("Expected a pinned section first, got %d sections", len(m.sections))
+	}
+	if got := m.sections[0].Tasks[0].Description; got != "Second" {
+		t.Errorf("Expected Second to be pinned, got %q", got)
+	}
+	if got := m.tasks[m.cursor].Description; got != "Second" {
+		t.Errorf("Expected cursor to stay on Second, got %q", got)
+	}
+
+	// Pins persist for the next session
+	next := newModel(nil, tmpDir, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	next.loadPins()
+	next.refresh()
+	if len(next.sections) != 2 || next.sections[0].Name != pinnedSectionName {
+		t.Error("Expected the pin to be restored from disk")
+	}
+
+	// Unpinning removes the section
+	m.cursor = 0
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'*'}})
+	m = updated.(model)
+	if len(m.sections) != 1 {
+		t.Errorf("Expected the pinned section to disappear, got %d sections", len(m.sections))
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	styled := selectedStyle.Render("- [ ] A fairly long task description")
+
+	for _, width := range []int{1, 10, 20} {
+		got := truncateToWidth(styled, width)
+		if w := lipgloss.Width(got); w != width {
+			t.Errorf("width %d: got rendered width %d", width, w)
+		}
+		if !strings.HasSuffix(got, "…") {
+			t.Errorf("width %d: expected an ellipsis, got %q", width, got)
+		}
+	}
+
+	if got := truncateToWidth("short", 20); got != "short" {
+		t.Errorf("Expected a fitting line to be unchanged, got %q", got)
+	}
+
+	// Before the first WindowSizeMsg the width is unknown
+	if got := truncateToWidth(styled, 0); got != styled {
+		t.Errorf("Expected no limit at width 0, got %q", got)
+	}
+}
+
+func TestShortModeDropsFileSuffix(t *testing.T) {
+	tasks := []*Task{{Description: "A fairly long task description that won't fit", FilePath: "/vault/tasks.md", LineNumber: 1}}
+
+	m := newTestModel(t, tasks)
+	m.sections[0].Query.Short = true
+	m.windowWidth = 30
+
+	lines := m.sectionLines()
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 line, got %d", len(lines))
+	}
+	if strings.Contains(lines[0].content, "tasks.md") {
+		t.Error("Expected the file suffix to be omitted in short mode")
+	}
+	if w := lipgloss.Width(lines[0].content); w > m.windowWidth {
+		t.Errorf("Expected line to fit %d cells, got %d", m.windowWidth, w)
+	}
+}
+
+func TestKeymapRemapsActions(t *testing.T) {
+	tasks := []*Task{{Description: "One"}, {Description: "Two"}, {Description: "Three"}}
+
+	m := newTestModel(t, tasks)
+	m.keys = newKeymap(map[string]string{"down": "ctrl+n", "up": "ctrl+p, up"})
+
+	press := func(msg tea.KeyMsg) {
+		updated, _ := m.Update(msg)
+		m = updated.(model)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if m.cursor != 1 {
+		t.Errorf("Expected ctrl+n to move down, cursor = %d", m.cursor)
+	}
+
+	// The default binding is replaced, not added to
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if m.cursor != 1 {
+		t.Errorf("Expected j to be unbound, cursor = %d", m.cursor)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if m.cursor != 0 {
+		t.Errorf("Expected ctrl+p to move up, cursor = %d", m.cursor)
+	}
+
+	if got := m.keys.label(actionUp); got != "ctrl+p/↑" {
+		t.Errorf("label(up) = %q, want %q", got, "ctrl+p/↑")
+	}
+	if got := m.keys.label(actionToggle); got != "enter/space/x" {
+		t.Errorf("label(toggle) = %q, want %q", got, "enter/space/x")
+	}
+}
+
+func TestKeybindingConflicts(t *testing.T) {
+	got := keybindingConflicts(map[string]string{"edit": "e, d", "add": "u"})
+
+	want := []string{
+		`keybindings: "d" for edit is also bound to delete`,
+		`keybindings: "u" for add shadows a built-in key`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("keybindingConflicts = %q, want %q", got, want)
+	}
+
+	if got := keybindingConflicts(map[string]string{"down": "J"}); len(got) != 0 {
+		t.Errorf("Expected no conflicts for a free key, got %q", got)
+	}
+}
+
+func TestSearchTypesHelpKey(t *testing.T) {
+	tasks := []*Task{{Description: "What?"}, {Description: "Other"}}
+	m := newTestModel(t, tasks)
+
+	for _, r := range "/t?" {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(model)
+	}
+
+	if m.aboutOpen {
+		t.Error("Expected ? to be typed into the search, not open help")
+	}
+	if m.searchQuery != "t?" {
+		t.Errorf("searchQuery = %q, want %q", m.searchQuery, "t?")
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeybinding(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.toml")
+	content := "[keybindings]\ndown = \"ctrl+n\"\nfly = \"f\"\n"
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, _, err := loadConfigFrom(cfgPath)
+	if err == nil || !strings.Contains(err.Error(), `"fly"`) {
+		t.Errorf("Expected an unknown action error, got %v", err)
+	}
+}
+
+func TestWrapLongTask(t *testing.T) {
+	tasks := []*Task{
+		{Description: "Short"},
+		{Description: "A long task description that needs several rows to fit in a narrow window"},
+		{Description: "After"},
+	}
+
+	m := newTestModel(t, tasks)
+	m.wrap = true
+	m.windowWidth = 30
+	m.cursor = 1
+
+	lines := m.sectionLines()
+	heights, total := viewLineHeights(lines)
+	if heights[1] < 2 {
+		t.Fatalf("Expected the long task to wrap, got height %d", heights[1])
+	}
+	if total != heights[0]+heights[1]+heights[2] {
+		t.Errorf("Expected total %d to sum line heights", total)
+	}
+	for _, row := range strings.Split(lines[1].content, "\n") {
+		if w := lipgloss.Width(row); w > m.windowWidth {
+			t.Errorf("Expected wrapped row to fit %d cells, got %d: %q", m.windowWidth, w, row)
+		}
+	}
+
+	// A window shorter than the list still shows every row of the cursor line
+	visible := heights[1]
+	start, end := calculateVisibleRange(1, heights, visible)
+	if start != 1 || end != 2 {
+		t.Errorf("Expected only the wrapped cursor line visible, got [%d, %d)", start, end)
+	}
+}
+
+func TestScrollLeftStyled(t *testing.T) {
+	styled := selectedStyle.Render("0123456789")
+
+	got := scrollLeft(styled, 4)
+	if w := lipgloss.Width(got); w != 6 {
+		t.Errorf("Expected 6 cells after scrolling 4, got %d", w)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.Contains(got, "56789") {
+		t.Errorf("Expected the scrolled line to end in 56789, got %q", got)
+	}
+
+	// Scrolling past the end keeps the last cell visible
+	if got := scrollLeft(styled, 50); !strings.Contains(got, "9") {
+		t.Errorf("Expected the last cell to stay visible, got %q", got)
+	}
+}
+
+func TestLongLinesTruncateAndScroll(t *testing.T) {
+	tasks := []*Task{
+		{Description: "A long task description that does not fit in a narrow window", FilePath: "/vault/tasks.md", LineNumber: 1},
+		{Description: "Another long task description that does not fit either", FilePath: "/vault/tasks.md", LineNumber: 2},
+	}
+
+	m := newTestModel(t, tasks)
+	m.windowWidth = 30
+
+	for _, line := range m.sectionLines() {
+		if w := lipgloss.Width(line.content); w > m.windowWidth {
+			t.Errorf("Expected line to fit %d cells, got %d", m.windowWidth, w)
+		}
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(model)
+	if m.hOffset != hScrollStep || m.hOffsetTask != tasks[0] {
+		t.Fatalf("Expected the selected line to scroll, offset = %d", m.hOffset)
+	}
+
+	lines := m.sectionLines()
+	if !strings.Contains(lines[0].content, "…") || strings.Contains(lines[0].content, "A long") {
+		t.Errorf("Expected the selected line to be scrolled, got %q", lines[0].content)
+	}
+	if strings.Contains(lines[1].content, "…Another") {
+		t.Error("Expected other lines not to scroll")
+	}
+
+	// Moving to another task starts it from the left
+	m.cursor = 1
+	m.scrollHorizontal(-hScrollStep)
+	if m.hOffset != 0 || m.hOffsetTask != tasks[1] {
+		t.Errorf("Expected the scroll to reset for a new selection, offset = %d", m.hOffset)
+	}
+}
+
+func TestCreateTaskInEmptyView(t *testing.T) {
+	vault := t.TempDir()
+
+	m := newModel(nil, vault, "test", "", []*Query{{NotDone: true}}, "", NewTaskCache(), nil, nil)
+	m.inboxFile = "notes/inbox.md"
+	m.refresh()
+	if len(m.tasks) != 0 {
+		t.Fatalf("Expected an empty view, got %d tasks", len(m.tasks))
+	}
+
+	press := func(msg tea.KeyMsg) {
+		updated, _ := m.Update(msg)
+		m = updated.(model)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'N'}})
+	if !m.creating {
+		t.Fatal("Expected N to open the new task modal with no tasks listed")
+	}
+	if got := m.creatingFile.Value(); got != "notes/inbox.md" {
+		t.Errorf("Expected the inbox file as default target, got %q", got)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Buy milk")})
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+
+	if m.creating {
+		t.Error("Expected the modal to close after saving")
+	}
+	if cmd == nil {
+		t.Fatal("Expected saving to refresh in the background")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	content, err := os.ReadFile(filepath.Join(vault, "notes", "inbox.md"))
+	if err != nil {
+		t.Fatalf("Expected the inbox file to be created: %v", err)
+	}
+	if string(content) != "- [ ] Buy milk\n" {
+		t.Errorf("Unexpected inbox content %q", content)
+	}
+	if len(m.tasks) != 1 || m.tasks[0].Description != "Buy milk" {
+		t.Errorf("Expected the new task to be listed, got %d tasks", len(m.tasks))
+	}
+}
+
+func TestAppendTask(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	if err := os.WriteFile(path, []byte("# Tasks\n- [ ] First"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	task, err := appendTask(path, "Second", nil)
+	if err != nil {
+		t.Fatalf("appendTask failed: %v", err)
+	}
+	if task.LineNumber != 3 {
+		t.Errorf("Expected line 3, got %d", task.LineNumber)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "# Tasks\n- [ ] First\n- [ ] Second\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+}
+
+func TestAddTaskInsertsAfterReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Parent\n  - [ ] Child one\n  - [ ] Child two\n- [ ] Sibling\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+
+	added, err := addTask(tasks[1], "Child one and a half")
+	if err != nil {
+		t.Fatalf("addTask failed: %v", err)
+	}
+	if added.LineNumber != 3 {
+		t.Errorf("Expected the new task on line 3, got %d", added.LineNumber)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "- [ ] Parent\n  - [ ] Child one\n  - [ ] Child one and a half\n  - [ ] Child two\n- [ ] Sibling\n"
+	if string(got) != want {
+		t.Errorf("Unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The reference is found even after lines above it moved
+	if err := os.WriteFile(path, []byte("# Header\n"+want), 0644); err != nil {
+		t.Fatalf("Failed to rewrite file: %v", err)
+	}
+	if _, err := addTask(tasks[3], "After sibling"); err != nil {
+		t.Fatalf("addTask failed: %v", err)
+	}
+	got, _ = os.ReadFile(path)
+	if !strings.HasSuffix(string(got), "- [ ] Sibling\n- [ ] After sibling\n") {
+		t.Errorf("Expected the task after Sibling, got:\n%s", got)
+	}
+}
+
+func TestParseFileNesting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Project\n  - [ ] Step one\n\t- [ ] Detail\n  - [ ] Step two\n- [ ] Other\n# Next\n  - [ ] Loose\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+	if len(tasks) != 6 {
+		t.Fatalf("Expected 6 tasks, got %d", len(tasks))
+	}
+
+	tests := []struct {
+		indent int
+		parent *Task
+	}{
+		{0, nil},
+		{1, tasks[0]},
+		{2, tasks[1]},
+		{1, tasks[0]},
+		{0, nil},
+		{0, nil}, // a heading ends the list above it
+	}
+
+	for i, tt := range tests {
+		if tasks[i].Indent != tt.indent {
+			t.Errorf("%s: Indent = %d, want %d", tasks[i].Description, tasks[i].Indent, tt.indent)
+		}
+		if tasks[i].Parent != tt.parent {
+			t.Errorf("%s: unexpected parent", tasks[i].Description)
+		}
+	}
+
+	// Children render under a listed parent, flat when it's filtered out
+	m := newTestModel(t, tasks[:4])
+	lines := m.sectionLines()
+	if !strings.HasPrefix(lines[2].content, "    ") {
+		t.Errorf("Expected the detail indented two levels, got %q", lines[2].content)
+	}
+
+	if depths := nestingDepths([]*Task{tasks[0], tasks[2]}); depths[1] != 1 {
+		t.Errorf("Expected depth 1 with the middle parent hidden, got %d", depths[1])
+	}
+
+	// A subtask sorted away from its parent isn't indented under another task
+	if depths := nestingDepths([]*Task{tasks[0], tasks[4], tasks[1]}); !slices.Equal(depths, []int{0, 0, 0}) {
+		t.Errorf("Expected a separated subtask to render flat, got %v", depths)
+	}
+	if depths := nestingDepths([]*Task{tasks[0], tasks[1], tasks[2], tasks[3]}); !slices.Equal(depths, []int{0, 1, 2, 1}) {
+		t.Errorf("Expected depths [0 1 2 1], got %v", depths)
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	if strings.TrimSpace(version) == "" {
+		t.Fatal("Expected an embedded version")
+	}
+
+	oldSHA, oldDate := buildSHA, buildDate
+	t.Cleanup(func() { buildSHA, buildDate = oldSHA, oldDate })
+
+	buildSHA, buildDate = "", ""
+	if got := versionString(); !strings.Contains(got, strings.TrimSpace(version)) || !strings.HasSuffix(got, "(unknown)") {
+		t.Errorf("Unexpected version string %q", got)
+	}
+
+	buildSHA, buildDate = "abc123", "2025-01-02"
+	if got := versionString(); !strings.HasSuffix(got, "(abc123, built 2025-01-02)") {
+		t.Errorf("Expected build metadata in %q", got)
+	}
+}
+
+func TestInitConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path, err := configPath()
+	if err != nil {
+		t.Fatalf("configPath failed: %v", err)
+	}
+
+	if err := initConfig(path, false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected the template to load: %v", err)
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Expected the template to validate: %v", err)
+	}
+	if _, ok := cfg.Profiles[cfg.DefaultProfile]; !ok {
+		t.Errorf("Expected the template's default profile %q to exist", cfg.DefaultProfile)
+	}
+
+	if err := os.WriteFile(path, []byte("# mine\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit config: %v", err)
+	}
+	if err := initConfig(path, false); !errors.Is(err, ErrConfigExists) {
+		t.Errorf("Expected ErrConfigExists, got %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "# mine\n" {
+		t.Error("Expected the existing config to be left alone")
+	}
+
+	if err := initConfig(path, true); err != nil {
+		t.Fatalf("initConfig with force failed: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != configTemplate {
+		t.Error("Expected --force to overwrite the config")
+	}
+}
+
+func TestLoadAllProfileTabsCollectsWarnings(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, name := range []string{"work", "home"} {
+		vault := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(vault, 0755); err != nil {
+			t.Fatalf("Failed to create vault: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(vault, "tasks.md"), []byte("- [ ] Task\n"), 0644); err != nil {
+			t.Fatalf("Failed to create task file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "work", "query.md"), []byte("No query here\n"), 0644); err != nil {
+		t.Fatalf("Failed to create query file: %v", err)
+	}
+
+	cfg := Config{
+		Profiles: map[string]Profile{
+			"work": {Vault: "work", Query: "query.md"},
+			"home": {Vault: "home"},
+		},
+		baseDir: baseDir,
+	}
+
+	tabs, err := loadAllProfileTabs(cfg, scanOptions{}, false, time.Millisecond)
+	if err != nil {
+		t.Fatalf("loadAllProfileTabs failed: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, tab := range tabs {
+			if tab.Watcher != nil {
+				tab.Watcher.Close()
+			}
+		}
+	})
+
+	if len(tabs) != 2 {
+		t.Fatalf("Expected 2 tabs, got %d", len(tabs))
+	}
+	for _, tab := range tabs {
+		switch tab.Profile.Name {
+		case "work":
+			if len(tab.Warnings) != 1 || !errors.Is(tab.Warnings[0].Err, ErrNoQueryBlock) {
+				t.Errorf("Expected a missing query block warning for work, got %v", tab.Warnings)
+			}
+		case "home":
+			if len(tab.Warnings) != 0 {
+				t.Errorf("Expected no warnings for home, got %v", tab.Warnings)
+			}
+		}
+	}
+}
+
+func TestLoadAllProfileTabsReverses(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, name := range []string{"work", "home"} {
+		vault := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(vault, 0755); err != nil {
+			t.Fatalf("Failed to create vault: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(vault, "tasks.md"), []byte("- [ ] First\n- [ ] Second\n"), 0644); err != nil {
+			t.Fatalf("Failed to create task file: %v", err)
+		}
+	}
+
+	cfg := Config{
+		Profiles: map[string]Profile{"work": {Vault: "work"}, "home": {Vault: "home"}},
+		baseDir:  baseDir,
+	}
+
+	tabs, err := loadAllProfileTabs(cfg, scanOptions{}, true, time.Millisecond)
+	if err != nil {
+		t.Fatalf("loadAllProfileTabs failed: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, tab := range tabs {
+			if tab.Watcher != nil {
+				tab.Watcher.Close()
+			}
+		}
+	})
+
+	for _, tab := range tabs {
+		if len(tab.Tasks) != 2 || tab.Tasks[0].Description != "Second" {
+			t.Errorf("Expected %s tasks reversed, got %d tasks", tab.Profile.Name, len(tab.Tasks))
+		}
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	baseDir := t.TempDir()
+	vault := filepath.Join(baseDir, "vault")
+	if err := os.MkdirAll(vault, 0755); err != nil {
+		t.Fatalf("Failed to create vault: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "query.md"), []byte("```tasks\nnot done\n```\n"), 0644); err != nil {
+		t.Fatalf("Failed to create query file: %v", err)
+	}
+
+	cfg := Config{
+		Profiles: map[string]Profile{
+			"good": {Vault: "vault", Query: "query.md"},
+			"bad":  {Vault: "missing"},
+		},
+		baseDir: baseDir,
+	}
+
+	var out strings.Builder
+	if checkConfig(cfg, &out) {
+		t.Error("Expected the check to fail with a bad profile")
+	}
+
+	report := out.String()
+	if !strings.Contains(report, "OK    good") {
+		t.Errorf("Expected good to pass, got:\n%s", report)
+	}
+	if !strings.Contains(report, `FAIL  profile "bad": vault`) {
+		t.Errorf("Expected bad to fail on its vault, got:\n%s", report)
+	}
+
+	delete(cfg.Profiles, "bad")
+	out.Reset()
+	if !checkConfig(cfg, &out) {
+		t.Errorf("Expected the check to pass, got:\n%s", out.String())
+	}
+}
+
+func TestListProfiles(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(baseDir, "work"), 0755); err != nil {
+		t.Fatalf("Failed to create vault: %v", err)
+	}
+
+	cfg := Config{
+		DefaultProfile: "work",
+		Profiles: map[string]Profile{
+			"work":     {Vault: "work"},
+			"personal": {Vault: "notes"},
+		},
+		baseDir: baseDir,
+	}
+
+	var out strings.Builder
+	listProfiles(cfg, &out)
+
+	want := fmt.Sprintf("  personal  %s  (missing)\n* work      %s\n", filepath.Join(baseDir, "notes"), filepath.Join(baseDir, "work"))
+	if got := out.String(); got != want {
+		t.Errorf("Unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestScanVaultOtignore(t *testing.T) {
+	vault := t.TempDir()
+
+	files := map[string]string{
+		"tasks.md":                 "- [ ] Keep\n",
+		"attachments/export.md":    "- [ ] Ignored subtree\n",
+		"attachments/deep/more.md": "- [ ] Ignored too\n",
+		"notes/draft.tmp.md":       "- [ ] Ignored by glob\n",
+		"notes/keep.tmp.md":        "- [ ] Re-included\n",
+		"notes/build/out.md":       "- [ ] Ignored anywhere\n",
+		"build.md":                 "- [ ] Not a directory\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(vault, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	ignore := "# big folders\n/attachments\n*.tmp.md\n!keep.tmp.md\nbuild/\n"
+	if err := os.WriteFile(filepath.Join(vault, ignoreFileName), []byte(ignore), 0644); err != nil {
+		t.Fatalf("Failed to create ignore file: %v", err)
+	}
+
+	found, err := scanVault(vault, scanOptions{})
+	if err != nil {
+		t.Fatalf("scanVault failed: %v", err)
+	}
+
+	var got []string
+	for _, path := range found {
+		rel, _ := filepath.Rel(vault, path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+
+	want := []string{"build.md", "notes/keep.tmp.md", "tasks.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestScanVaultFollowSymlinks(t *testing.T) {
+	vault := t.TempDir()
+	shared := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(vault, "local.md"), []byte("- [ ] Local\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(shared, "shared.md"), []byte("- [ ] Shared\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Symlink(shared, filepath.Join(vault, "shared")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	// A link back to the vault must not loop
+	if err := os.Symlink(vault, filepath.Join(shared, "back")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	files, err := scanVault(vault, scanOptions{})
+	if err != nil {
+		t.Fatalf("scanVault failed: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected symlinks to be ignored by default, got %v", files)
+	}
+
+	files, err = scanVault(vault, scanOptions{followSymlinks: true})
+	if err != nil {
+		t.Fatalf("scanVault failed: %v", err)
+	}
+
+	want := []string{filepath.Join(vault, "local.md"), filepath.Join(vault, "shared", "shared.md")}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	tasks := []*Task{
+		{Description: "One", FilePath: "/a/very/long/path/to/some/deeply/nested/notes/tasks.md", LineNumber: 7},
+		{Description: "Two", FilePath: "/a/tasks.md", LineNumber: 2},
+	}
+
+	m := newTestModel(t, tasks)
+	m.windowWidth = 40
+
+	status := m.statusLine()
+	if w := lipgloss.Width(status); w != m.windowWidth {
+		t.Errorf("Expected the status line to fill %d cells, got %d", m.windowWidth, w)
+	}
+	if !strings.Contains(status, "tasks.md:7") || !strings.Contains(status, "…") {
+		t.Errorf("Expected the path cut from the left, got %q", status)
+	}
+	if !strings.Contains(status, "1/2") {
+		t.Errorf("Expected the cursor position, got %q", status)
+	}
+
+	if strings.Count(m.footerView("help", 3), "\n") != 2 || strings.Contains(m.footerView("help", 3), "tasks.md") {
+		t.Error("Expected no status line unless enabled")
+	}
+	m.statusBar = true
+	if !strings.Contains(m.footerView("help", 3), "tasks.md:7") {
+		t.Error("Expected the status line above the help line")
+	}
+}
+
+func TestHelpModalScrollsAndFilters(t *testing.T) {
+	m := newTestModel(t, []*Task{{Description: "One"}})
+	m.windowWidth = 40
+	m.windowHeight = 10
+
+	press := func(keys ...string) {
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			switch key {
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			}
+			updated, _ := m.Update(msg)
+			m = updated.(model)
+		}
+	}
+
+	press("?")
+	if !m.aboutOpen {
+		t.Fatal("Expected ? to open help")
+	}
+
+	maxScroll := m.aboutMaxScroll()
+	if maxScroll == 0 {
+		t.Fatal("Expected the help to overflow a 10 row window")
+	}
+	for i := 0; i < maxScroll+5; i++ {
+		press("j")
+	}
+	if m.aboutScroll != maxScroll {
+		t.Errorf("Expected scrolling to stop at %d, got %d", maxScroll, m.aboutScroll)
+	}
+	if h := lipgloss.Height(m.View()); h > m.windowHeight {
+		t.Errorf("Expected the help to fit %d rows, got %d", m.windowHeight, h)
+	}
+	press("k")
+	if m.aboutScroll != maxScroll-1 {
+		t.Errorf("Expected k to scroll up, got %d", m.aboutScroll)
+	}
+
+	press("/", "p", "i", "n", "enter")
+	if m.aboutFiltering || m.aboutFilter != "pin" || m.aboutScroll != 0 {
+		t.Fatalf("Expected the filter to be set, got %q", m.aboutFilter)
+	}
+	view := m.View()
+	if !strings.Contains(view, "pin/unpin") || strings.Contains(view, "cycle sort") {
+		t.Errorf("Expected only matching bindings, got:\n%s", view)
+	}
+
+	// esc clears the filter first, then closes
+	press("esc")
+	if !m.aboutOpen || m.aboutFilter != "" {
+		t.Error("Expected esc to clear the filter")
+	}
+	press("esc")
+	if m.aboutOpen {
+		t.Error("Expected esc to close help")
+	}
+
+	// A tall terminal has nothing to scroll
+	m.windowHeight = 200
+	m.windowWidth = 200
+	if got := m.aboutMaxScroll(); got != 0 {
+		t.Errorf("Expected no scrolling on a tall terminal, got %d", got)
+	}
+}
+
+func TestDateFormatWritesAndParses(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", defaultDateFormat},
+		{"datetime", "2006-01-02 15:04"},
+		{"02.01.2006", "02.01.2006"},
+		{"not a layout", defaultDateFormat},
+		{"2006", defaultDateFormat},
+	}
+	for _, tt := range tests {
+		if got := resolveDateFormat(tt.value); got != tt.want {
+			t.Errorf("resolveDateFormat(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	format := newTaskFormat("02.01.2006")
+
+	task := &Task{RawLine: "- [ ] Ship it 📅 20.03.2024", Description: "Ship it 📅 20.03.2024", Format: format}
+	task.Toggle()
+
+	today := time.Now().Format("02.01.2006")
+	if !strings.HasSuffix(task.RawLine, "✅ "+today) {
+		t.Fatalf("Expected done date in custom format, got %q", task.RawLine)
+	}
+
+	done := format.parseDoneDate(task.RawLine)
+	if done == nil || done.Format("2006-01-02") != time.Now().Format("2006-01-02") {
+		t.Errorf("Expected to re-parse today's done date, got %v", done)
+	}
+
+	due := format.parseDueDate(task.RawLine)
+	if due == nil || due.Format("2006-01-02") != "2024-03-20" {
+		t.Errorf("Expected due date 2024-03-20, got %v", due)
+	}
+
+	// The default layout is still recognized alongside the custom one
+	if due := format.parseDueDate("Old 📅 2024-01-05"); due == nil || due.Day() != 5 {
+		t.Errorf("Expected default layout to parse, got %v", due)
+	}
+
+	task.Toggle()
+	if task.RawLine != "- [ ] Ship it 📅 20.03.2024" {
+		t.Errorf("Expected done date to be stripped, got %q", task.RawLine)
+	}
+
+	// Parsed tasks keep the format they were read with
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] Ship it 📅 20.03.2024\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	tasks, err := parseFile(path, format)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+	if tasks[0].DueDate == nil || tasks[0].DueDate.Format("2006-01-02") != "2024-03-20" {
+		t.Errorf("Expected due date 2024-03-20, got %v", tasks[0].DueDate)
+	}
+	tasks[0].Toggle()
+	if !strings.HasSuffix(tasks[0].RawLine, "✅ "+today) {
+		t.Errorf("Expected the parsed task to write its done date in the custom format, got %q", tasks[0].RawLine)
+	}
+}
+
+func TestEditDoneTaskKeepsDoneDate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.md")
+	if err := os.WriteFile(path, []byte("- [x] Write report ✅ 2023-04-01\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := tasks[0]
+	if task.DoneDate == nil || task.DoneDate.Format("2006-01-02") != "2023-04-01" {
+		t.Fatalf("Expected done date to be parsed, got %v", task.DoneDate)
+	}
+
+	// The editor may drop the marker; the original date comes back
+	task.Description = "Write final report"
+	task.rebuildRawLine()
+	if task.RawLine != "- [x] Write final report ✅ 2023-04-01" {
+		t.Errorf("Expected original done date to be kept, got %q", task.RawLine)
+	}
+	if err := saveTask(task); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "✅ 2023-04-01") {
+		t.Errorf("Expected saved line to keep the done date, got %q", content)
+	}
+
+	// Undoing and redoing is a new completion
+	task.Toggle()
+	task.Toggle()
+	today := time.Now().Format("2006-01-02")
+	if !strings.HasSuffix(task.RawLine, "✅ "+today) || strings.Contains(task.RawLine, "2023-04-01") {
+		t.Errorf("Expected a fresh done date after re-toggling, got %q", task.RawLine)
+	}
+}
+
+func TestAddCreatedDate(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(vault, "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] First\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	m := newModel(nil, vault, "test", "", []*Query{{NotDone: true}}, "", NewTaskCache(), nil, nil)
+	m.addCreatedDate = true
+	m.refresh()
+
+	press := func(msg tea.KeyMsg) {
+		updated, _ := m.Update(msg)
+		m = updated.(model)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Second")})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+
+	today := time.Now().Format("2006-01-02")
+	content, _ := os.ReadFile(path)
+	if want := "- [ ] First\n- [ ] Second ➕ " + today + "\n"; string(content) != want {
+		t.Errorf("Expected %q, got %q", want, content)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks[0].CreatedDate != nil {
+		t.Error("Expected no created date on the first task")
+	}
+	if tasks[1].CreatedDate == nil || tasks[1].CreatedDate.Format("2006-01-02") != today {
+		t.Errorf("Expected created date %s, got %v", today, tasks[1].CreatedDate)
+	}
+}
+
+func TestParseWarningsRecorded(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "good.md"), []byte("- [ ] Fine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling link is listed by the scan but can't be opened
+	if err := os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	files, err := scanVault(vault, scanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, warnings, err := parseFiles(context.Background(), files, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || len(warnings) != 1 || filepath.Base(warnings[0].File) != "broken.md" {
+		t.Fatalf("Expected one task and a warning for broken.md, got %d tasks, %v", len(tasks), warnings)
+	}
+
+	m := newModel(nil, vault, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+	if len(m.parseWarnings) != 1 {
+		t.Fatalf("Expected the refresh to record 1 warning, got %d", len(m.parseWarnings))
+	}
+	if view := m.View(); !strings.Contains(view, "1 file failed to parse") {
+		t.Errorf("Expected a warning indicator in the footer, got:\n%s", view)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'W'}})
+	m = updated.(model)
+	if !m.warningsOpen || !strings.Contains(m.View(), "broken.md") {
+		t.Fatal("Expected W to list the problem file")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if m.warningsOpen {
+		t.Error("Expected esc to close the warnings list")
+	}
+}
+
+func TestFileManagerCommand(t *testing.T) {
+	for goos, want := range map[string]string{"darwin": "open", "linux": "xdg-open", "windows": "explorer"} {
+		c, err := fileManagerCommand(goos, "/vault/notes")
+		if err != nil {
+			t.Fatalf("%s: %v", goos, err)
+		}
+		if c.Args[0] != want || c.Args[1] != "/vault/notes" {
+			t.Errorf("%s: got %v", goos, c.Args)
+		}
+	}
+
+	if _, err := fileManagerCommand("plan9", "/vault"); !errors.Is(err, ErrNoFileManager) {
+		t.Errorf("Expected ErrNoFileManager, got %v", err)
+	}
+}
+
+func TestSectionLimitShowsMore(t *testing.T) {
+	if q := parseQueryContent("not done\nlimit to 5 tasks"); q.Limit != 5 {
+		t.Errorf("Expected limit 5, got %d", q.Limit)
+	}
+	if q := parseQueryContent("limit 3"); q.Limit != 3 {
+		t.Errorf("Expected limit 3, got %d", q.Limit)
+	}
+
+	var tasks []*Task
+	for i, name := range []string{"One", "Two", "Three", "Four", "Five"} {
+		tasks = append(tasks, &Task{Description: name, FilePath: "/vault/tasks.md", LineNumber: i + 1})
+	}
+
+	m := newModel(nil, "/vault", "test", "", []*Query{{Name: "Inbox", Limit: 2}}, "", nil, nil, nil)
+	m.allTasks = tasks
+	m.rebuildSections()
+
+	if len(m.tasks) != 2 {
+		t.Fatalf("Expected 2 tasks shown, got %d", len(m.tasks))
+	}
+	view := m.View()
+	if !strings.Contains(view, "… 3 more") || strings.Contains(view, "Three") {
+		t.Fatalf("Expected a more line in place of the hidden tasks, got:\n%s", view)
+	}
+	if !strings.Contains(view, "Inbox") || !strings.Contains(view, "(5)") {
+		t.Error("Expected the section count to include hidden tasks")
+	}
+
+	press := func(key string) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+
+	press("j")
+	press("j")
+	if !m.moreSelected || m.cursor != 1 {
+		t.Fatalf("Expected the cursor on the more line, cursor=%d more=%v", m.cursor, m.moreSelected)
+	}
+	press("k")
+	if m.moreSelected || m.cursor != 1 {
+		t.Fatal("Expected k to move back to the last shown task")
+	}
+	press("j")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if len(m.tasks) != 5 || m.moreSelected {
+		t.Fatalf("Expected enter to expand the section, got %d tasks", len(m.tasks))
+	}
+	if m.tasks[m.cursor].Description != "Three" {
+		t.Errorf("Expected the cursor on the first revealed task, got %q", m.tasks[m.cursor].Description)
+	}
+	if strings.Contains(m.View(), "more") {
+		t.Error("Expected no more line once expanded")
+	}
+}
+
+func TestExpandMoreOnlyExpandsItsSection(t *testing.T) {
+	var tasks []*Task
+	for i, name := range []string{"One", "Two", "Three"} {
+		tasks = append(tasks, &Task{Description: name, FilePath: "/vault/tasks.md", LineNumber: i + 1})
+	}
+
+	// Two unnamed sections share the empty name
+	queries := []*Query{{Limit: 1}, {Limit: 1}}
+	m := newModel(nil, "/vault", "test", "", queries, "", nil, nil, nil)
+	m.allTasks = tasks
+	m.rebuildSections()
+
+	m.cursor = 0
+	m.moreSelected = true
+	m.expandMore()
+
+	if m.sections[0].Hidden != 0 {
+		t.Errorf("Expected the first section expanded, %d hidden", m.sections[0].Hidden)
+	}
+	if m.sections[1].Hidden != 2 {
+		t.Errorf("Expected the second section to keep its limit, %d hidden", m.sections[1].Hidden)
+	}
+}
+
+func TestGroupByTitle(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"2024-05-01.md": "- [ ] Before the title\n# Weekly Review\n- [ ] Review goals\n",
+		"scratch.md":    "- [ ] No heading here\n## Only a subheading\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tasks []*Task
+	for _, name := range []string{"2024-05-01.md", "scratch.md"} {
+		parsed, err := parseFile(filepath.Join(vault, name), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, parsed...)
+	}
+
+	groups := groupTasks(tasks, "title", "", vault)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Name != "Weekly Review" || len(groups[0].Tasks) != 2 {
+		t.Errorf("Expected the H1 as group name for both tasks, got %q with %d tasks", groups[0].Name, len(groups[0].Tasks))
+	}
+	if groups[1].Name != "scratch.md" {
+		t.Errorf("Expected the filename without an H1, got %q", groups[1].Name)
+	}
+}
+
+func TestTaskMatchingTwoSectionsCountsOnce(t *testing.T) {
+	shared := &Task{Description: "Urgent and due", FilePath: "/vault/a.md", LineNumber: 1, Priority: PriorityHighest}
+	other := &Task{Description: "Just due", FilePath: "/vault/a.md", LineNumber: 2, Priority: PriorityNormal}
+
+	queries := []*Query{
+		{Name: "All"},
+		{Name: "Urgent", SortBy: "priority", Limit: 1},
+	}
+	tab := ProfileTab{Profile: &ResolvedProfile{Name: "work", VaultPath: "/vault"}, Queries: queries}
+	m := newModelWithTabs([]ProfileTab{tab})
+	m.allTasks = []*Task{shared, other}
+	m.rebuildSections()
+
+	// The shared task is listed, and reachable, under both sections
+	if len(m.tasks) != 3 || m.tasks[0] != shared || m.tasks[2] != shared {
+		t.Fatalf("Expected the shared task under each section, got %d rows", len(m.tasks))
+	}
+
+	press := func(key string) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+	press("G")
+	if m.cursor != 2 {
+		t.Errorf("Expected G to reach the last row, got %d", m.cursor)
+	}
+
+	if got := countUniqueTasks(m.tasks); got != 2 {
+		t.Errorf("Expected 2 unique tasks, got %d", got)
+	}
+	if bar := m.renderTabBar(); !strings.Contains(bar, "work (2)") {
+		t.Errorf("Expected the tab count to use unique tasks, got %q", bar)
+	}
+}
+
+func TestJumpSection(t *testing.T) {
+	var tasks []*Task
+	for i := 1; i <= 5; i++ {
+		tasks = append(tasks, &Task{Description: fmt.Sprintf("Task %d", i), FilePath: "/vault/a.md", LineNumber: i})
+	}
+	section := func(name string, tasks ...*Task) QuerySection {
+		return QuerySection{Name: name, Query: &Query{}, Groups: []TaskGroup{{Tasks: tasks}}, Tasks: tasks}
+	}
+	sections := []QuerySection{
+		section("First", tasks[0], tasks[1]),
+		section("Empty"),
+		section("Second", tasks[2], tasks[3]),
+		section("Third", tasks[4]),
+	}
+	m := newModel(sections, "/vault", "test", "", nil, "", nil, nil, nil)
+
+	press := func(key string) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+
+	for _, want := range []int{2, 4, 4} {
+		press("}")
+		if m.cursor != want {
+			t.Errorf("Expected } to move to %d, got %d", want, m.cursor)
+		}
+	}
+
+	m.cursor = 3
+	for _, want := range []int{2, 0, 0} {
+		press("{")
+		if m.cursor != want {
+			t.Errorf("Expected { to move to %d, got %d", want, m.cursor)
+		}
+	}
+}
+
+func TestHeadingFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	content := "- [ ] Loose\n# Project\n## Meeting notes\n- [ ] Send recap\n## Backlog\n- [ ] Refactor parser\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks[0].Heading != "" || tasks[1].Heading != "Meeting notes" || tasks[2].Heading != "Backlog" {
+		t.Fatalf("Unexpected headings %q, %q, %q", tasks[0].Heading, tasks[1].Heading, tasks[2].Heading)
+	}
+
+	// A tag line under a heading leaves the heading alone
+	tagged := filepath.Join(t.TempDir(), "tagged.md")
+	if err := os.WriteFile(tagged, []byte("## Sprint\n#work #q3\n- [ ] Ship it\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	taggedTasks, err := parseFile(tagged, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taggedTasks) != 1 || taggedTasks[0].Heading != "Sprint" {
+		t.Errorf("Expected the heading Sprint, got %+v", taggedTasks)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"heading includes meeting", []string{"Send recap"}},
+		{"heading does not include Meeting", []string{"Loose", "Refactor parser"}},
+		{"heading includes notes\nheading does not include meeting", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, task := range filterTasks(tasks, parseQueryContent(tt.query)) {
+			got = append(got, task.Description)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+}
+
+func TestSelectQueriesFallbackChain(t *testing.T) {
+	vault := t.TempDir()
+	queryFile := filepath.Join(vault, "query.md")
+	if err := os.WriteFile(queryFile, []byte("```tasks\ndone\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const defaultQuery = "not done\nsort by due"
+
+	tests := []struct {
+		name      string
+		queryStr  string
+		queryFile string
+		check     func(q *Query) bool
+	}{
+		{"explicit query", "due today", queryFile, func(q *Query) bool { return len(q.DateFilters) == 1 }},
+		{"query file", "", queryFile, func(q *Query) bool { return q.DoneOnly }},
+		{"default_query", "", "", func(q *Query) bool { return q.NotDone && q.SortBy == "due" }},
+	}
+
+	for _, tt := range tests {
+		queries, err := selectQueries(tt.queryStr, tt.queryFile, vault, defaultQuery, "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(queries) != 1 || !tt.check(queries[0]) {
+			t.Errorf("%s: unexpected query %+v", tt.name, queries[0])
+		}
+	}
+
+	queries, _ := selectQueries("", "", vault, "", "")
+	if len(queries) != 1 || !queries[0].NotDone || queries[0].SortBy != "priority" {
+		t.Errorf("Expected the built-in default without default_query, got %+v", queries[0])
+	}
+}
+
+func TestWriteListReversed(t *testing.T) {
+	tasks := []*Task{
+		{Description: "Oldest", FilePath: "/vault/a.md", LineNumber: 1},
+		{Description: "Middle", FilePath: "/vault/a.md", LineNumber: 2},
+		{Description: "Newest", FilePath: "/vault/b.md", LineNumber: 1},
+	}
+	query := &Query{Name: "Notes", GroupBy: []string{"filename"}}
+	groups := reverseGroups(groupTasksBy(tasks, query.GroupBy, "", "/vault"))
+
+	var out strings.Builder
+	writeList(&out, []QuerySection{{Name: "Notes", Query: query, Groups: groups, Tasks: tasks}}, "/vault", len(tasks), false)
+
+	want := "Found 3 task(s):\n\n" +
+		"## Notes (3)\n" +
+		"### a.md\n" +
+		"[ ] Middle (a.md:2)\n" +
+		"[ ] Oldest (a.md:1)\n" +
+		"### b.md\n" +
+		"[ ] Newest (b.md:1)\n\n"
+	if out.String() != want {
+		t.Errorf("Unexpected list output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if tasks[0].Description != "Oldest" {
+		t.Error("Expected reverseGroups to leave the input untouched")
+	}
+}
+
+func TestCopyFormats(t *testing.T) {
+	vault := filepath.Join("home", "me", "My Vault")
+	task := &Task{
+		FilePath:    filepath.Join(vault, "projects", "road map.md"),
+		LineNumber:  12,
+		Description: "Ship it",
+	}
+
+	if got := copyFileLine(task, vault); got != filepath.Join("projects", "road map.md")+":12" {
+		t.Errorf("file:line = %q", got)
+	}
+
+	if got := copyMarkdownLink(task, vault); got != "[road map](projects/road%20map.md)" {
+		t.Errorf("markdown link = %q", got)
+	}
+
+	task.NoteTitle = "Road Map"
+	if got := copyMarkdownLink(task, vault); got != "[Road Map](projects/road%20map.md)" {
+		t.Errorf("markdown link with title = %q", got)
+	}
+
+	want := "obsidian://open?vault=My%20Vault&file=projects%2Froad%20map"
+	if got := copyObsidianURL(task, vault); got != want {
+		t.Errorf("obsidian URL = %q, want %q", got, want)
+	}
+
+	// Query separators in names must not end the vault or file value
+	task.FilePath = filepath.Join(vault, "R&D", "a=b+c.md")
+	want = "obsidian://open?vault=My%20Vault&file=R%26D%2Fa%3Db%2Bc"
+	if got := copyObsidianURL(task, vault); got != want {
+		t.Errorf("obsidian URL = %q, want %q", got, want)
+	}
+
+	if got := copyOptions[0].format(task, vault); got != "Ship it" {
+		t.Errorf("description = %q", got)
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	task := &Task{Description: "Ship  #release 📅 2025-03-01 ⏫ ➕ 2025-02-01"}
+	if got := toMarkdown(task, false); got != "- [ ] Ship  #release 📅 2025-03-01 ⏫ ➕ 2025-02-01" {
+		t.Errorf("toMarkdown = %q", got)
+	}
+	if got := toMarkdown(task, true); got != "- [ ] Ship #release" {
+		t.Errorf("toMarkdown stripped = %q", got)
+	}
+
+	task = &Task{Description: "Done thing ✅ 2025-03-02 ⏳ 2025-03-01 🛫 2025-02-28", Done: true}
+	if got := toMarkdown(task, true); got != "- [x] Done thing" {
+		t.Errorf("toMarkdown done = %q", got)
+	}
+}
+
+func TestCopyMenuOpensAndCloses(t *testing.T) {
+	m := newTestModel(t, []*Task{{FilePath: "a.md", LineNumber: 1, Description: "one"}})
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Y")})
+	m = updated.(model)
+	if !m.copyMenuOpen {
+		t.Fatal("Y should open the copy menu")
+	}
+	if !strings.Contains(m.View(), "markdown link") {
+		t.Error("copy menu should list its options")
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = updated.(model)
+	if m.copyMenuOpen || cmd == nil {
+		t.Error("selecting an option should close the menu and copy")
+	}
+
+	updated, _ = m.Update(copyFinishedMsg{err: ErrNoClipboard})
+	if updated.(model).err != ErrNoClipboard {
+		t.Error("clipboard errors should be reported")
+	}
+}
+
+func TestCustomQueryBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.md")
+	content := "## Ignored\n\n```tasks\ndone\n```\n\n## Live\n\n```dataview\nnot done\nsort by due\n```\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, err := parseAllQueryBlocks(path, "dataview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || queries[0].Name != "Live" || !queries[0].NotDone || queries[0].SortBy != "due" {
+		t.Fatalf("expected only the dataview block, got %+v", queries)
+	}
+
+	queries, err = parseAllQueryBlocks(path, "")
+	if err != nil || len(queries) != 1 || queries[0].Name != "Ignored" {
+		t.Fatalf("default label should read tasks blocks, got %+v, %v", queries, err)
+	}
+}
+
+func TestParseQueryFilesMergesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("daily.md", "## Today\n\n```tasks\ndue today\n```\n")
+	write("weekly.md", "## Week\n\n```tasks\nnot done\n```\n\n## Later\n\n```tasks\ndone\n```\n")
+	write("notes.md", "# Just notes\n")
+
+	files, err := queryFileArgs([]string{"daily.md", "notes.md,weekly.md"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries, warnings, err := parseQueryFiles(files, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, q := range queries {
+		names = append(names, q.Name)
+	}
+	if strings.Join(names, ",") != "Today,Week,Later" {
+		t.Errorf("sections = %v, want Today,Week,Later", names)
+	}
+
+	if len(warnings) != 1 || filepath.Base(warnings[0].File) != "notes.md" || !errors.Is(warnings[0].Err, ErrNoQueryBlock) {
+		t.Errorf("expected a warning for notes.md, got %+v", warnings)
+	}
+
+	if _, err := queryFileArgs([]string{"missing.md"}, dir); !errors.Is(err, ErrPathNotExist) {
+		t.Errorf("missing query file should fail, got %v", err)
+	}
+}
+
+func TestParseInterspersedFlags(t *testing.T) {
+	fs := flag.NewFlagSet("ot", flag.ContinueOnError)
+	query := fs.String("q", "", "")
+	list := fs.Bool("list", false, "")
+
+	if err := fs.Parse([]string{"~/vault", "-q", "due today", "daily.md", "--list"}); err != nil {
+		t.Fatal(err)
+	}
+	args := parseInterspersed(fs)
+
+	if strings.Join(args, ",") != "~/vault,daily.md" || *query != "due today" || !*list {
+		t.Errorf("args = %v, query = %q, list = %v", args, *query, *list)
+	}
+}
+
+func TestQuerySummary(t *testing.T) {
+	query := parseQueryContent("not done\ndue today or tomorrow\nscheduled before 2024-06-01\ngroup by folder\nsort by due")
+	want := "Filters: not done, due today or tomorrow, scheduled before 2024-06-01 • group by folder • sort by due"
+	if got := query.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	if got := (&Query{}).Summary(); got != "Filters: none" {
+		t.Errorf("empty Summary() = %q", got)
+	}
+
+	m := newTestModel(t, nil)
+	m.queries = []*Query{parseQueryContent("not done\ndue today")}
+	m.allTasks = []*Task{{FilePath: "a.md", LineNumber: 1, Done: true}, {FilePath: "a.md", LineNumber: 2, Done: true}}
+	view := m.View()
+	if !strings.Contains(view, "Filters: not done, due today • 0 of 2 tasks matched") {
+		t.Errorf("empty state should explain the query, got:\n%s", view)
+	}
+
+	// Each tab counts its own vault
+	notDone := parseQueryContent("not done")
+	tabs := []ProfileTab{
+		{Profile: &ResolvedProfile{Name: "home"}, Queries: []*Query{notDone}, AllTasks: []*Task{{Done: true}}},
+		{Profile: &ResolvedProfile{Name: "work"}, Queries: []*Query{notDone}, AllTasks: []*Task{{Done: true}, {Done: true}, {Done: true}}},
+	}
+	m = newModelWithTabs(tabs)
+	if view := m.View(); !strings.Contains(view, "0 of 1 tasks matched") {
+		t.Errorf("first tab should count its tasks, got:\n%s", view)
+	}
+	m.switchTab(1)
+	if view := m.View(); !strings.Contains(view, "0 of 3 tasks matched") {
+		t.Errorf("switched tab should count its tasks, got:\n%s", view)
+	}
+}
+
+func TestDateBadges(t *testing.T) {
+	now := time.Date(2024, time.June, 10, 15, 0, 0, 0, time.Local)
+	day := func(d int) *time.Time {
+		date := time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	if got := dateBadges(&Task{Description: "plain"}, now); got != "" {
+		t.Errorf("task without dates should have no badges, got %q", got)
+	}
+
+	got := ansi.Strip(dateBadges(&Task{ScheduledDate: day(13)}, now))
+	if got != " ⏳ in 3d" {
+		t.Errorf("scheduled badge = %q", got)
+	}
+
+	for _, tt := range []struct {
+		day  int
+		want string
+	}{
+		{10, "today"}, {11, "tomorrow"}, {9, "yesterday"}, {7, "3d ago"},
+	} {
+		if got := humanizeDate(*day(tt.day), now); got != tt.want {
+			t.Errorf("humanizeDate(June %d) = %q, want %q", tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestStartDateParseAndFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Early 🛫 2020-01-01\n- [ ] Later 🛫 2999-01-01\n- [ ] Whenever\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks[0].StartDate == nil || tasks[0].StartDate.Year() != 2020 {
+		t.Fatalf("Expected start date to be parsed, got %v", tasks[0].StartDate)
+	}
+	if tasks[2].StartDate != nil {
+		t.Errorf("Expected no start date, got %v", tasks[2].StartDate)
+	}
+
+	query := parseQueryContent("starts before tomorrow")
+	if len(query.DateFilters) != 1 || query.DateFilters[0].Field != "starts" {
+		t.Fatalf("Expected a starts filter, got %+v", query.DateFilters)
+	}
+
+	filtered := filterTasks(tasks, query)
+	if len(filtered) != 1 || filtered[0].Description != "Early 🛫 2020-01-01" {
+		t.Errorf("starts before tomorrow should match only Early, got %d tasks", len(filtered))
+	}
+
+	if got := ansi.Strip(dateBadges(tasks[0], time.Date(2020, time.January, 3, 0, 0, 0, 0, time.Local))); got != " 🛫 2d ago" {
+		t.Errorf("start badge = %q", got)
+	}
+}
+
+func TestTodayView(t *testing.T) {
+	today := startOfDay(time.Now())
+	day := func(offset int) *time.Time {
+		date := today.AddDate(0, 0, offset)
+		return &date
+	}
+
+	tasks := []*Task{
+		{FilePath: "a.md", LineNumber: 1, Description: "overdue", DueDate: day(-2)},
+		{FilePath: "a.md", LineNumber: 2, Description: "due today", DueDate: day(0)},
+		{FilePath: "a.md", LineNumber: 3, Description: "scheduled", ScheduledDate: day(-1)},
+		{FilePath: "a.md", LineNumber: 4, Description: "future", DueDate: day(3)},
+		{FilePath: "a.md", LineNumber: 5, Description: "done", DueDate: day(0), Done: true},
+		{FilePath: "a.md", LineNumber: 6, Description: "undated"},
+	}
+
+	m := newTestModel(t, tasks)
+	original := m.queries
+	m.allTasks = tasks
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	m = updated.(model)
+
+	if !m.todayView || len(m.sections) != 1 || m.sections[0].Name != "Today" {
+		t.Fatalf("T should show the Today view, got %+v", m.sections)
+	}
+
+	var groups []string
+	for _, g := range m.sections[0].Groups {
+		var names []string
+		for _, task := range g.Tasks {
+			names = append(names, task.Description)
+		}
+		groups = append(groups, g.Name+": "+strings.Join(names, ","))
+	}
+	want := []string{"Overdue: overdue", "Today: due today", "No due date: scheduled"}
+	if strings.Join(groups, "; ") != strings.Join(want, "; ") {
+		t.Errorf("Today groups = %v, want %v", groups, want)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	m = updated.(model)
+	if m.todayView || len(m.queries) != 1 || m.queries[0] != original[0] {
+		t.Error("T again should restore the original queries")
+	}
+	if len(m.tasks) != len(tasks) {
+		t.Errorf("restored view should list all %d tasks, got %d", len(tasks), len(m.tasks))
+	}
+}
+
+func TestOpenFirstTask(t *testing.T) {
+	first := &Task{FilePath: "/vault/a.md", LineNumber: 7}
+	sections := []QuerySection{
+		{Name: "Empty", Groups: []TaskGroup{{Name: ""}}},
+		{Name: "Work", Groups: []TaskGroup{{Name: "x"}, {Name: "y", Tasks: []*Task{first, {FilePath: "/vault/b.md", LineNumber: 1}}}}},
+	}
+
+	if got := firstTask(sections); got != first {
+		t.Fatalf("firstTask = %+v, want %+v", got, first)
+	}
+	if got := firstTask(sections[:1]); got != nil {
+		t.Errorf("firstTask of empty sections = %+v, want nil", got)
+	}
+
+	t.Setenv("EDITOR", "nano")
+	c := editorCommand(first)
+	if strings.Join(c.Args, " ") != "nano +7 /vault/a.md" {
+		t.Errorf("editor args = %v", c.Args)
+	}
+}
+
+func TestCheckboxGlyphs(t *testing.T) {
+	glyphs := checkboxGlyphs{todo: "☐", done: "☑"}
+	if err := checkCheckboxGlyphs(glyphs.todo, glyphs.done); err != nil {
+		t.Fatalf("equal width glyphs should be accepted: %v", err)
+	}
+
+	todo := ansi.Strip(renderCheckboxLine(false, "Write docs", glyphs))
+	if !strings.Contains(todo, "☐ Write docs") || strings.Contains(todo, "[ ]") {
+		t.Errorf("todo line = %q, want the ☐ glyph", todo)
+	}
+
+	done := ansi.Strip(renderCheckboxLine(true, "Ship", glyphs))
+	if !strings.Contains(done, "☑ Ship") {
+		t.Errorf("done line = %q, want the ☑ glyph", done)
+	}
+
+	m := newTestModel(t, []*Task{{Description: "From the model", LineNumber: 1}})
+	m.checkboxes = glyphs
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "☐ From the model") {
+		t.Errorf("the list should draw the model's glyphs, got:\n%s", view)
+	}
+
+	if err := checkCheckboxGlyphs("( )", "●"); !errors.Is(err, ErrCheckboxWidth) {
+		t.Errorf("glyphs of different widths should warn, got %v", err)
+	}
+	if err := checkCheckboxGlyphs("", ""); err != nil {
+		t.Errorf("defaults should be accepted, got %v", err)
+	}
+}
+
+func TestRenderTaskSameInSearchAndList(t *testing.T) {
+	due := time.Date(2030, time.January, 2, 0, 0, 0, 0, time.UTC)
+	tasks := []*Task{
+		{FilePath: "notes/a.md", LineNumber: 3, Description: "Plan the trip ⏳ 2030-01-02", ScheduledDate: &due},
+		{FilePath: "notes/b.md", LineNumber: 9, Description: "Book flights", Done: true},
+	}
+
+	for _, short := range []bool{false, true} {
+		m := newTestModel(t, tasks)
+		m.windowWidth = 60
+		m.short = short
+
+		list, _ := m.listLines()
+		search := m.searchLines(tasks)
+
+		for i := range tasks {
+			if list[i].content != search[i].content {
+				t.Errorf("short=%v task %d renders differently:\nlist:   %q\nsearch: %q", short, i, list[i].content, search[i].content)
+			}
+		}
+	}
+}
+
+func TestExpandDueShorthand(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, time.June, 4, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Call mom due: today", "Call mom 📅 2025-06-04"},
+		{"Call mom due: tomorrow", "Call mom 📅 2025-06-05"},
+		{"Review due: next monday #work", "Review 📅 2025-06-09 #work"},
+		{"Review due: Friday", "Review 📅 2025-06-06"},
+		{"Standup due: wednesday", "Standup 📅 2025-06-11"},
+		{"Pay rent due: +3d", "Pay rent 📅 2025-06-07"},
+		{"Renew due: +2w", "Renew 📅 2025-06-18"},
+		{"Nothing due: someday", "Nothing due: someday"},
+		{"Plain task 📅 2025-07-01", "Plain task 📅 2025-07-01"},
+	}
+
+	for _, tt := range tests {
+		if got := expandDueShorthand(tt.input, now, nil); got != tt.want {
+			t.Errorf("expandDueShorthand(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestQuickDeleteIsUndoable(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.md")
+	content := "- [ ] Task one\n- [ ] Task two\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := parseFile(testFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := &Query{}
+	sections := []QuerySection{{Query: query, Groups: groupTasks(tasks, "", "", ""), Tasks: tasks}}
+	m := newModel(sections, tmpDir, "test", "", []*Query{query}, "", nil, nil, nil)
+	m.quickDelete = true
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m = updated.(model)
+
+	if m.deleting {
+		t.Fatal("quick delete should not ask for confirmation")
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "- [ ] Task two\n" {
+		t.Fatalf("expected Task one deleted, file is %q", data)
+	}
+	if !strings.Contains(m.View(), "deleted — u to undo") {
+		t.Error("expected an undo hint after deleting")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	m = updated.(model)
+
+	if data, _ := os.ReadFile(testFile); string(data) != content {
+		t.Errorf("undo should restore the file, got %q", data)
+	}
+	if strings.Contains(m.View(), "deleted — u to undo") {
+		t.Error("the undo hint should clear on the next key")
+	}
+}
+
+func TestScanVaultExtensions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.markdown", "c.MDX", "d.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("- [ ] task\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := scanOptions{extensions: normalizeExtensions([]string{"md", ".Markdown", " .mdx "})}
+	files, err := scanVault(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	if strings.Join(names, ",") != "a.md,b.markdown,c.MDX" {
+		t.Errorf("scanned %v, want a.md, b.markdown and c.MDX", names)
+	}
+
+	if _, ok := fileChangeFromEvent(fsnotify.Event{Name: filepath.Join(dir, "b.markdown"), Op: fsnotify.Write}, opts); !ok {
+		t.Error("watcher should report changes to .markdown files")
+	}
+
+	if files, _ := scanVault(dir, scanOptions{extensions: normalizeExtensions(nil)}); len(files) != 1 {
+		t.Errorf("default extensions should scan only .md files, got %v", files)
+	}
+}
+
+func TestDailyNoteCapture(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 9, 0, 0, 0, time.Local)
+	if got := expandDateTokens("Daily/{YYYY}/{YY}-{MM}-{DD}.md", now); got != "Daily/2025/25-06-01.md" {
+		t.Errorf("expandDateTokens = %q", got)
+	}
+
+	vault := t.TempDir()
+	m := newTestModel(t, nil)
+	m.vaultPath = vault
+	m.dailyNoteFormat = "Daily/{YYYY}-{MM}-{DD}.md"
+	m.dailyNoteHeader = "# {YYYY}-{MM}-{DD}"
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	m = updated.(model)
+	if !m.creating {
+		t.Fatal("D should open the new task modal")
+	}
+
+	today := time.Now()
+	m.creatingInput.SetValue("Water plants")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+
+	path := filepath.Join(vault, expandDateTokens("Daily/{YYYY}-{MM}-{DD}.md", today))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("daily note should be created: %v", err)
+	}
+	want := expandDateTokens("# {YYYY}-{MM}-{DD}", today) + "\n\n- [ ] Water plants\n"
+	if string(data) != want {
+		t.Errorf("daily note = %q, want %q", data, want)
+	}
+
+	// An existing note only gets the task appended
+	if err := ensureNoteHeader(path, "# ignored"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != want {
+		t.Errorf("existing note should be left alone, got %q", again)
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	now := time.Date(2025, time.June, 4, 12, 0, 0, 0, time.Local)
+	day := func(offset int) *time.Time {
+		date := startOfDay(now).AddDate(0, 0, offset)
+		return &date
+	}
+
+	overdue := &Task{Description: "late", Priority: PriorityHigh, DueDate: day(-1)}
+	soon := &Task{Description: "soon", Priority: PriorityNormal, DueDate: day(3)}
+	later := &Task{Description: "later", Priority: PriorityNormal, DueDate: day(30)}
+	finished := &Task{Description: "finished", Done: true, Priority: PriorityHigh, DueDate: day(-5)}
+
+	sections := []QuerySection{
+		{Name: "Work", Tasks: []*Task{overdue, soon, finished}},
+		{Name: "All", Tasks: []*Task{overdue, soon, later, finished}},
+	}
+
+	var out strings.Builder
+	writeStats(&out, sections, now)
+	got := out.String()
+
+	for _, want := range []string{
+		"  open         3\n",
+		"  done         1\n",
+		"  total        4\n",
+		"  high         1\n",
+		"  normal       2\n",
+		"  overdue      1\n",
+		"  next 7 days  1\n",
+		"  Work         3\n",
+		"  All          4\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stats missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestNoColorViewHasNoEscapes(t *testing.T) {
+	prevProfile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(prevProfile)
+		noColor = false
+		initRenderer(defaultTheme)
+	})
+
+	tasks := []*Task{
+		{Description: "open task", LineNumber: 1, Priority: PriorityHigh},
+		{Description: "done task", LineNumber: 2, Done: true},
+	}
+
+	updated, _ := newTestModel(t, tasks).Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m := updated.(model)
+	if !strings.Contains(m.View(), "\x1b[") {
+		t.Fatal("expected styled output before --no-color")
+	}
+
+	setNoColor()
+	view := m.View()
+	if strings.Contains(view, "\x1b") {
+		t.Errorf("no-color view contains escape sequences:\n%q", view)
+	}
+	if !strings.Contains(view, "[x]") || !strings.Contains(view, "[ ]") {
+		t.Errorf("no-color view should use [ ]/[x] checkboxes:\n%s", view)
+	}
+
+	for _, tc := range []struct {
+		flag bool
+		env  map[string]string
+		want bool
+	}{
+		{false, nil, false},
+		{true, nil, true},
+		{false, map[string]string{"NO_COLOR": "1"}, true},
+		{false, map[string]string{"CLICOLOR": "0"}, true},
+		{false, map[string]string{"CLICOLOR": "1"}, false},
+	} {
+		if got := noColorRequested(tc.flag, func(k string) string { return tc.env[k] }); got != tc.want {
+			t.Errorf("noColorRequested(%v, %v) = %v, want %v", tc.flag, tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestSearchHistoryRecall(t *testing.T) {
+	m := newTestModel(t, []*Task{
+		{Description: "alpha", LineNumber: 1},
+		{Description: "beta", LineNumber: 2},
+	})
+
+	press := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			updated, _ := m.Update(key)
+			m = updated.(model)
+		}
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	up := tea.KeyMsg{Type: tea.KeyUp}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+
+	for _, query := range []string{"alpha", "beta", "beta"} {
+		press(runes("/"))
+		for _, r := range query {
+			press(runes(string(r)))
+		}
+		press(enter, esc)
+	}
+
+	if want := []string{"alpha", "beta"}; !slices.Equal(m.searchHistory, want) {
+		t.Fatalf("history = %v, want %v (consecutive repeats dropped)", m.searchHistory, want)
+	}
+
+	press(runes("/"), up)
+	if m.searchQuery != "beta" || len(m.filteredTasks) != 1 {
+		t.Fatalf("first up = %q (%d matches), want newest query beta", m.searchQuery, len(m.filteredTasks))
+	}
+
+	press(up)
+	if m.searchQuery != "alpha" {
+		t.Errorf("second up = %q, want alpha", m.searchQuery)
+	}
+
+	press(up)
+	if m.searchQuery != "alpha" {
+		t.Errorf("up past the oldest = %q, want alpha", m.searchQuery)
+	}
+
+	press(down, down)
+	if m.searchQuery != "" {
+		t.Errorf("down past the newest = %q, want the empty query", m.searchQuery)
+	}
+}
+
+func TestSearchHistoryPersistsAndCaps(t *testing.T) {
+	var history []string
+	for i := 0; i < maxSearchHistory+5; i++ {
+		history = addSearchHistory(history, fmt.Sprintf("q%d", i))
+	}
+
+	if len(history) != maxSearchHistory || history[0] != "q5" {
+		t.Fatalf("history has %d entries starting at %q, want %d starting at q5", len(history), history[0], maxSearchHistory)
+	}
+
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path, err := searchHistoryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveSearchHistory(path, history); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadSearchHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded, history) {
+		t.Errorf("loaded %v, want %v", loaded, history)
+	}
+}
+
+func TestModifiedBucketsAndSort(t *testing.T) {
+	now := time.Date(2025, 6, 15, 14, 0, 0, 0, time.Local)
+
+	for _, tc := range []struct {
+		modTime time.Time
+		want    string
+	}{
+		{now.Add(-time.Hour), "Today"},
+		{time.Date(2025, 6, 15, 0, 0, 0, 0, time.Local), "Today"},
+		{time.Date(2025, 6, 14, 23, 59, 0, 0, time.Local), "This week"},
+		{time.Date(2025, 6, 9, 8, 0, 0, 0, time.Local), "This week"},
+		{time.Date(2025, 6, 8, 23, 0, 0, 0, time.Local), "Older"},
+		{time.Time{}, "Older"},
+	} {
+		if got := modifiedBucket(tc.modTime, now); got != tc.want {
+			t.Errorf("modifiedBucket(%v) = %q, want %q", tc.modTime, got, tc.want)
+		}
+	}
+
+	old := &Task{Description: "old", FileModTime: time.Now().AddDate(0, -1, 0)}
+	recent := &Task{Description: "recent", FileModTime: time.Now()}
+	week := &Task{Description: "week", FileModTime: time.Now().AddDate(0, 0, -3)}
+
+	sorted := sortTasks([]*Task{old, recent, week}, "modified")
+	if sorted[0] != recent || sorted[1] != week || sorted[2] != old {
+		t.Errorf("sort by modified = %s, %s, %s; want recent, week, old", sorted[0].Description, sorted[1].Description, sorted[2].Description)
+	}
+
+	groups := groupTasks([]*Task{old, recent, week}, "modified", "", "")
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	if want := []string{"Today", "This week", "Older"}; !slices.Equal(names, want) {
+		t.Errorf("group by modified = %v, want %v", names, want)
+	}
+}
+
+func TestParseFileRecordsModTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	if err := os.WriteFile(path, []byte("- [ ] task\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tasks[0].FileModTime.Equal(modTime) {
+		t.Errorf("FileModTime = %v, want %v", tasks[0].FileModTime, modTime)
+	}
+}
+
+func TestParseFileContinuationLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	content := "- [ ] Write the report\n" +
+		"  covering Q3 numbers\n" +
+		"- [ ] Sibling task\n" +
+		"  - a note bullet\n" +
+		"not indented text\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+
+	first := tasks[0]
+	if first.Description != "Write the report covering Q3 numbers" {
+		t.Errorf("Description = %q, want the continuation appended", first.Description)
+	}
+	if first.LineNumber != 1 || first.RawLine != "- [ ] Write the report" {
+		t.Errorf("task points at line %d %q, want the checkbox line", first.LineNumber, first.RawLine)
+	}
+	if tasks[1].Description != "Sibling task" {
+		t.Errorf("sibling Description = %q, want bullets and unindented text left out", tasks[1].Description)
+	}
+
+	first.SetPriority(PriorityHigh)
+	if first.RawLine != "- [ ] Write the report ⏫" {
+		t.Errorf("RawLine after SetPriority = %q, want only the checkbox line", first.RawLine)
+	}
+	if first.Description != "Write the report ⏫ covering Q3 numbers" {
+		t.Errorf("Description after SetPriority = %q", first.Description)
+	}
+}
+
+func TestDeleteTaskRemovesContinuationLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	content := "- [ ] Write the report\n  covering Q3 numbers\n  and Q4 plans\n- [ ] Sibling task\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestModel(t, tasks)
+	m.vaultPath = dir
+
+	m.deleteWithUndo(tasks[0])
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "- [ ] Sibling task\n" {
+		t.Errorf("after delete the file is %q, want only the sibling", got)
+	}
+
+	m.undoLastOperation()
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	got, _ = os.ReadFile(path)
+	if string(got) != content {
+		t.Errorf("after undo the file is %q, want %q", got, content)
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		query   string
+		warning string
+	}{
+		{"range", "due after 2025-01-01\ndue before 2025-02-01", ""},
+		{"empty range", "due after 2025-02-01\ndue before 2025-01-01", `"due after 2025-02-01" and "due before 2025-01-01" can never both match`},
+		{"adjacent days", "due after 2025-01-01\ndue before 2025-01-02", `"due after 2025-01-01" and "due before 2025-01-02" can never both match`},
+		{"other fields", "due after 2025-02-01\nscheduled before 2025-01-01", ""},
+		{"duplicate", "due before 2025-01-01\ndue before 2025-01-01", `duplicate filter "due before 2025-01-01"`},
+		{"two days", "due on 2025-01-01\ndue on 2025-01-02", `"due 2025-01-01" and "due 2025-01-02" can never both match`},
+		{"on outside range", "due on 2025-01-01\ndue after 2025-01-05", `"due 2025-01-01" and "due after 2025-01-05" can never both match`},
+		{"done and not done", "not done\ndone", `"done" and "not done" can never both match`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings := parseQueryContent(tc.query).Validate()
+			if tc.warning == "" {
+				if len(warnings) > 0 {
+					t.Errorf("unexpected warnings %q", warnings)
+				}
+				return
+			}
+			if !slices.Contains(warnings, tc.warning) {
+				t.Errorf("warnings = %q, want %q", warnings, tc.warning)
+			}
+		})
+	}
+}
+
+func TestQueryWarningShownInModel(t *testing.T) {
+	query := parseQueryContent("due after 2025-02-01\ndue before 2025-01-01")
+	query.Name = "Soon"
+
+	m := newModel(nil, "", "test", "", []*Query{query}, "", nil, nil, nil)
+	want := `query: Soon: "due after 2025-02-01" and "due before 2025-01-01" can never both match`
+	if m.warning != want {
+		t.Errorf("warning = %q, want %q", m.warning, want)
+	}
+}
+
+func TestOrderedMapDeleteAndEach(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	for i, key := range []string{"a", "b", "c", "d"} {
+		m.Set(key, i)
+	}
+
+	m.Delete("b")
+	m.Delete("missing")
+	if want := []string{"a", "c", "d"}; !slices.Equal(m.Keys(), want) {
+		t.Fatalf("keys after delete = %v, want %v", m.Keys(), want)
+	}
+	if _, ok := m.Get("b"); ok {
+		t.Error("deleted key still present")
+	}
+
+	// A deleted key comes back at the end
+	m.Set("b", 10)
+	if want := []string{"a", "c", "d", "b"}; !slices.Equal(m.Keys(), want) {
+		t.Errorf("keys after re-adding = %v, want %v", m.Keys(), want)
+	}
+
+	var visited []string
+	var sum int
+	m.Each(func(key string, value int) bool {
+		visited = append(visited, key)
+		sum += value
+		return true
+	})
+	if want := []string{"a", "c", "d", "b"}; !slices.Equal(visited, want) || sum != 0+2+3+10 {
+		t.Errorf("Each visited %v (sum %d), want %v (sum 15)", visited, sum, want)
+	}
+
+	visited = nil
+	m.Each(func(key string, _ int) bool {
+		visited = append(visited, key)
+		return key != "c"
+	})
+	if want := []string{"a", "c"}; !slices.Equal(visited, want) {
+		t.Errorf("Each stopped after %v, want %v", visited, want)
+	}
+}
+
+func TestGroupByFolderDepth(t *testing.T) {
+	nested := &Task{FilePath: "/vault/a/b/c/x.md", LineNumber: 1}
+	shallow := &Task{FilePath: "/vault/a/y.md", LineNumber: 1}
+	root := &Task{FilePath: "/vault/z.md", LineNumber: 1}
+	tasks := []*Task{nested, shallow, root}
+
+	for _, tc := range []struct {
+		groupBy string
+		want    []string
+	}{
+		{"folder", []string{"a/b/c", "a", "/"}},
+		{"folder 1", []string{"a", "/"}},
+		{"folder 2", []string{"a/b", "a", "/"}},
+	} {
+		var names []string
+		for _, group := range groupTasks(tasks, tc.groupBy, "", "/vault") {
+			names = append(names, group.Name)
+		}
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("group by %s = %v, want %v", tc.groupBy, names, tc.want)
+		}
+	}
+
+	// Paths outside the vault keep their leading slash
+	if got := folderKey("/a/b/c/x.md", 2); got != "/a/b" {
+		t.Errorf("folderKey outside the vault = %q, want /a/b", got)
+	}
+
+	if got := strings.Join(parseQueryContent("group by folder 2").GroupBy, ", "); got != "folder 2" {
+		t.Errorf("parsed GroupBy = %q, want \"folder 2\"", got)
+	}
+}
+
+func TestListAbsolutePaths(t *testing.T) {
+	task := &Task{Description: "call", FilePath: "/vault/work/todo.md", LineNumber: 3}
+	sections := []QuerySection{{
+		Query:  &Query{},
+		Groups: []TaskGroup{{Tasks: []*Task{task}}},
+		Tasks:  []*Task{task},
+	}}
+
+	var out strings.Builder
+	writeList(&out, sections, "/vault", 1, false)
+	if !strings.Contains(out.String(), "[ ] call (work/todo.md:3)") {
+		t.Errorf("relative list output:\n%s", out.String())
+	}
+
+	out.Reset()
+	writeList(&out, sections, "/vault", 1, true)
+	if !strings.Contains(out.String(), "[ ] call (/vault/work/todo.md:3)") {
+		t.Errorf("absolute list output:\n%s", out.String())
+	}
+}
+
+func TestScanAndParse(t *testing.T) {
+	vault := t.TempDir()
+	good := filepath.Join(vault, "good.md")
+	if err := os.WriteFile(good, []byte("- [ ] Fine\n- [x] Done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling link is listed by the scan but can't be opened
+	if err := os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	var phases []string
+	cache := NewTaskCache()
+	result := scanAndParse(context.Background(), vault, scanOptions{}, cache, func(p ScanProgress) {
+		phases = append(phases, p.Phase)
+	})
+
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if len(result.Files) != 2 || len(result.Tasks) != 2 {
+		t.Fatalf("got %d files and %d tasks, want 2 and 2", len(result.Files), len(result.Tasks))
+	}
+	if len(result.Warnings) != 1 || filepath.Base(result.Warnings[0].File) != "broken.md" {
+		t.Errorf("warnings = %v, want one for broken.md", result.Warnings)
+	}
+	if result.Cache != cache {
+		t.Error("result should carry the cache it was given")
+	}
+	if want := []string{"scanning", "scanning", "parsing", "parsing"}; !slices.Equal(phases, want) {
+		t.Errorf("progress phases = %v, want %v", phases, want)
+	}
+
+	// Unchanged files come from the cache: the same task pointers
+	again := scanAndParse(context.Background(), vault, scanOptions{}, cache, nil)
+	if len(again.Tasks) != 2 || again.Tasks[0] != result.Tasks[0] {
+		t.Error("second scan should reuse the cached tasks")
+	}
+
+	// A changed file is parsed again
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(good, []byte("- [ ] Fine\n- [x] Done\n- [ ] New\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(good, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if fresh := scanAndParse(context.Background(), vault, scanOptions{}, cache, nil); len(fresh.Tasks) != 3 {
+		t.Errorf("changed file gave %d tasks, want 3", len(fresh.Tasks))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if cancelled := scanAndParse(ctx, vault, scanOptions{}, nil, nil); !errors.Is(cancelled.Error, ErrScanCancelled) {
+		t.Errorf("cancelled scan error = %v, want ErrScanCancelled", cancelled.Error)
+	}
+}
+
+func TestEditorFlagOverridesProfile(t *testing.T) {
+	vault := t.TempDir()
+	t.Setenv("EDITOR", "vim")
+
+	resolved, err := resolveProfilePaths("work", Profile{Vault: vault, Editor: "external"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode, err := resolveEditorMode("inline", resolved.EditorMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newModel(nil, vault, "work", "", []*Query{{}}, mode, nil, nil, nil)
+	if !m.useInlineEditor() {
+		t.Errorf("--editor inline should win over the profile's external editor, got %q", mode)
+	}
+
+	if mode, _ := resolveEditorMode("", resolved.EditorMode); mode != "external" {
+		t.Errorf("without the flag the profile's editor applies, got %q", mode)
+	}
+
+	if _, err := resolveEditorMode("vscode", resolved.EditorMode); !errors.Is(err, ErrInvalidEditor) {
+		t.Errorf("expected ErrInvalidEditor for an unknown mode, got %v", err)
+	}
+}
+
+func TestWriteListDueAndPriority(t *testing.T) {
+	due := time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)
+	tasks := []*Task{
+		{Description: "Pay rent 📅 2025-03-07 ⏫", FilePath: "/vault/a.md", LineNumber: 1, DueDate: &due, Priority: PriorityHigh},
+		{Description: "Plain", FilePath: "/vault/a.md", LineNumber: 2, Priority: PriorityNormal},
+	}
+	sections := []QuerySection{{Query: &Query{}, Groups: []TaskGroup{{Tasks: tasks}}, Tasks: tasks}}
+
+	var out strings.Builder
+	writeList(&out, sections, "/vault", len(tasks), false)
+
+	for _, want := range []string{
+		"[ ] Pay rent 📅 2025-03-07 ⏫ (a.md:1) due:2025-03-07 priority:high\n",
+		"[ ] Plain (a.md:2)\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("list output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestHideBacklinkPerSection(t *testing.T) {
+	hidden := &Task{Description: "quiet #home ⏫", FilePath: "/vault/a.md", LineNumber: 1, Priority: PriorityHigh}
+	shown := &Task{Description: "loud", FilePath: "/vault/b.md", LineNumber: 2}
+
+	quiet := parseQueryContent("not done\nhide backlink\nhide tags\nhide priority")
+	quiet.Name = "Quiet"
+	loud := parseQueryContent("not done")
+	loud.Name = "Loud"
+
+	if !quiet.Hide["backlink"] || !quiet.Hide["tags"] || !quiet.Hide["priority"] || len(loud.Hide) != 0 {
+		t.Fatalf("parsed hide sets %v and %v", quiet.Hide, loud.Hide)
+	}
+
+	sections := []QuerySection{
+		{Name: "Quiet", Query: quiet, Groups: []TaskGroup{{Tasks: []*Task{hidden}}}, Tasks: []*Task{hidden}},
+		{Name: "Loud", Query: loud, Groups: []TaskGroup{{Tasks: []*Task{shown}}}, Tasks: []*Task{shown}},
+	}
+	updated, _ := newModel(sections, "/vault", "test", "", []*Query{quiet, loud}, "", nil, nil, nil).Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	view := ansi.Strip(updated.(model).View())
+
+	if strings.Contains(view, "a.md:1") {
+		t.Errorf("hide backlink should drop the suffix in its section:\n%s", view)
+	}
+	if !strings.Contains(view, "(b.md:2)") {
+		t.Errorf("other sections keep their suffix:\n%s", view)
+	}
+	if strings.Contains(view, "#home") || strings.Contains(view, "⏫") || !strings.Contains(view, "quiet") {
+		t.Errorf("hide tags/priority should leave only the text:\n%s", view)
+	}
+}
+
+func TestCachedLinesFollowCursorAcrossGroups(t *testing.T) {
+	var tasks []*Task
+	for i := range 6 {
+		tasks = append(tasks, &Task{Description: fmt.Sprintf("task %d", i), FilePath: fmt.Sprintf("/vault/%c.md", 'a'+i%2), LineNumber: i + 1})
+	}
+	query := &Query{GroupBy: []string{"filename"}}
+	sections := []QuerySection{{Query: query, Groups: groupTasks(tasks, "filename", "", "/vault"), Tasks: tasks}}
+	m := newModel(sections, "/vault", "test", "", []*Query{query}, "", nil, nil, nil)
+
+	uncached := m
+	uncached.lines = nil
+
+	down := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}
+	for range len(tasks) {
+		if got, want := m.View(), uncached.View(); got != want {
+			t.Fatalf("cursor %d: cached view differs from a full render:\n%s\nwant:\n%s", m.cursor, got, want)
+		}
+
+		updated, _ := m.Update(down)
+		m = updated.(model)
+		uncached.cursor = m.cursor
+	}
+
+	// Changing a task in place shows up once the lines are invalidated
+	m.tasks[0].Toggle()
+	m.invalidateLines()
+	if got, want := m.View(), uncached.View(); got != want {
+		t.Errorf("toggle not reflected in the cached view:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func BenchmarkViewCursorMove(b *testing.B) {
+	var tasks []*Task
+	for i := range 1000 {
+		tasks = append(tasks, &Task{Description: fmt.Sprintf("task %d 📅 2025-01-01", i), FilePath: fmt.Sprintf("/vault/notes/%d.md", i%20), LineNumber: i + 1})
+	}
+	query := &Query{GroupBy: []string{"filename"}}
+	sections := []QuerySection{{Query: query, Groups: groupTasks(tasks, "filename", "", "/vault"), Tasks: tasks}}
+
+	for _, bc := range []struct {
+		name   string
+		cached bool
+	}{{"cached", true}, {"uncached", false}} {
+		b.Run(bc.name, func(b *testing.B) {
+			m := newModel(sections, "/vault", "bench", "", []*Query{query}, "", nil, nil, nil)
+			if !bc.cached {
+				m.lines = nil
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.cursor = i % len(tasks)
+				_ = m.View()
+			}
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	vault := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("home.md", "- [ ] Renew passport 📅 2025-03-01 ⏫ #admin\n- [ ] Water plants\n")
+	write("work.md", "- [ ] renew  passport\n- [ ] Water the plants\n")
+
+	result := scanAndParse(context.Background(), vault, scanOptions{}, nil, nil)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	groups := findDuplicates(result.Tasks)
+	if len(groups) != 1 {
+		t.Fatalf("got %d duplicate groups, want 1: %+v", len(groups), groups)
+	}
+	if groups[0].Text != "renew passport" || len(groups[0].Tasks) != 2 {
+		t.Errorf("group = %q with %d tasks, want \"renew passport\" with 2", groups[0].Text, len(groups[0].Tasks))
+	}
+
+	var out strings.Builder
+	writeDuplicates(&out, groups, vault, false)
+	for _, want := range []string{"renew passport (2)\n", "(home.md:1)", "(work.md:1)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// Without every task loaded the scan runs in the command
+	m := newModel(nil, vault, "test", "", []*Query{{}}, "", nil, nil, nil)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("=")})
+	if cmd == nil || updated.(model).duplicates != nil {
+		t.Fatal("= should look for duplicates in a command")
+	}
+	updated, _ = updated.Update(cmd())
+	if view := updated.(model).View(); !strings.Contains(view, "renew passport") || !strings.Contains(view, "work.md:1") {
+		t.Errorf("= should list the duplicates:\n%s", view)
+	}
+}
+
+func TestLoaderProgressBar(t *testing.T) {
+	show := func(width int) string {
+		var m tea.Model = newLoaderModel(nil)
+		m, _ = m.Update(tea.WindowSizeMsg{Width: width, Height: 10})
+		m, cmd := m.Update(scanProgressMsg{Phase: "parsing", FilesFound: 10, FilesParsed: 5})
+		if cmd == nil {
+			t.Error("expected a frame command animating the bar")
+		}
+		lm := m.(loaderModel)
+		lm.showLoader = true
+		return ansi.Strip(lm.View())
+	}
+
+	if view := show(80); !strings.Contains(view, "░") || !strings.Contains(view, "5/10") {
+		t.Errorf("wide loader should draw a bar with the file count:\n%s", view)
+	}
+	if view := show(30); strings.Contains(view, "░") || !strings.Contains(view, "5/10 (50%)") {
+		t.Errorf("narrow loader should fall back to text:\n%s", view)
+	}
+}
+
+func TestFocusSection(t *testing.T) {
+	work := &Task{Description: "work task", FilePath: "/vault/work.md", LineNumber: 1}
+	home := &Task{Description: "home task", FilePath: "/vault/home.md", LineNumber: 1}
+	errand := &Task{Description: "home errand", FilePath: "/vault/home.md", LineNumber: 2}
+
+	m := newModel(nil, "/vault", "test", "", []*Query{{Name: "Work"}, {Name: "Home"}}, "", nil, nil, nil)
+	m.allTasks = []*Task{work, home, errand}
+	m.rebuildSections()
+
+	m.cursor = 4 // "home task" in the second section
+	selected := m.tasks[m.cursor]
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")})
+	m = updated.(model)
+
+	if m.focusSection != "Home" || len(m.sections) != 1 || m.sections[0].Name != "Home" {
+		t.Fatalf("focus = %q with %d sections, want only Home", m.focusSection, len(m.sections))
+	}
+	if m.tasks[m.cursor] != selected {
+		t.Errorf("cursor should stay on the selected task, got %q", m.tasks[m.cursor].Description)
+	}
+
+	view := ansi.Strip(m.View())
+	if strings.Contains(view, "# Work") || !strings.Contains(view, "# Home") || !strings.Contains(view, "focus:Home") {
+		t.Errorf("focused view should only list Home:\n%s", view)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")})
+	m = updated.(model)
+	if m.focusSection != "" || len(m.sections) != 2 {
+		t.Errorf("second F should restore all sections, got %d", len(m.sections))
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	cfg := Config{Profiles: map[string]Profile{"work": {}, "personal": {}}}
+
+	var out strings.Builder
+	writeProfileNames(cfg, &out)
+	if got := out.String(); got != "personal\nwork\n" {
+		t.Errorf("profile names = %q, want one per line in order", got)
+	}
+
+	fs := flag.NewFlagSet("ot", flag.ContinueOnError)
+	fs.String("profile", "", "")
+	fs.String("complete", "", "")
+
+	for _, shell := range []string{"bash", "zsh"} {
+		script, err := completionScript(shell, fs)
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		if !strings.Contains(script, "ot --complete profiles") || !strings.Contains(script, "--profile") {
+			t.Errorf("%s script should complete flags and profiles:\n%s", shell, script)
+		}
+		if strings.Contains(script, "--complete\"") || strings.Contains(completionFlags(fs), "--complete") {
+			t.Errorf("%s script should not offer the hidden --complete flag", shell)
+		}
+	}
+
+	if _, err := completionScript("fish", fs); !errors.Is(err, ErrUnknownShell) {
+		t.Errorf("Expected ErrUnknownShell, got %v", err)
+	}
+}
+
+func TestTabIndentedTaskRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tabs.md")
+	content := "- [ ] Parent\n\t- [ ] Tab child\n\t \t- [ ]\tMixed grandchild\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+	if len(tasks) != 3 || tasks[1].Indent != 1 || tasks[2].Indent != 2 {
+		t.Fatalf("Expected nesting 0/1/2 from tab indentation, got %d tasks", len(tasks))
+	}
+
+	tasks[1].Toggle()
+	if err := saveTask(tasks[1]); err != nil {
+		t.Fatalf("saveTask failed: %v", err)
+	}
+
+	tasks[2].Description = "Renamed"
+	tasks[2].rebuildRawLine()
+	if err := saveTask(tasks[2]); err != nil {
+		t.Fatalf("saveTask failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "- [ ] Parent\n\t- [x] Tab child ✅ " + defaultTaskFormat.formatDate(time.Now()) + "\n\t \t- [ ]\tRenamed\n"
+	if string(got) != want {
+		t.Errorf("Whitespace should survive byte-for-byte:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestToggleEditorMode(t *testing.T) {
+	t.Setenv("EDITOR", "")
+	task := &Task{Description: "Edit me", RawLine: "- [ ] Edit me", FilePath: "/vault/tasks.md", LineNumber: 1}
+	m := newTestModel(t, []*Task{task})
+
+	if m.editorModeName() != "inline" {
+		t.Fatalf("Expected inline editing without $EDITOR, got %s", m.editorModeName())
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updated.(model)
+	if m.editorMode != "external" {
+		t.Fatalf("ctrl+e should switch to external, got %q", m.editorMode)
+	}
+	if cmd := m.startEdit(task); cmd == nil || m.editing {
+		t.Error("startEdit should open $EDITOR in external mode")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updated.(model)
+	if cmd := m.startEdit(task); cmd != nil || !m.editing {
+		t.Error("startEdit should edit inline after switching back")
+	}
+}
+
+func TestRefreshDebounce(t *testing.T) {
+	tests := []struct {
+		ms      int
+		want    time.Duration
+		wantErr bool
+	}{
+		{0, defaultRefreshDebounce, false},
+		{1000, time.Second, false},
+		{-5, defaultRefreshDebounce, true},
+	}
+
+	for _, tt := range tests {
+		d, err := refreshDebounce(tt.ms)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidDebounce)) {
+			t.Errorf("refreshDebounce(%d) error = %v, wantErr %v", tt.ms, err, tt.wantErr)
+		}
+		if got := NewDebouncer(d).duration; got != tt.want {
+			t.Errorf("refreshDebounce(%d) gave the debouncer %v, want %v", tt.ms, got, tt.want)
+		}
+	}
+
+	var out strings.Builder
+	checkConfig(Config{RefreshDebounce: -1}, &out)
+	if !strings.Contains(out.String(), "WARN  refresh_debounce_ms must be positive") {
+		t.Errorf("--check should warn about the debounce, got:\n%s", out.String())
+	}
+}
+
+func TestCompleteSection(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work.md")
+	home := filepath.Join(dir, "home.md")
+	if err := os.WriteFile(work, []byte("- [ ] Report\n- [x] Filed\n- [ ] Review\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(home, []byte("- [ ] Laundry\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	workTasks, _ := parseFile(work, nil)
+	homeTasks, _ := parseFile(home, nil)
+
+	workQuery := &Query{Name: "Work"}
+	homeQuery := &Query{Name: "Home"}
+	sections := []QuerySection{
+		{Name: "Work", Query: workQuery, Groups: groupTasks(workTasks, "", "", ""), Tasks: workTasks},
+		{Name: "Home", Query: homeQuery, Groups: groupTasks(homeTasks, "", "", ""), Tasks: homeTasks},
+	}
+	m := newModel(sections, dir, "test", "", []*Query{workQuery, homeQuery}, "", nil, nil, nil)
+	m.cursor = 1
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("X")})
+	m = updated.(model)
+	if len(m.completing) != 2 {
+		t.Fatalf("Expected to confirm 2 open tasks, got %d", len(m.completing))
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "2 open task(s) in Work") {
+		t.Errorf("Confirmation should show the count:\n%s", view)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = updated.(model)
+	if m.completing != nil || m.err != nil {
+		t.Fatalf("Expected the section to be completed, err = %v", m.err)
+	}
+
+	got, _ := os.ReadFile(work)
+	done := defaultTaskFormat.formatDate(time.Now())
+	want := "- [x] Report ✅ " + done + "\n- [x] Filed\n- [x] Review ✅ " + done + "\n"
+	if string(got) != want {
+		t.Errorf("Unexpected work.md:\n%q\nwant:\n%q", got, want)
+	}
+	if got, _ := os.ReadFile(home); string(got) != "- [ ] Laundry\n" {
+		t.Errorf("Other sections should be untouched, got %q", got)
+	}
+	if len(m.undoStack) != 1 || len(m.undoStack[0].Entries) != 2 {
+		t.Fatalf("Expected one undo entry for both tasks, got %+v", m.undoStack)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	m = updated.(model)
+	if got, _ := os.ReadFile(work); string(got) != "- [ ] Report\n- [x] Filed\n- [ ] Review\n" {
+		t.Errorf("A single undo should reopen both tasks, got %q", got)
+	}
+}
+
+func TestCompleteSectionPartialFailureKeepsUndo(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work.md")
+	locked := filepath.Join(dir, "locked.md")
+	if err := os.WriteFile(work, []byte("- [ ] Report\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(locked, []byte("- [ ] Archive\n"), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	workTasks, _ := parseFile(work, nil)
+	lockedTasks, _ := parseFile(locked, nil)
+	tasks := append(workTasks, lockedTasks...)
+
+	m := newTestModel(t, tasks)
+	m.vaultPath = dir
+	m.completing = tasks
+	m.completeSection()
+
+	if m.notice != "cannot save: file is read-only" {
+		t.Errorf("Expected the read-only notice, got %q (err %v)", m.notice, m.err)
+	}
+	if got, _ := os.ReadFile(work); !strings.HasPrefix(string(got), "- [x] Report") {
+		t.Errorf("The writable file should be saved, got %q", got)
+	}
+	if len(m.undoStack) != 1 || len(m.undoStack[0].Entries) != 1 || m.undoStack[0].Entries[0].FilePath != work {
+		t.Fatalf("Expected undo for the written file only, got %+v", m.undoStack)
+	}
+}
+
+func TestApplyTaskEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.md")
+	other := filepath.Join(dir, "other.md")
+	content := "# Tasks\n- [ ] One\n- [ ] Two\n- [ ] Three\n- [ ] Four\n- [ ] Five\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(other, []byte("- [ ] Elsewhere\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tasks, _ := parseFile(path, nil)
+	elsewhere, _ := parseFile(other, nil)
+
+	tasks[0].Description = "One edited"
+	tasks[0].rebuildRawLine()
+	tasks[3].Description = "Four edited"
+	tasks[3].rebuildRawLine()
+	elsewhere[0].Description = "Elsewhere edited"
+	elsewhere[0].rebuildRawLine()
+
+	changes, err := saveTasks([]*Task{tasks[3], tasks[0], elsewhere[0], tasks[3]})
+	if err != nil {
+		t.Fatalf("saveTasks failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected a change per task, got %+v", changes)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "# Tasks\n- [ ] One edited\n- [ ] Two\n- [ ] Three\n- [ ] Four edited\n- [ ] Five\n"
+	if string(got) != want {
+		t.Errorf("Unexpected file:\n%q\nwant:\n%q", got, want)
+	}
+	if got, _ := os.ReadFile(other); string(got) != "- [ ] Elsewhere edited\n" {
+		t.Errorf("Unexpected other file: %q", got)
+	}
+
+	// Lines moved by an outside edit are found again
+	if err := os.WriteFile(path, []byte("# Moved\n"+want), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks[3].Toggle()
+	tasks[4].Toggle()
+	if _, err := saveTasks([]*Task{tasks[3], tasks[4]}); err != nil {
+		t.Fatalf("saveTasks failed: %v", err)
+	}
+	got, _ = os.ReadFile(path)
+	if lines := strings.Split(string(got), "\n"); !strings.HasPrefix(lines[5], "- [x] Four edited") || !strings.HasPrefix(lines[6], "- [x] Five") {
+		t.Errorf("Unexpected file after saveTasks:\n%s", got)
+	}
+}
+
+func TestSaveTasksDuplicateLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] Water plants\n- [ ] Water plants\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, _ := parseFile(path, nil)
+	// Both tasks still point at the first line, as after a line was removed
+	// above them
+	tasks[1].LineNumber = 1
+	tasks[0].SetPriority(PriorityHigh)
+	tasks[1].SetPriority(PriorityLow)
+	if _, err := saveTasks(tasks); err != nil {
+		t.Fatalf("saveTasks failed: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "- [ ] Water plants ⏫\n- [ ] Water plants 🔽\n" {
+		t.Errorf("Each task should take its own line, got %q", got)
+	}
+
+	// A third copy has no line left
+	extra := *tasks[0]
+	extra.OriginalRawLine, extra.RawLine = "- [ ] Water plants ⏫", "- [x] Water plants ⏫"
+	dup := extra
+	if _, err := saveTasks([]*Task{&extra, &dup}); !errors.Is(err, ErrTaskLineChanged) {
+		t.Errorf("Expected ErrTaskLineChanged without a free line, got %v", err)
+	}
+}
+
+func TestSnoozeDueDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Pay rent 📅 2025-03-31 ⏫\n- [ ] Call mom\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tasks, _ := parseFile(path, nil)
+	m := newTestModel(t, tasks)
+
+	press := func(key string) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+
+	press(">")
+	press("]")
+	if got := tasks[0].RawLine; got != "- [ ] Pay rent 📅 2025-04-08 ⏫" {
+		t.Errorf("Existing due date should move in place, got %q", got)
+	}
+
+	m.cursor = 1
+	press("<")
+	yesterday := defaultTaskFormat.formatDate(time.Now().AddDate(0, 0, -1))
+	if got := tasks[1].RawLine; got != "- [ ] Call mom 📅 "+yesterday {
+		t.Errorf("A due date should be added from today, got %q", got)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "- [ ] Pay rent 📅 2025-04-08 ⏫\n- [ ] Call mom 📅 " + yesterday + "\n"
+	if string(got) != want {
+		t.Errorf("Unexpected file:\n%q\nwant:\n%q", got, want)
+	}
+
+	press("u")
+	if tasks[1].DueDate != nil || tasks[1].RawLine != "- [ ] Call mom" {
+		t.Errorf("Undo should remove the added due date, got %q", tasks[1].RawLine)
+	}
+}
+
+func TestLayoutLeavesRoomForChrome(t *testing.T) {
+	var tasks []*Task
+	for i := 1; i <= 20; i++ {
+		tasks = append(tasks, &Task{Description: fmt.Sprintf("Task %02d", i), FilePath: "/vault/tasks.md", LineNumber: i})
+	}
+
+	for _, statusBar := range []bool{false, true} {
+		m := newTestModel(t, tasks)
+		m.statusBar = statusBar
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+		m = updated.(model)
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+		m = updated.(model)
+
+		header, footer := m.chromeHeights()
+		_, content, _ := m.layoutHeights()
+		if header+content+footer != 10 {
+			t.Errorf("statusBar=%v: %d+%d+%d rows don't fill the window", statusBar, header, content, footer)
+		}
+
+		lines := strings.Split(ansi.Strip(m.View()), "\n")
+		if len(lines) != 10 {
+			t.Fatalf("statusBar=%v: view has %d lines, want 10", statusBar, len(lines))
+		}
+		if !strings.Contains(lines[header+content-1], "Task 20") {
+			t.Errorf("statusBar=%v: the selected last task should sit right above the footer:\n%s", statusBar, strings.Join(lines, "\n"))
+		}
+		if statusBar && !strings.Contains(lines[len(lines)-2], "20/20") {
+			t.Errorf("The status bar should not be clipped:\n%s", strings.Join(lines, "\n"))
+		}
+	}
+
+	// A title too long for the window wraps the header onto more lines
+	m := newTestModel(t, tasks)
+	m.titleName = strings.Repeat("long title ", 6)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 30, Height: 10})
+	m = updated.(model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	m = updated.(model)
+
+	header, footer := m.chromeHeights()
+	if header < 2 {
+		t.Fatalf("Expected the long title to wrap, got a %d line header", header)
+	}
+	_, content, _ := m.layoutHeights()
+	lines := strings.Split(ansi.Strip(m.View()), "\n")
+	if len(lines) != 10 || header+content+footer != 10 {
+		t.Fatalf("wrapped header: view has %d lines, want 10:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[header+content-1], "Task 20") {
+		t.Errorf("The selected last task should stay visible under a wrapped header:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestNestedGroupBy(t *testing.T) {
+	query := parseQueryContent("not done\ngroup by folder\ngroup by priority")
+	if !slices.Equal(query.GroupBy, []string{"folder", "priority"}) {
+		t.Fatalf("GroupBy = %q, want folder then priority", query.GroupBy)
+	}
+
+	tasks := []*Task{
+		{Description: "Plan sprint", FilePath: "/vault/work/a.md", LineNumber: 1, Priority: PriorityNormal},
+		{Description: "Fix outage", FilePath: "/vault/work/b.md", LineNumber: 1, Priority: PriorityHighest},
+		{Description: "Water plants", FilePath: "/vault/home/c.md", LineNumber: 1, Priority: PriorityNormal},
+		{Description: "Review PR", FilePath: "/vault/work/a.md", LineNumber: 2, Priority: PriorityHighest},
+	}
+
+	groups := groupTasksBy(tasks, query.GroupBy, "", "/vault")
+	var got []string
+	for _, group := range groups {
+		got = append(got, fmt.Sprintf("%s/%s:%d", strings.Join(group.Parents, "/"), group.Name, len(group.Tasks)))
+	}
+	want := []string{"work/Highest:2", "work/Normal:1", "home/Normal:1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+
+	if headers := groupHeaders(groups, 1); len(headers) != 1 || headers[0] != (groupHeader{Name: "Normal", Level: 1, Count: 1}) {
+		t.Errorf("The second group should only open its own header, got %+v", headers)
+	}
+
+	sections := []QuerySection{{Name: "Tasks", Query: query, Groups: groups, Tasks: tasks}}
+	m := newModel(sections, "/vault", "test", "", []*Query{query}, "", nil, nil, nil)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = updated.(model)
+
+	view := ansi.Strip(m.View())
+	last := -1
+	for _, line := range []string{"  ## work (3)", "    ### Highest (2)", "Review PR", "    ### Normal (1)", "Plan sprint", "  ## home (1)", "    ### Normal (1)", "Water plants"} {
+		i := strings.Index(view[last+1:], line)
+		if i < 0 {
+			t.Fatalf("view is missing %q after the previous line:\n%s", line, view)
+		}
+		last += i + 1
+	}
+
+	var out strings.Builder
+	writeList(&out, sections, "/vault", len(tasks), false)
+	if list := out.String(); !strings.Contains(list, "### work\n#### Highest\n[ ] Fix outage") || strings.Count(list, "### work") != 1 {
+		t.Errorf("--list should nest the headers:\n%s", list)
+	}
+}
+
+func TestDiffTasks(t *testing.T) {
+	before := []*Task{
+		{Description: "Write report", LineNumber: 1},
+		{Description: "Call bank", LineNumber: 2},
+		{Description: "Old idea", LineNumber: 3},
+		{Description: "Shipped ✅ 2025-01-02", LineNumber: 4, Done: true},
+		{Description: "Typo in titel", LineNumber: 5},
+	}
+	after := []*Task{
+		{Description: "Write report ✅ 2025-03-01", LineNumber: 1, Done: true},
+		{Description: "Call bank", LineNumber: 2},
+		{Description: "Shipped", LineNumber: 3},
+		{Description: "Typo in title", LineNumber: 4},
+		{Description: "New one", LineNumber: 6},
+		{Description: "New two", LineNumber: 7},
+	}
+
+	// The fixed typo also moved up a line, so it counts as removed and added
+	diff := diffTasks(before, after)
+	want := TaskDiff{Added: 3, Removed: 2, Completed: 1, Reopened: 1}
+	if diff != want {
+		t.Errorf("diffTasks = %+v, want %+v", diff, want)
+	}
+	if got := diff.String(); got != "3 tasks added, 2 removed, 1 completed, 1 reopened" {
+		t.Errorf("String = %q", got)
+	}
+
+	edited := diffTasks(before[:1], []*Task{{Description: "Write the report", LineNumber: 1}})
+	if edited != (TaskDiff{Edited: 1}) || edited.String() != "1 task edited" {
+		t.Errorf("Same line, new text should be an edit, got %+v %q", edited, edited.String())
+	}
+
+	if got := diffTasks(before, before).String(); got != "" {
+		t.Errorf("No changes should give an empty summary, got %q", got)
+	}
+}
+
+func TestPlanTaskEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.md")
+	content := "- [ ] Keep\n- [ ] Finish\n- [ ] Drop\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tasks, _ := parseFile(path, nil)
+	done := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	tasks[1].DoneDate = &done
+	tasks[1].Toggle()
+
+	changes, err := planTaskEdits([]taskEdit{{Task: tasks[2], Delete: true}, {Task: tasks[1]}})
+	if err != nil {
+		t.Fatalf("planTaskEdits failed: %v", err)
+	}
+
+	var out strings.Builder
+	writeLineChanges(&out, changes, dir, false)
+	want := "tasks.md:2\n- - [ ] Finish\n+ - [x] Finish ✅ 2025-03-01\ntasks.md:3\n- - [ ] Drop\n"
+	if out.String() != want {
+		t.Errorf("Unexpected dry run:\n%q\nwant:\n%q", out.String(), want)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != content {
+		t.Errorf("A dry run must not write, got %q", got)
+	}
+	if tasks[1].OriginalRawLine != "- [ ] Finish" {
+		t.Errorf("A dry run must not update the tasks, got %q", tasks[1].OriginalRawLine)
+	}
+}
+
+func TestToggleTaskRef(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "notes"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	path := filepath.Join(vault, "notes", "x.md")
+	content := "# Notes\n- [ ] First\n- [x] Second ✅ 2025-01-01\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var out strings.Builder
+	if err := toggleTaskRef(&out, "notes/x.md:3", vault, nil, true, false); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if want := filepath.Join("notes", "x.md") + ":3\n- - [x] Second ✅ 2025-01-01\n+ - [ ] Second\n"; out.String() != want {
+		t.Errorf("dry run printed %q, want %q", out.String(), want)
+	}
+	if got, _ := os.ReadFile(path); string(got) != content {
+		t.Fatalf("dry run wrote the file: %q", got)
+	}
+
+	out.Reset()
+	if err := toggleTaskRef(&out, "notes/x.md:3", vault, nil, false, false); err != nil {
+		t.Fatalf("toggle failed: %v", err)
+	}
+	if out.String() != "- [ ] Second\n" {
+		t.Errorf("Expected the new line, got %q", out.String())
+	}
+	if got, _ := os.ReadFile(path); string(got) != "# Notes\n- [ ] First\n- [ ] Second\n" {
+		t.Errorf("Unexpected file: %q", got)
+	}
+
+	if err := toggleTaskRef(&out, "notes/x.md:1", vault, nil, false, false); !errors.Is(err, ErrNotTaskLine) {
+		t.Errorf("A heading should be ErrNotTaskLine, got %v", err)
+	}
+	for _, ref := range []string{"notes/x.md", "notes/x.md:0", ":3", "notes/x.md:two"} {
+		if err := toggleTaskRef(&out, ref, vault, nil, false, false); !errors.Is(err, ErrInvalidTaskRef) {
+			t.Errorf("%q should be ErrInvalidTaskRef, got %v", ref, err)
+		}
+	}
+}
+
+func TestTopBottomSkipHiddenTasks(t *testing.T) {
+	var tasks []*Task
+	for i, heading := range []string{"A", "A", "A", "B", "B"} {
+		tasks = append(tasks, &Task{Description: fmt.Sprintf("%s%d", heading, i), Heading: heading, FilePath: "/vault/t.md", LineNumber: i + 1})
+	}
+
+	queries := []*Query{
+		{Name: "A", Limit: 1, HeadingIncludes: []string{"A"}},
+		{Name: "B", HeadingIncludes: []string{"B"}},
+	}
+	m := newModel(nil, "/vault", "test", "", queries, "", nil, nil, nil)
+	m.allTasks = tasks
+	m.rebuildSections()
+
+	press := func(key string) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+
+	press("G")
+	if m.moreSelected || m.tasks[m.cursor].Description != "B4" {
+		t.Errorf("G should land on the last shown task, got %q", m.tasks[m.cursor].Description)
+	}
+
+	// From the "… 2 more" line of the limited section
+	press("g")
+	press("j")
+	if !m.moreSelected {
+		t.Fatal("j should select the more line below A0")
+	}
+	press("G")
+	if m.moreSelected || m.tasks[m.cursor].Description != "B4" {
+		t.Errorf("G from the more line should land on B4, got %q", m.tasks[m.cursor].Description)
+	}
+	press("g")
+	if m.moreSelected || m.tasks[m.cursor].Description != "A0" {
+		t.Errorf("g should land on the first shown task, got %q", m.tasks[m.cursor].Description)
+	}
+}
+
+func TestCompletedLast(t *testing.T) {
+	query := parseQueryContent("sort by priority\ncompleted last")
+	if !query.CompletedLast {
+		t.Fatal("completed last should be parsed")
+	}
+
+	tasks := []*Task{
+		{Description: "Ship release", Done: true, Priority: PriorityHighest},
+		{Description: "Write notes", Priority: PriorityHighest},
+		{Description: "Fix typo", Done: true, Priority: PriorityLow},
+		{Description: "Book flights", Priority: PriorityNormal},
+		{Description: "Call bank", Priority: PriorityLow},
+	}
+
+	names := func(groups []TaskGroup) []string {
+		var got []string
+		for _, task := range groups[0].Tasks {
+			got = append(got, task.Description)
+		}
+		return got
+	}
+
+	groups := groupTasksBy(tasks, query.GroupBy, query.SortBy, "/vault")
+	got := names(completedLast(groups))
+	want := []string{"Write notes", "Book flights", "Call bank", "Ship release", "Fix typo"}
+	if !slices.Equal(got, want) {
+		t.Errorf("completed last = %v, want %v", got, want)
+	}
+
+	got = names(completedLast(reverseGroups(groups)))
+	want = []string{"Call bank", "Book flights", "Write notes", "Fix typo", "Ship release"}
+	if !slices.Equal(got, want) {
+		t.Errorf("reversed completed last = %v, want %v", got, want)
+	}
+
+	if names(groups)[0] != "Ship release" {
+		t.Error("completedLast should not reorder the groups it was given")
+	}
+}
+
+func TestTaskAge(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 18, 0, 0, 0, time.Local)
+	day := func(d int) *time.Time {
+		date := time.Date(2024, time.June, d, 0, 0, 0, 0, time.Local)
+		return &date
+	}
+
+	tests := []struct {
+		task *Task
+		want string
+	}{
+		{&Task{CreatedDate: day(3)}, "12d old"},
+		{&Task{CreatedDate: day(15)}, "0d old"},
+		{&Task{CreatedDate: day(3), Done: true}, ""},
+		{&Task{}, ""},
+	}
+	for _, tt := range tests {
+		if got := taskAge(tt.task, now); got != tt.want {
+			t.Errorf("taskAge(%+v) = %q, want %q", tt.task, got, tt.want)
+		}
+	}
+
+	if got := ansi.Strip(dateBadges(&Task{CreatedDate: day(3)}, now)); got != " 12d old" {
+		t.Errorf("dateBadges = %q, want the age badge", got)
+	}
+
+	tasks := []*Task{
+		{Description: "undated"},
+		{Description: "recent", CreatedDate: day(14)},
+		{Description: "stale", CreatedDate: day(1)},
+	}
+	for sortBy, want := range map[string][]string{
+		"age":         {"stale", "recent", "undated"},
+		"age reverse": {"recent", "stale", "undated"},
+	} {
+		var got []string
+		for _, task := range sortTasks(tasks, sortBy) {
+			got = append(got, task.Description)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("sort by %s = %v, want %v", sortBy, got, want)
+		}
+	}
+}
+
+func TestToggleReadOnlyFile(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(vault, "tasks.md")
+	content := "- [ ] First\n"
+	if err := os.WriteFile(path, []byte(content), 0444); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	m := newModel(nil, vault, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = updated.(model)
+
+	if m.err != nil {
+		t.Fatalf("A read-only file should not show the error screen, got %v", m.err)
+	}
+	if m.notice != "cannot save: file is read-only" {
+		t.Errorf("notice = %q, want the read-only message", m.notice)
+	}
+	if task := m.tasks[0]; task.Done || task.RawLine != "- [ ] First" {
+		t.Errorf("The toggle should be reverted, got %+v", task)
+	}
+	if len(m.undoStack) != 0 {
+		t.Errorf("A failed toggle should not be undoable, got %d entries", len(m.undoStack))
+	}
+	if got, _ := os.ReadFile(path); string(got) != content {
+		t.Errorf("The file should be untouched, got %q", got)
+	}
+
+	// An inline edit is reverted too
+	m.notice = ""
+	m.editing, m.editingTask = true, m.tasks[0]
+	m.textInput.SetValue("Renamed")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if m.err != nil || m.notice != "cannot save: file is read-only" {
+		t.Fatalf("A read-only edit should only notify, got err %v, notice %q", m.err, m.notice)
+	}
+	if task := m.tasks[0]; task.Description != "First" || task.RawLine != "- [ ] First" {
+		t.Errorf("The edit should be reverted, got %q", task.RawLine)
+	}
+
+	// So is a quick delete, which isn't reported as done
+	m.notice = ""
+	m.quickDelete = true
+	m.startDelete(m.tasks[0])
+	if m.err != nil || m.notice != "cannot save: file is read-only" {
+		t.Errorf("A read-only delete should only notify, got err %v, notice %q", m.err, m.notice)
+	}
+}
+
+func TestProfileDefaultGroupAndSort(t *testing.T) {
+	queries := []*Query{
+		parseQueryContent("not done"),
+		parseQueryContent("not done\ngroup by priority\nsort by description"),
+	}
+	tasks := []*Task{
+		{Description: "b", FilePath: "/vault/work/a.md", LineNumber: 1, Priority: PriorityHigh},
+		{Description: "a", FilePath: "/vault/home/b.md", LineNumber: 1, Priority: PriorityNormal},
+	}
+
+	m := newModel(nil, "/vault", "test", "", queries, "", nil, nil, nil)
+	m.defaultGroup, m.defaultSort = "folder", "description"
+	m.allTasks = tasks
+	m.rebuildSections()
+
+	groupNames := func(s QuerySection) []string {
+		var names []string
+		for _, group := range s.Groups {
+			names = append(names, group.Name)
+		}
+		return names
+	}
+
+	if got := groupNames(m.sections[0]); !slices.Equal(got, []string{"work", "home"}) {
+		t.Errorf("A block without group by should use the profile's, got %v", got)
+	}
+	if got := m.sections[0].Query.SortBy; got != "description" {
+		t.Errorf("A block without sort by should use the profile's, got %q", got)
+	}
+	if got := groupNames(m.sections[1]); !slices.Equal(got, []string{"High", "Normal"}) {
+		t.Errorf("A block's own group by should win, got %v", got)
+	}
+	if len(queries[0].GroupBy) != 0 || queries[0].SortBy != "" {
+		t.Error("The parsed query should be left untouched")
+	}
+}
+
+func TestUppercaseDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [X] Shipped ✅ 2025-01-01\n- [ ] Open\n- [x] Lower ✅ 2025-01-02\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	for _, tt := range []struct {
+		uppercase bool
+		want      []string
+	}{
+		{false, []string{"- [X] Shipped", "- [x] Open", "- [x] Lower"}},
+		{true, []string{"- [X] Shipped", "- [X] Open", "- [X] Lower"}},
+	} {
+		format := newTaskFormat("")
+		format.uppercaseDone = tt.uppercase
+
+		tasks, err := parseFile(path, format)
+		if err != nil {
+			t.Fatalf("parseFile failed: %v", err)
+		}
+		if !tasks[0].Done {
+			t.Fatal("[X] should parse as done")
+		}
+
+		tasks[0].SetPriority(PriorityHigh)
+		tasks[1].Toggle()
+		tasks[2].SetPriority(PriorityHigh)
+
+		for i, task := range tasks {
+			if !strings.HasPrefix(task.RawLine, tt.want[i]) {
+				t.Errorf("uppercase_done=%v: line %d = %q, want %q", tt.uppercase, i+1, task.RawLine, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNextActions(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2024, time.June, d, 0, 0, 0, 0, time.Local)
+		return &date
+	}
+
+	later := &Task{Description: "high, due later", Priority: PriorityHigh, DueDate: day(20)}
+	sooner := &Task{Description: "high, due sooner", Priority: PriorityHigh, DueDate: day(10)}
+	undated := &Task{Description: "high, no due", Priority: PriorityHigh}
+	normal := &Task{Description: "normal, due first", Priority: PriorityNormal, DueDate: day(1)}
+	done := &Task{Description: "done highest", Priority: PriorityHighest, Done: true}
+
+	got := nextActions([]*Task{normal, later, done, undated, sooner, later})
+	want := []*Task{sooner, later, undated, normal}
+	if !slices.Equal(got, want) {
+		var names []string
+		for _, task := range got {
+			names = append(names, task.Description)
+		}
+		t.Fatalf("nextActions = %v, want highest priority first, then soonest due", names)
+	}
+
+	m := newTestModel(t, []*Task{normal, later, sooner})
+	m.sections = []QuerySection{{Name: "Tasks", Tasks: []*Task{normal, later, sooner}}}
+	m.nextIndex = -1
+	if task, i, total := m.nextAction(); task != normal || i != 2 || total != 3 {
+		t.Errorf("Skipping back from the first action should wrap to the last, got %q (%d of %d)", task.Description, i+1, total)
+	}
+}
+
+func TestNextActionContextLoadedOnMove(t *testing.T) {
+	dir := t.TempDir()
+	alpha := filepath.Join(dir, "alpha.md")
+	beta := filepath.Join(dir, "beta.md")
+	if err := os.WriteFile(alpha, []byte("Alpha notes\n- [ ] Urgent ⏫\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(beta, []byte("Beta notes\n- [ ] Someday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	alphaTasks, _ := parseFile(alpha, nil)
+	betaTasks, _ := parseFile(beta, nil)
+	tasks := append(alphaTasks, betaTasks...)
+
+	m := newTestModel(t, tasks)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	m = updated.(model)
+	press := func(key tea.KeyMsg) {
+		updated, _ := m.Update(key)
+		m = updated.(model)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if m.nextTask != alphaTasks[0] || !strings.Contains(ansi.Strip(m.nextContext), "Alpha notes") {
+		t.Fatalf("Opening should load the first action's context, got %q", m.nextContext)
+	}
+
+	// Drawing the view uses the loaded context without reading the note
+	if err := os.Remove(alpha); err != nil {
+		t.Fatal(err)
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "Alpha notes") {
+		t.Errorf("The view should show the loaded context:\n%s", view)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if m.nextTask != betaTasks[0] || !strings.Contains(ansi.Strip(m.nextContext), "Beta notes") {
+		t.Errorf("Moving on should load the next action's context, got %q", m.nextContext)
+	}
+}
+
+func TestJumpPercent(t *testing.T) {
+	tasks := make([]*Task, 100)
+	for i := range tasks {
+		tasks[i] = &Task{Description: fmt.Sprintf("Task %d", i+1), FilePath: "/vault/a.md", LineNumber: i + 1}
+	}
+	m := newTestModel(t, tasks)
+
+	press := func(keys string) {
+		for _, r := range keys {
+			updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			m = updated.(model)
+		}
+	}
+
+	press("50%")
+	if m.cursor != 49 {
+		t.Errorf("50%% should land on task 50, got %d", m.cursor+1)
+	}
+
+	press("250%")
+	if m.cursor != 99 {
+		t.Errorf("Over 100%% should clamp to the last task, got %d", m.cursor+1)
+	}
+
+	press("1%")
+	if m.cursor != 0 {
+		t.Errorf("1%% should land on the first task, got %d", m.cursor+1)
+	}
+
+	press("5j")
+	if m.cursor != 1 || m.countPrefix != "" {
+		t.Errorf("A count before another key should be dropped, got cursor %d and count %q", m.cursor, m.countPrefix)
+	}
+}
+
+func TestPollDetectsChangedFiles(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(vault, "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] Synced task\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	m := newModel(nil, vault, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.pollInterval = time.Millisecond
+	m.refresh()
+
+	msg := m.pollCmd()().(pollMsg)
+	if len(msg.changed) != 0 {
+		t.Fatalf("Nothing changed yet, got %v", msg.changed)
+	}
+
+	// Completed on another device and synced in
+	if err := os.WriteFile(path, []byte("- [x] Synced task\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+
+	msg = m.pollCmd()().(pollMsg)
+	if !slices.Equal(msg.changed, []string{path}) {
+		t.Fatalf("The poll should find the changed file, got %v", msg.changed)
+	}
+
+	updated, cmd := m.Update(msg)
+	m = updated.(model)
+	if !m.refreshing || cmd == nil {
+		t.Fatal("A changed file should start a refresh")
+	}
+	for _, c := range cmd().(tea.BatchMsg) {
+		if done, ok := c().(refreshDoneMsg); ok {
+			updated, _ = m.Update(done)
+			m = updated.(model)
+		}
+	}
+
+	if len(m.tasks) != 1 || !m.tasks[0].Done {
+		t.Errorf("The refresh should pick up the completion, got %+v", m.tasks)
+	}
+
+	var out strings.Builder
+	checkConfig(Config{PollInterval: -1}, &out)
+	if !strings.Contains(out.String(), "WARN  poll_interval must not be negative") {
+		t.Errorf("--check should warn about the poll interval, got:\n%s", out.String())
+	}
+}
+
+func TestSectionOrder(t *testing.T) {
+	queries := []*Query{
+		{Name: "Inbox"},
+		{Name: "Today"},
+		{Name: "Overdue"},
+		{},
+	}
+	tasks := []*Task{{Description: "Task", FilePath: "/vault/a.md", LineNumber: 1}}
+
+	m := newModel(nil, "/vault", "test", "", queries, "", nil, nil, nil)
+	m.sectionOrder = []string{"overdue", "Today", "Missing"}
+	m.allTasks = tasks
+	m.rebuildSections()
+
+	var got []string
+	for _, section := range m.sections {
+		got = append(got, section.Name)
+	}
+	if want := []string{"Overdue", "Today", "Inbox", ""}; !slices.Equal(got, want) {
+		t.Errorf("sections = %q, want %q", got, want)
+	}
+
+	if sections := orderSections(m.sections, nil); &sections[0] != &m.sections[0] {
+		t.Error("No section_order should leave the sections as they are")
+	}
+}
+
+func TestFooterAlignsWideRunes(t *testing.T) {
+	m := newTestModel(t, nil)
+	m.windowWidth = 40
+
+	for _, left := range []string{
+		"📅 due soon ⚠",
+		"📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅📅",
+	} {
+		line := ansi.Strip(m.renderFooterSplit(dimTextStyle.Render(left), helpBarInfoStyle.Render("1-5 of 9")))
+		if got := ansi.StringWidth(line); got != m.windowWidth {
+			t.Errorf("footer for %q is %d cells wide, want %d", left, got, m.windowWidth)
+		}
+		if !strings.HasSuffix(line, "1-5 of 9") {
+			t.Errorf("The scroll indicator should end at the right edge, got %q", line)
+		}
+	}
+
+	m.searching = true
+	for _, key := range []string{"c", "a", "f", "é"} {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(model)
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = updated.(model)
+	if m.searchQuery != "caf" {
+		t.Errorf("Backspace should drop the whole é, got %q", m.searchQuery)
+	}
+}
+
+func TestRenderCheckboxLineCachesGlamour(t *testing.T) {
+	initRenderer(defaultTheme)
+	t.Cleanup(func() { initRenderer(defaultTheme) })
+
+	got := renderCheckboxLine(false, "ship **bold** now", checkboxGlyphs{})
+	if stripped := strings.TrimSpace(ansi.Strip(got)); stripped != "[ ] ship bold now" {
+		t.Errorf("Expected markdown to be rendered, got %q", stripped)
+	}
+	if got == ansi.Strip(got) {
+		t.Error("Expected **bold** to be styled")
+	}
+
+	if _, ok := renderedLines["- [ ] ship **bold** now"]; !ok {
+		t.Fatal("Expected the rendered line to be cached")
+	}
+	if again := renderCheckboxLine(false, "ship **bold** now", checkboxGlyphs{}); again != got {
+		t.Errorf("The cached line should match, got %q and %q", again, got)
+	}
+
+	initRenderer(defaultTheme)
+	if len(renderedLines) != 0 {
+		t.Error("A new renderer should start with an empty cache")
+	}
+}
+
+func TestStaleRefreshIsDropped(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(vault, "tasks.md")
+	if err := os.WriteFile(path, []byte("- [ ] Old\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	m := newModel(nil, vault, "test", "", []*Query{{}}, "", NewTaskCache(), nil, nil)
+	m.refresh()
+
+	cmd := m.refreshCmd()
+	if !m.refreshing {
+		t.Fatal("refreshCmd should mark the model as refreshing")
+	}
+	stale := cmd()
+
+	if err := os.WriteFile(path, []byte("- [ ] Old\n- [ ] New\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	m.cache.Invalidate(path)
+	m.refresh()
+
+	updated, _ := m.Update(stale)
+	m = updated.(model)
+	if len(m.tasks) != 2 {
+		t.Errorf("A refresh started before a newer one should be dropped, got %d tasks", len(m.tasks))
+	}
+}
+
+func TestHelpHintsFollowKeymap(t *testing.T) {
+	m := newTestModel(t, []*Task{{Description: "One"}})
+	m.windowWidth = 120
+	m.windowHeight = 60
+	m.keys = newKeymap(map[string]string{"help": "ctrl+g", "quit": "ctrl+q", "up": "ctrl+p", "down": "ctrl+o"})
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	m = updated.(model)
+	if !m.aboutOpen {
+		t.Fatal("Expected ctrl+g to open help")
+	}
+
+	want := "esc/ctrl+q/ctrl+g close • / filter • ctrl+p ctrl+o scroll"
+	if view := ansi.Strip(m.View()); !strings.Contains(view, want) {
+		t.Errorf("Expected the help to show %q, got:\n%s", want, view)
+	}
+
+	m.err = errors.New("boom")
+	if view := m.View(); !strings.Contains(view, "Press ctrl+q to quit.") {
+		t.Errorf("Expected the error view to show the remapped quit key, got %q", view)
+	}
+}
+
+func TestParseFileTagLineKeepsNesting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Project\n#work #urgent\n  - [ ] Step one\n#\n  - [ ] Step two\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(tasks))
+	}
+	if tasks[1].Parent != tasks[0] {
+		t.Errorf("Expected a tag line to keep Step one under Project")
+	}
+	if tasks[2].Parent != nil {
+		t.Errorf("Expected an empty heading to end the list")
+	}
+}
+
+func TestMouseIgnoredUnderModals(t *testing.T) {
+	modals := []struct {
+		name string
+		open func(m *model)
+	}{
+		{"copy menu", func(m *model) { m.copyMenuOpen = true }},
+		{"parse warnings", func(m *model) { m.warningsOpen = true }},
+		{"duplicates", func(m *model) { m.duplicates = []DuplicateGroup{} }},
+	}
+
+	for _, tt := range modals {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "test.md")
+			if err := os.WriteFile(testFile, []byte("- [ ] Task one\n- [ ] Task two\n- [ ] Task three\n"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			tasks, err := parseFile(testFile, nil)
+			if err != nil {
+				t.Fatalf("parseFile failed: %v", err)
+			}
+
+			m := newTestModel(t, tasks)
+			tt.open(&m)
+
+			// The checkbox of the third task, then the wheel
+			updated, _ := m.Update(tea.MouseMsg{X: 3, Y: 3, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+			m = updated.(model)
+			updated, _ = m.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+			m = updated.(model)
+
+			if m.cursor != 0 {
+				t.Errorf("Expected the cursor to stay on task 0, got %d", m.cursor)
+			}
+			if tasks[2].Done {
+				t.Error("Expected the click not to toggle the task")
+			}
+			got, _ := os.ReadFile(testFile)
+			if strings.Contains(string(got), "[x]") {
+				t.Errorf("Expected the file unchanged, got:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestConfigureModelSharedByTabs(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	confirm := false
+	cfg := Config{InboxFile: "inbox.md", ConfirmDelete: &confirm, Keybindings: map[string]string{"quit": "ctrl+q"}}
+	tabs := []ProfileTab{{Profile: &ResolvedProfile{Name: "one"}}, {Profile: &ResolvedProfile{Name: "two"}}}
+
+	for _, m := range []model{newModelWithTabs(tabs), newTestModel(t, nil)} {
+		configureModel(&m, cfg, scanOptions{}, checkboxGlyphs{todo: "○"}, true, true, time.Second)
+		if m.inboxFile != "inbox.md" || !m.quickDelete || !m.fullPaths || !m.reversed || m.pollInterval != time.Second {
+			t.Errorf("Expected the config applied, got inbox %q, quick delete %v", m.inboxFile, m.quickDelete)
+		}
+		if m.checkboxes.todo != "○" || !m.keys.is("ctrl+q", actionQuit) {
+			t.Errorf("Expected the checkboxes and keymap applied")
+		}
+		if m.pinsPath == "" || m.historyPath == "" {
+			t.Errorf("Expected the pins and search history loaded")
+		}
+	}
+}
+
+func TestDeleteConfirmMentionsUndo(t *testing.T) {
+	tasks := []*Task{{Description: "Doomed"}}
+	m := newTestModel(t, tasks)
+	m.deleting = true
+	m.deletingTask = tasks[0]
+
+	view := m.View()
+	if strings.Contains(view, "cannot be undone") || !strings.Contains(view, "u afterwards to undo") {
+		t.Errorf("Expected the confirm to point at undo, got:\n%s", view)
+	}
+}
+
+func TestAddTaskSkipsContinuationLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "- [ ] Write the report\n  covering both quarters\n- [ ] Send it\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatalf("parseFile failed: %v", err)
+	}
+
+	added, err := addTask(tasks[0], "Proofread it")
+	if err != nil {
+		t.Fatalf("addTask failed: %v", err)
+	}
+	if added.LineNumber != 3 {
+		t.Errorf("Expected the new task on line 3, got %d", added.LineNumber)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "- [ ] Write the report\n  covering both quarters\n- [ ] Proofread it\n- [ ] Send it\n"
+	if string(got) != want {
+		t.Errorf("Unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCompleteSectionConfirmMentionsUndo(t *testing.T) {
+	tasks := []*Task{{Description: "One"}, {Description: "Two"}}
+	m := newTestModel(t, tasks)
+	m.completing = tasks
+	m.completingSection = "Today"
+
+	view := m.View()
+	if strings.Contains(view, "one task at a time") || !strings.Contains(view, "single u reopens the whole section") {
+		t.Errorf("Expected the confirm to say one undo reverts the section, got:\n%s", view)
+	}
+}
+
+func TestApplyTaskEditsMixesDeletesAndInserts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.md")
+	content := "# Tasks\n- [ ] One\n- [ ] Two\n  with a note\n- [ ] Three\n- [ ] Four\n- [ ] Five\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := parseFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks[3].Toggle()
+	tasks[4].Description = "Five edited"
+	tasks[4].rebuildRawLine()
+
+	// Out of order, with an insert right above a delete and one at the end
+	edits := []taskEdit{
+		{Task: tasks[3]},
+		{Task: tasks[2], Delete: true},
+		{Task: tasks[0], Insert: []string{"- [ ] One and a half"}},
+		{Task: tasks[1], Delete: true},
+		{Task: tasks[4], Insert: []string{"- [ ] Six", "- [ ] Seven"}},
+	}
+	changes, err := applyTaskEdits(edits)
+	if err != nil {
+		t.Fatalf("applyTaskEdits failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "# Tasks\n- [ ] One\n- [ ] One and a half\n" + tasks[3].RawLine + "\n- [ ] Five edited\n- [ ] Six\n- [ ] Seven\n"
+	if string(got) != want {
+		t.Errorf("Unexpected file:\n%q\nwant:\n%q", got, want)
+	}
+
+	// Two, its note and Three deleted, three rewrites and three inserts
+	var deleted, inserted int
+	for _, change := range changes {
+		if change.Deleted {
+			deleted++
+		}
+		if change.Inserted {
+			inserted++
+		}
+	}
+	if deleted != 3 || inserted != 3 || len(changes) != 9 {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+
+	// Kept tasks follow the lines removed and added above them
+	if tasks[0].LineNumber != 2 || tasks[3].LineNumber != 4 || tasks[4].LineNumber != 5 {
+		t.Errorf("Line numbers = %d, %d, %d, want 2, 4, 5", tasks[0].LineNumber, tasks[3].LineNumber, tasks[4].LineNumber)
+	}
+
+	// A second batch finds the lines at their new positions
+	tasks[4].Toggle()
+	if _, err := saveTasks([]*Task{tasks[4]}); err != nil {
+		t.Fatalf("saveTasks failed: %v", err)
+	}
+	got, _ = os.ReadFile(path)
+	if lines := strings.Split(string(got), "\n"); !strings.HasPrefix(lines[4], "- [x] Five edited") {
+		t.Errorf("Unexpected file after saveTasks:\n%s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// noteContextLines is how many lines of the note are shown above and below
+// a next action
+const noteContextLines = 6
+
+// nextActions orders open tasks for the next action view: highest priority
+// first, then soonest due, each task once
+func nextActions(tasks []*Task) []*Task {
+	seen := make(map[*Task]bool, len(tasks))
+	var open []*Task
+	for _, task := range tasks {
+		if task.Done || seen[task] {
+			continue
+		}
+		seen[task] = true
+		open = append(open, task)
+	}
+
+	// Stable sorts, so due breaks ties within a priority
+	return sortTasks(sortTasks(open, "due"), "priority")
+}
+
+// noteContext reads the lines of task's note within n lines of it
+func noteContext(task *Task, n int) (string, error) {
+	content, err := os.ReadFile(task.FilePath)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	line := task.LineNumber - 1
+	start := max(0, line-n)
+	end := min(len(lines), line+n+1)
+	if start >= end {
+		return "", nil
+	}
+	return strings.Join(lines[start:end], "\n"), nil
+}
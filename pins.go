@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const pinnedSectionName = "Pinned"
+
+// Pin references a task that is always shown at the top of the view. Text
+// holds the task's raw line so the pin can follow it when lines move.
+type Pin struct {
+	FilePath   string `json:"file"`
+	LineNumber int    `json:"line"`
+	Text       string `json:"text,omitempty"`
+}
+
+// pinsPath returns where pins are persisted under the XDG data directory
+func pinsPath() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataDir, "ot", "pins.json"), nil
+}
+
+// loadPins reads pins from path, returning none if the file doesn't exist
+func loadPins(path string) ([]Pin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var pins []Pin
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, err
+	}
+
+	return pins, nil
+}
+
+// savePins writes pins to path, creating the parent directory if needed
+func savePins(path string, pins []Pin) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// isPinned reports whether task is referenced by one of pins
+func isPinned(pins []Pin, task *Task) bool {
+	for _, pin := range pins {
+		if pin.FilePath == task.FilePath && pin.LineNumber == task.LineNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// prunePins drops pins whose file is gone and, for files inside vaultPath,
+// moves each pin to the task holding its text or drops it when no task is
+// left on its line. Pins into other vaults are kept as they are. It reports
+// whether any pin was dropped or moved.
+func prunePins(pins []Pin, vaultPath string, tasks []*Task) ([]Pin, bool) {
+	var kept []Pin
+	changed := false
+
+	for _, pin := range pins {
+		if _, err := os.Stat(pin.FilePath); err != nil {
+			changed = true
+			continue
+		}
+
+		rel := relPath(vaultPath, pin.FilePath)
+		if rel == pin.FilePath || strings.HasPrefix(rel, "..") {
+			kept = append(kept, pin)
+			continue
+		}
+
+		task := pinnedTask(pin, tasks)
+		if task == nil {
+			changed = true
+			continue
+		}
+
+		if task.LineNumber != pin.LineNumber || task.RawLine != pin.Text {
+			pin.LineNumber = task.LineNumber
+			pin.Text = task.RawLine
+			changed = true
+		}
+
+		kept = append(kept, pin)
+	}
+
+	return kept, changed
+}
+
+// pinnedTask finds the task pin refers to: the task in its file carrying its
+// text nearest to its line, or else the task still on its line, which was
+// edited in place
+func pinnedTask(pin Pin, tasks []*Task) *Task {
+	var atLine, nearest *Task
+
+	for _, task := range tasks {
+		if task.FilePath != pin.FilePath {
+			continue
+		}
+		if task.LineNumber == pin.LineNumber {
+			atLine = task
+		}
+		if pin.Text != "" && task.RawLine == pin.Text {
+			if nearest == nil || lineDistance(task, pin) < lineDistance(nearest, pin) {
+				nearest = task
+			}
+		}
+	}
+
+	if nearest != nil {
+		return nearest
+	}
+	return atLine
+}
+
+// lineDistance returns how many lines task is away from pin
+func lineDistance(task *Task, pin Pin) int {
+	if task.LineNumber > pin.LineNumber {
+		return task.LineNumber - pin.LineNumber
+	}
+	return pin.LineNumber - task.LineNumber
+}
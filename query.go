@@ -2,24 +2,42 @@ package main
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 var (
-	blockRe         = regexp.MustCompile("(?s)```tasks\\s*\\n(.+?)```")
 	headerRe        = regexp.MustCompile(`(?m)^##\s+(.+)$`)
 	groupByFuncRe   = regexp.MustCompile(`group by function task\.file\.(\w+)`)
-	groupBySimpleRe = regexp.MustCompile(`group by (\w+)`)
-	dateFilterRe    = regexp.MustCompile(`(due|scheduled|done)\s+((?:today|tomorrow|yesterday)(?:\s+or\s+(?:today|tomorrow|yesterday))*|before\s+\S+|after\s+\S+|on\s+\S+(?:\s+or\s+\S+)*)`)
-	sortByRe        = regexp.MustCompile(`sort by (\w+)`)
+	groupBySimpleRe = regexp.MustCompile(`group by (\w+(?: \d+)?)`)
+	dateFilterRe    = regexp.MustCompile(`(due|scheduled|starts|done)\s+((?:today|tomorrow|yesterday)(?:\s+or\s+(?:today|tomorrow|yesterday))*|before\s+\S+|after\s+\S+|on\s+\S+(?:\s+or\s+\S+)*)`)
+	limitRe         = regexp.MustCompile(`^limit (?:to )?(\d+)(?: tasks?)?$`)
+	sortByRe        = regexp.MustCompile(`sort by (\w+( reverse)?)`)
 )
 
+// ErrNoQueryBlock is returned for query files without a query block
+var ErrNoQueryBlock = errors.New("no query block found")
+
+// defaultQueryBlock is the fence label of Obsidian Tasks query blocks
+const defaultQueryBlock = "tasks"
+
+// blockLabel returns the fence label read from query files (config
+// "query_block"), "tasks" when unset
+func blockLabel(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return defaultQueryBlock
+	}
+	return label
+}
+
 // DateFilter represents a date-based filter
 type DateFilter struct {
 	Field    string
@@ -28,27 +46,67 @@ type DateFilter struct {
 	Dates    []string
 }
 
-// Query represents parsed query options
+// Query represents parsed query options. Every entry of DateFilters must
+// match (AND), so "due after X" and "due before Y" select the dates between.
 type Query struct {
-	Name        string
-	NotDone     bool
-	GroupBy     string
-	DateFilters []DateFilter
-	SortBy      string
+	Name            string
+	NotDone         bool
+	DoneOnly        bool     // Bare "done": only completed tasks
+	Short           bool     // Compact lines without the file suffix
+	Wrap            bool     // Soft-wrap long lines
+	CompletedLast   bool     // Done tasks after open ones within each group
+	Limit           int      // Tasks shown before a "more" line, 0 for all
+	HeadingIncludes []string // Substrings the task's heading must contain
+	HeadingExcludes []string // Substrings the task's heading must not contain
+	GroupBy         []string // One key per "group by" line, outermost first
+	DateFilters     []DateFilter
+	AnyDateFilters  []DateFilter // At least one must match
+	SortBy          string
+	Hide            map[string]bool // Metadata left out of the rows, by hideFields name
 }
 
-// TaskGroup represents a group of tasks
+// hideFields are the fields accepted by "hide <field>"
+var hideFields = []string{"due date", "priority", "backlink", "tags"}
+
+// TaskGroup represents a group of tasks. With several group by keys only
+// the innermost groups are kept, each naming the groups it's nested in.
 type TaskGroup struct {
-	Name  string
-	Tasks []*Task
+	Name    string
+	Tasks   []*Task
+	Parents []string // Enclosing group names, outermost first
 }
 
 // QuerySection represents a section with its query and results
 type QuerySection struct {
 	Name   string
 	Query  *Query
+	Index  int // Position of Query among the queries the sections were built from
 	Groups []TaskGroup
 	Tasks  []*Task
+	Hidden int // Tasks left out of Groups by the query's limit
+}
+
+// orderSections moves the sections named in order to the front, in that
+// order; the rest follow in their own order
+func orderSections(sections []QuerySection, order []string) []QuerySection {
+	if len(order) == 0 {
+		return sections
+	}
+
+	rank := func(s QuerySection) int {
+		if i := slices.IndexFunc(order, func(name string) bool {
+			return s.Name != "" && strings.EqualFold(name, s.Name)
+		}); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+
+	sorted := slices.Clone(sections)
+	slices.SortStableFunc(sorted, func(a, b QuerySection) int {
+		return rank(a) - rank(b)
+	})
+	return sorted
 }
 
 // OrderedMap maintains insertion order for keys
@@ -79,9 +137,27 @@ func (m *OrderedMap[K, V]) Keys() []K {
 	return m.order
 }
 
+// Delete removes key, keeping the order of the remaining keys
+func (m *OrderedMap[K, V]) Delete(key K) {
+	if _, exists := m.data[key]; !exists {
+		return
+	}
+	delete(m.data, key)
+	m.order = slices.DeleteFunc(m.order, func(k K) bool { return k == key })
+}
+
+// Each calls fn for every entry in insertion order until fn returns false
+func (m *OrderedMap[K, V]) Each(fn func(key K, value V) bool) {
+	for _, key := range m.order {
+		if !fn(key, m.data[key]) {
+			return
+		}
+	}
+}
+
 // parseQueryFile checks if the query file contains "not done" filter
 func parseQueryFile(filePath string) (bool, error) {
-	queries, err := parseAllQueryBlocks(filePath)
+	queries, err := parseAllQueryBlocks(filePath, defaultQueryBlock)
 
 	if err != nil {
 		return false, err
@@ -96,31 +172,33 @@ func parseQueryFile(filePath string) (bool, error) {
 
 // parseQueryFileExtended parses the first query block
 func parseQueryFileExtended(filePath string) (*Query, error) {
-	queries, err := parseAllQueryBlocks(filePath)
+	queries, err := parseAllQueryBlocks(filePath, defaultQueryBlock)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if len(queries) == 0 {
-		return nil, fmt.Errorf("no ```tasks block found in %s", filePath)
+		return nil, fmt.Errorf("no ```%s block found in %s", defaultQueryBlock, filePath)
 	}
 
 	return queries[0], nil
 }
 
-// parseAllQueryBlocks parses all ```tasks blocks from a file
-func parseAllQueryBlocks(filePath string) ([]*Query, error) {
+// parseAllQueryBlocks parses all query blocks fenced with label from a file
+func parseAllQueryBlocks(filePath string, label string) ([]*Query, error) {
 	content, err := os.ReadFile(filePath)
 
 	if err != nil {
 		return nil, err
 	}
 
+	label = blockLabel(label)
+	blockRe := regexp.MustCompile("(?s)```" + regexp.QuoteMeta(label) + "\\s*\\n(.+?)```")
 	matches := blockRe.FindAllStringSubmatchIndex(string(content), -1)
 
 	if matches == nil {
-		return nil, fmt.Errorf("no ```tasks block found in %s", filePath)
+		return nil, fmt.Errorf("%w: no ```%s block in %s", ErrNoQueryBlock, label, filePath)
 	}
 
 	headers := headerRe.FindAllStringSubmatchIndex(string(content), -1)
@@ -150,12 +228,42 @@ func parseAllQueryBlocks(filePath string) ([]*Query, error) {
 	return queries, nil
 }
 
-// parseQueryContent parses the content of a single ```tasks block
+// parseQueryContent parses the content of a single query block
 func parseQueryContent(queryContent string) *Query {
 	query := &Query{}
 
-	if strings.Contains(queryContent, "not done") {
-		query.NotDone = true
+	// Status filters must be a whole line so text like "is not done yet"
+	// elsewhere in the block doesn't switch them on
+	for _, line := range strings.Split(queryContent, "\n") {
+		switch strings.TrimSpace(line) {
+		case "not done":
+			query.NotDone = true
+		case "done":
+			query.DoneOnly = true
+		case "short", "short mode":
+			query.Short = true
+		case "wrap":
+			query.Wrap = true
+		case "completed last":
+			query.CompletedLast = true
+		}
+
+		if m := limitRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			query.Limit, _ = strconv.Atoi(m[1])
+		}
+
+		if field, ok := strings.CutPrefix(strings.TrimSpace(line), "hide "); ok && slices.Contains(hideFields, field) {
+			if query.Hide == nil {
+				query.Hide = make(map[string]bool)
+			}
+			query.Hide[field] = true
+		}
+
+		if text, ok := strings.CutPrefix(strings.TrimSpace(line), "heading includes "); ok {
+			query.HeadingIncludes = append(query.HeadingIncludes, strings.TrimSpace(text))
+		} else if text, ok := strings.CutPrefix(strings.TrimSpace(line), "heading does not include "); ok {
+			query.HeadingExcludes = append(query.HeadingExcludes, strings.TrimSpace(text))
+		}
 	}
 
 	dateMatches := dateFilterRe.FindAllStringSubmatch(queryContent, -1)
@@ -194,11 +302,13 @@ func parseQueryContent(queryContent string) *Query {
 		})
 	}
 
-	if funcMatch := groupByFuncRe.FindStringSubmatch(queryContent); funcMatch != nil {
-		query.GroupBy = funcMatch[1]
-	} else if simpleMatch := groupBySimpleRe.FindStringSubmatch(queryContent); simpleMatch != nil {
-		if simpleMatch[1] != "function" {
-			query.GroupBy = simpleMatch[1]
+	for _, line := range strings.Split(queryContent, "\n") {
+		if funcMatch := groupByFuncRe.FindStringSubmatch(line); funcMatch != nil {
+			query.GroupBy = append(query.GroupBy, funcMatch[1])
+		} else if simpleMatch := groupBySimpleRe.FindStringSubmatch(line); simpleMatch != nil {
+			if simpleMatch[1] != "function" {
+				query.GroupBy = append(query.GroupBy, simpleMatch[1])
+			}
 		}
 	}
 
@@ -209,6 +319,151 @@ func parseQueryContent(queryContent string) *Query {
 	return query
 }
 
+// Summary describes the query's filters, grouping and sorting on one line
+func (q *Query) Summary() string {
+	var filters []string
+	if q.NotDone {
+		filters = append(filters, "not done")
+	}
+	if q.DoneOnly {
+		filters = append(filters, "done")
+	}
+	for _, df := range q.DateFilters {
+		filters = append(filters, df.String())
+	}
+	for _, h := range q.HeadingIncludes {
+		filters = append(filters, "heading includes "+h)
+	}
+	for _, h := range q.HeadingExcludes {
+		filters = append(filters, "heading does not include "+h)
+	}
+
+	parts := []string{"Filters: none"}
+	if len(filters) > 0 {
+		parts[0] = "Filters: " + strings.Join(filters, ", ")
+	}
+	if len(q.GroupBy) > 0 {
+		parts = append(parts, "group by "+strings.Join(q.GroupBy, ", "))
+	}
+	if q.SortBy != "" {
+		parts = append(parts, "sort by "+q.SortBy)
+	}
+
+	return strings.Join(parts, " • ")
+}
+
+// Validate reports filters that are repeated or can never match together,
+// such as "due after 2025-02-01" with "due before 2025-01-01"
+func (q *Query) Validate() []string {
+	var warnings []string
+
+	if q.NotDone && q.DoneOnly {
+		warnings = append(warnings, `"done" and "not done" can never both match`)
+	}
+
+	seen := make(map[string]bool)
+	for _, df := range q.DateFilters {
+		if text := df.String(); seen[text] {
+			warnings = append(warnings, fmt.Sprintf("duplicate filter %q", text))
+		} else {
+			seen[text] = true
+		}
+	}
+
+	// Compare the tightest bounds per field; "on a or b" filters are skipped
+	type bounds struct{ after, before, on *DateFilter }
+	fields := NewOrderedMap[string, *bounds]()
+	for i := range q.DateFilters {
+		df := &q.DateFilters[i]
+		if len(df.Dates) > 0 {
+			continue
+		}
+
+		b, ok := fields.Get(df.Field)
+		if !ok {
+			b = &bounds{}
+			fields.Set(df.Field, b)
+		}
+
+		date := resolveDate(df.Date)
+		switch df.Operator {
+		case "after":
+			if b.after == nil || date.After(resolveDate(b.after.Date)) {
+				b.after = df
+			}
+		case "before":
+			if b.before == nil || date.Before(resolveDate(b.before.Date)) {
+				b.before = df
+			}
+		case "on":
+			if b.on != nil && !date.Equal(resolveDate(b.on.Date)) {
+				warnings = append(warnings, conflictWarning(*b.on, *df))
+			}
+			b.on = df
+		}
+	}
+
+	fields.Each(func(_ string, b *bounds) bool {
+		// after X and before Y only leave the days strictly between
+		if b.after != nil && b.before != nil && !resolveDate(b.after.Date).AddDate(0, 0, 1).Before(resolveDate(b.before.Date)) {
+			warnings = append(warnings, conflictWarning(*b.after, *b.before))
+		}
+		if b.on == nil {
+			return true
+		}
+		on := resolveDate(b.on.Date)
+		if b.after != nil && !on.After(resolveDate(b.after.Date)) {
+			warnings = append(warnings, conflictWarning(*b.on, *b.after))
+		}
+		if b.before != nil && !on.Before(resolveDate(b.before.Date)) {
+			warnings = append(warnings, conflictWarning(*b.on, *b.before))
+		}
+		return true
+	})
+
+	return warnings
+}
+
+// String formats the filter the way it is written in a query
+func (df DateFilter) String() string {
+	switch {
+	case len(df.Dates) > 0:
+		return df.Field + " " + strings.Join(df.Dates, " or ")
+	case df.Operator == "on":
+		return df.Field + " " + df.Date
+	default:
+		return df.Field + " " + df.Operator + " " + df.Date
+	}
+}
+
+// conflictWarning describes two filters that select no dates together
+func conflictWarning(a, b DateFilter) string {
+	return fmt.Sprintf("%q and %q can never both match", a.String(), b.String())
+}
+
+// queriesWarning is the first Validate warning of queries, prefixed with its
+// section name, or "" when every query is sound
+func queriesWarning(queries []*Query) string {
+	var warnings []string
+	for _, query := range queries {
+		for _, w := range query.Validate() {
+			if query.Name != "" {
+				w = query.Name + ": " + w
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	switch len(warnings) {
+	case 0:
+		return ""
+	case 1:
+		return "query: " + warnings[0]
+	default:
+		return fmt.Sprintf("query: %s (+%d more)", warnings[0], len(warnings)-1)
+	}
+}
+
 func splitOrDates(value string) []string {
 	parts := strings.Split(value, " or ")
 
@@ -255,6 +510,10 @@ func matchDateFilter(task *Task, filter DateFilter) bool {
 	switch filter.Field {
 	case "due":
 		taskDate = task.DueDate
+	case "scheduled":
+		taskDate = task.ScheduledDate
+	case "starts":
+		taskDate = task.StartDate
 	default:
 		return true
 	}
@@ -312,20 +571,60 @@ func matchAllDateFilters(task *Task, filters []DateFilter) bool {
 	return true
 }
 
+// matchQueryDates checks a task against every date filter of the query and
+// at least one of its alternatives
+func matchQueryDates(task *Task, query *Query) bool {
+	if len(query.DateFilters) > 0 && !matchAllDateFilters(task, query.DateFilters) {
+		return false
+	}
+	if len(query.AnyDateFilters) == 0 {
+		return true
+	}
+	for _, filter := range query.AnyDateFilters {
+		if matchDateFilter(task, filter) {
+			return true
+		}
+	}
+	return false
+}
+
 // filterTasks applies a query's filters to a task list
 func filterTasks(allTasks []*Task, query *Query) []*Task {
 	return Filter(allTasks, func(task *Task) bool {
 		if query.NotDone && task.Done {
 			return false
 		}
-		if len(query.DateFilters) > 0 && !matchAllDateFilters(task, query.DateFilters) {
+		if query.DoneOnly && !task.Done {
 			return false
 		}
-		return true
+		if !matchQueryDates(task, query) {
+			return false
+		}
+		return matchHeadingFilters(task, query)
 	})
 }
 
-// sortTasks sorts tasks by the specified field (stable sort preserves original order for equal elements)
+// matchHeadingFilters checks the task's heading against the query's
+// "heading includes" and "heading does not include" lines
+func matchHeadingFilters(task *Task, query *Query) bool {
+	heading := strings.ToLower(task.Heading)
+
+	for _, text := range query.HeadingIncludes {
+		if !strings.Contains(heading, strings.ToLower(text)) {
+			return false
+		}
+	}
+	for _, text := range query.HeadingExcludes {
+		if strings.Contains(heading, strings.ToLower(text)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortTasks sorts tasks by the specified field (stable sort preserves original order for equal elements).
+// A " reverse" suffix sorts descending; tasks missing a date stay at the end either way.
 func sortTasks(tasks []*Task, sortBy string) []*Task {
 	if sortBy == "" {
 		return tasks
@@ -335,28 +634,148 @@ func sortTasks(tasks []*Task, sortBy string) []*Task {
 	sorted := make([]*Task, len(tasks))
 	copy(sorted, tasks)
 
-	switch sortBy {
+	field, reverse := strings.CutSuffix(sortBy, " reverse")
+	dir := 1
+	if reverse {
+		dir = -1
+	}
+
+	switch field {
 	case "priority":
 		slices.SortStableFunc(sorted, func(a, b *Task) int {
-			return cmp.Compare(a.Priority, b.Priority)
+			return dir * cmp.Compare(a.Priority, b.Priority)
 		})
 	case "due":
 		slices.SortStableFunc(sorted, func(a, b *Task) int {
-			// Tasks without due dates go to the end
-			if a.DueDate == nil && b.DueDate == nil {
+			return compareDates(a.DueDate, b.DueDate, dir)
+		})
+	case "created", "age":
+		// Oldest first for age, the same as by created date
+		slices.SortStableFunc(sorted, func(a, b *Task) int {
+			return compareDates(a.CreatedDate, b.CreatedDate, dir)
+		})
+	case "scheduled":
+		slices.SortStableFunc(sorted, func(a, b *Task) int {
+			return compareDates(a.ScheduledDate, b.ScheduledDate, dir)
+		})
+	case "description":
+		slices.SortStableFunc(sorted, func(a, b *Task) int {
+			return dir * cmp.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+		})
+	case "modified":
+		// Most recently modified notes first
+		slices.SortStableFunc(sorted, func(a, b *Task) int {
+			return dir * b.FileModTime.Compare(a.FileModTime)
+		})
+	}
+
+	return sorted
+}
+
+// compareDates orders two optional dates in direction dir, with missing
+// dates always sorting to the end
+func compareDates(a, b *time.Time, dir int) int {
+	if a == nil && b == nil {
+		return 0
+	}
+	if a == nil {
+		return 1
+	}
+	if b == nil {
+		return -1
+	}
+	return dir * a.Compare(*b)
+}
+
+// countUniqueTasks counts tasks by identity, so a task listed under several
+// sections is counted once
+func countUniqueTasks(tasks []*Task) int {
+	seen := make(map[*Task]bool, len(tasks))
+	for _, task := range tasks {
+		seen[task] = true
+	}
+	return len(seen)
+}
+
+// reverseGroups reverses the task order within each group, leaving the
+// groups themselves in place
+func reverseGroups(groups []TaskGroup) []TaskGroup {
+	reversed := make([]TaskGroup, len(groups))
+	for i, group := range groups {
+		tasks := slices.Clone(group.Tasks)
+		slices.Reverse(tasks)
+		group.Tasks = tasks
+		reversed[i] = group
+	}
+	return reversed
+}
+
+// withProfileDefaults fills in a profile's group and sort for a query that
+// has none of its own, returning a copy when anything changes
+func withProfileDefaults(query *Query, group, sort string) *Query {
+	setGroup := group != "" && len(query.GroupBy) == 0
+	setSort := sort != "" && query.SortBy == ""
+	if !setGroup && !setSort {
+		return query
+	}
+
+	q := *query
+	if setGroup {
+		q.GroupBy = []string{group}
+	}
+	if setSort {
+		q.SortBy = sort
+	}
+	return &q
+}
+
+// completedLast moves done tasks after the open ones in each group, keeping
+// the sorted order within both
+func completedLast(groups []TaskGroup) []TaskGroup {
+	result := make([]TaskGroup, len(groups))
+	for i, group := range groups {
+		tasks := slices.Clone(group.Tasks)
+		slices.SortStableFunc(tasks, func(a, b *Task) int {
+			switch {
+			case a.Done == b.Done:
 				return 0
-			}
-			if a.DueDate == nil {
+			case a.Done:
 				return 1
-			}
-			if b.DueDate == nil {
+			default:
 				return -1
 			}
-			return a.DueDate.Compare(*b.DueDate)
 		})
+		group.Tasks = tasks
+		result[i] = group
+	}
+	return result
+}
+
+// limitGroups keeps the first limit tasks across groups, dropping groups
+// left empty, and reports how many tasks were cut
+func limitGroups(groups []TaskGroup, limit int) ([]TaskGroup, int) {
+	if limit <= 0 {
+		return groups, 0
 	}
 
-	return sorted
+	var limited []TaskGroup
+	hidden := 0
+	remaining := limit
+
+	for _, group := range groups {
+		if remaining == 0 {
+			hidden += len(group.Tasks)
+			continue
+		}
+		if len(group.Tasks) > remaining {
+			hidden += len(group.Tasks) - remaining
+			group.Tasks = group.Tasks[:remaining]
+		}
+		remaining -= len(group.Tasks)
+		limited = append(limited, group)
+	}
+
+	return limited, hidden
 }
 
 // groupTasks groups tasks by the specified field and optionally sorts within each group
@@ -366,19 +785,29 @@ func groupTasks(tasks []*Task, groupBy string, sortBy string, vaultPath string)
 	}
 
 	groups := NewOrderedMap[string, []*Task]()
+	now := time.Now()
+	field, depth := splitGroupDepth(groupBy)
 
 	for _, task := range tasks {
 		var key string
 		rel := relPath(vaultPath, task.FilePath)
 
-		switch groupBy {
+		switch field {
 		case "folder":
-			key = filepath.Dir(rel)
-			if key == "." {
-				key = "/"
-			}
+			key = folderKey(rel, depth)
 		case "filename":
 			key = filepath.Base(task.FilePath)
+		case "title":
+			key = task.NoteTitle
+			if key == "" {
+				key = filepath.Base(task.FilePath)
+			}
+		case "due":
+			key = dueBucket(task.DueDate)
+		case "modified":
+			key = modifiedBucket(task.FileModTime, now)
+		case "priority":
+			key = priorityGroup(task.Priority)
 		default:
 			key = ""
 		}
@@ -389,18 +818,201 @@ func groupTasks(tasks []*Task, groupBy string, sortBy string, vaultPath string)
 
 	result := make([]TaskGroup, 0, len(groups.Keys()))
 
-	for _, name := range groups.Keys() {
-		groupTasks, _ := groups.Get(name)
+	groups.Each(func(name string, groupTasks []*Task) bool {
 		// Sort within each group
 		result = append(result, TaskGroup{
 			Name:  name,
 			Tasks: sortTasks(groupTasks, sortBy),
 		})
+		return true
+	})
+
+	switch groupBy {
+	case "due":
+		slices.SortStableFunc(result, func(a, b TaskGroup) int {
+			return slices.Index(dueBuckets, a.Name) - slices.Index(dueBuckets, b.Name)
+		})
+	case "modified":
+		slices.SortStableFunc(result, func(a, b TaskGroup) int {
+			return slices.Index(modifiedBuckets, a.Name) - slices.Index(modifiedBuckets, b.Name)
+		})
+	case "priority":
+		slices.SortStableFunc(result, func(a, b TaskGroup) int {
+			return slices.Index(priorityGroups, a.Name) - slices.Index(priorityGroups, b.Name)
+		})
+	}
+
+	return result
+}
+
+// groupTasksBy groups tasks by each key in turn, nesting the groups of a key
+// inside those of the previous one
+func groupTasksBy(tasks []*Task, groupBy []string, sortBy string, vaultPath string) []TaskGroup {
+	if len(groupBy) <= 1 {
+		return groupTasks(tasks, strings.Join(groupBy, ""), sortBy, vaultPath)
 	}
 
+	var result []TaskGroup
+	for _, outer := range groupTasks(tasks, groupBy[0], "", vaultPath) {
+		for _, inner := range groupTasksBy(outer.Tasks, groupBy[1:], sortBy, vaultPath) {
+			inner.Parents = append([]string{outer.Name}, inner.Parents...)
+			result = append(result, inner)
+		}
+	}
 	return result
 }
 
+// groupHeader is a group title printed above a group's tasks
+type groupHeader struct {
+	Name  string
+	Level int // 0 for the outermost group by
+	Count int
+}
+
+// groupHeaders returns the headers printed before groups[i]: the enclosing
+// groups not already opened by the previous non-empty group, then its own
+func groupHeaders(groups []TaskGroup, i int) []groupHeader {
+	group := groups[i]
+
+	var previous []string
+	for j := i - 1; j >= 0; j-- {
+		if len(groups[j].Tasks) > 0 {
+			previous = append(slices.Clone(groups[j].Parents), groups[j].Name)
+			break
+		}
+	}
+
+	var headers []groupHeader
+	path := append(slices.Clone(group.Parents), group.Name)
+	opened := true
+	for level, name := range path {
+		opened = opened && level < len(previous) && previous[level] == name
+		if opened || name == "" {
+			continue
+		}
+
+		count := 0
+		for _, other := range groups[i:] {
+			otherPath := append(slices.Clone(other.Parents), other.Name)
+			if len(otherPath) > level && slices.Equal(otherPath[:level+1], path[:level+1]) {
+				count += len(other.Tasks)
+			}
+		}
+		headers = append(headers, groupHeader{Name: name, Level: level, Count: count})
+	}
+	return headers
+}
+
+// groupDepth is how many group headers a group's tasks are nested under
+func groupDepth(group TaskGroup) int {
+	depth := len(group.Parents)
+	if group.Name != "" {
+		depth++
+	}
+	return depth
+}
+
+// splitGroupDepth splits "folder 2" into the field and its depth, 0 when the
+// group by has no number
+func splitGroupDepth(groupBy string) (string, int) {
+	field, number, ok := strings.Cut(groupBy, " ")
+	if !ok {
+		return groupBy, 0
+	}
+	depth, _ := strconv.Atoi(number)
+	return field, depth
+}
+
+// folderKey is the "group by folder" key of a vault-relative path: its
+// directory, cut to the first depth levels when depth is positive
+func folderKey(rel string, depth int) string {
+	dir := filepath.Dir(rel)
+	if dir == "." {
+		return "/"
+	}
+
+	if depth > 0 {
+		// Files outside the vault keep their root
+		root, rest := "", filepath.ToSlash(dir)
+		if trimmed, ok := strings.CutPrefix(rest, "/"); ok {
+			root, rest = "/", trimmed
+		}
+		if parts := strings.Split(rest, "/"); len(parts) > depth {
+			dir = filepath.FromSlash(root + strings.Join(parts[:depth], "/"))
+		}
+	}
+
+	return dir
+}
+
+// dueBuckets are the "group by due" groups, in display order
+var dueBuckets = []string{"Overdue", "Today", "Tomorrow", "Upcoming", "No due date"}
+
+// dueBucket names the "group by due" group of a due date
+func dueBucket(due *time.Time) string {
+	if due == nil {
+		return "No due date"
+	}
+
+	today := resolveDate("today")
+	switch date := startOfDay(*due); {
+	case date.Before(today):
+		return "Overdue"
+	case date.Equal(today):
+		return "Today"
+	case date.Equal(today.AddDate(0, 0, 1)):
+		return "Tomorrow"
+	default:
+		return "Upcoming"
+	}
+}
+
+// priorityGroups are the "group by priority" groups, in display order
+var priorityGroups = []string{"Highest", "High", "Medium", "Normal", "Low", "Lowest"}
+
+// priorityGroup names the "group by priority" group of a priority
+func priorityGroup(priority int) string {
+	if priority < PriorityHighest || priority > PriorityLowest {
+		priority = PriorityNormal
+	}
+	return priorityGroups[priority-PriorityHighest]
+}
+
+// modifiedBuckets are the "group by modified" groups, in display order
+var modifiedBuckets = []string{"Today", "This week", "Older"}
+
+// modifiedBucket names the "group by modified" group of a file's mtime:
+// today, within the last 7 days, or older
+func modifiedBucket(modTime, now time.Time) string {
+	// mtimes are instants, so compare against local midnight
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case modTime.IsZero():
+		return "Older"
+	case !modTime.Before(today):
+		return "Today"
+	case !modTime.Before(today.AddDate(0, 0, -6)):
+		return "This week"
+	default:
+		return "Older"
+	}
+}
+
+// todayQuery is the built-in "Today" view: open tasks due or scheduled
+// today or earlier, grouped by due bucket
+func todayQuery() *Query {
+	return &Query{
+		Name:    "Today",
+		NotDone: true,
+		AnyDateFilters: []DateFilter{
+			{Field: "due", Operator: "before", Date: "tomorrow"},
+			{Field: "scheduled", Operator: "before", Date: "tomorrow"},
+		},
+		GroupBy: []string{"due"},
+		SortBy:  "priority",
+	}
+}
+
 // relPath returns the relative path from basePath
 func relPath(basePath, filePath string) string {
 	if rel, err := filepath.Rel(basePath, filePath); err == nil {
@@ -409,33 +1021,95 @@ func relPath(basePath, filePath string) string {
 	return filePath
 }
 
+// displayPath is the path shown for filePath in task lines and --list:
+// relative to basePath, or with absolute (config "show_absolute_paths") the
+// absolute path
+func displayPath(basePath, filePath string, absolute bool) string {
+	if !absolute {
+		return relPath(basePath, filePath)
+	}
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filePath
+}
+
 // resolveQuery determines if input is a file path or inline query string
-// and returns parsed queries accordingly
-func resolveQuery(input string, vaultPath string) ([]*Query, error) {
+// and returns parsed queries accordingly, reading blocks fenced with label
+func resolveQuery(input string, vaultPath string, label string) ([]*Query, error) {
 	// Try to resolve as file path first
+	if filePath, ok := queryFilePath(input, vaultPath); ok {
+		return parseAllQueryBlocks(filePath, label)
+	}
+
+	// Not a file - treat as inline query
+	return parseInlineQuery(input)
+}
+
+// queryFilePath resolves input to an existing file, absolute or relative to
+// the vault
+func queryFilePath(input string, vaultPath string) (string, bool) {
 	expanded, err := expandPath(input)
 	if err != nil {
-		// If expansion fails, treat as inline query
-		return parseInlineQuery(input)
+		return "", false
 	}
 
-	// Determine file path (absolute or relative to vault)
-	var filePath string
-	if filepath.IsAbs(expanded) {
-		filePath = expanded
-	} else if vaultPath != "" {
+	filePath := expanded
+	if !filepath.IsAbs(expanded) && vaultPath != "" {
 		filePath = filepath.Join(vaultPath, expanded)
-	} else {
-		filePath = expanded
 	}
 
-	// Check if file exists and is not a directory
 	if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
-		return parseAllQueryBlocks(filePath)
+		return filePath, true
 	}
+	return "", false
+}
 
-	// Not a file - treat as inline query
-	return parseInlineQuery(input)
+// queryFileArgs resolves query file arguments, each of which may be a comma
+// separated list
+func queryFileArgs(args []string, vaultPath string) ([]string, error) {
+	var files []string
+
+	for _, arg := range args {
+		for _, input := range strings.Split(arg, ",") {
+			input = strings.TrimSpace(input)
+			if input == "" {
+				continue
+			}
+			filePath, ok := queryFilePath(input, vaultPath)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotExist, input)
+			}
+			files = append(files, filePath)
+		}
+	}
+
+	return files, nil
+}
+
+// parseQueryFiles parses the blocks fenced with label of every query file in
+// order. Files without a query block are skipped and reported as warnings.
+func parseQueryFiles(files []string, label string) ([]*Query, []ParseWarning, error) {
+	var queries []*Query
+	var warnings []ParseWarning
+
+	for _, file := range files {
+		blocks, err := parseAllQueryBlocks(file, label)
+		if errors.Is(err, ErrNoQueryBlock) {
+			warnings = append(warnings, ParseWarning{File: file, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, warnings, err
+		}
+		queries = append(queries, blocks...)
+	}
+
+	if len(queries) == 0 {
+		return nil, warnings, fmt.Errorf("%w in %s", ErrNoQueryBlock, strings.Join(files, ", "))
+	}
+
+	return queries, warnings, nil
 }
 
 // parseInlineQuery parses an inline query string like "not done" or "due today"
@@ -444,6 +1118,30 @@ func parseInlineQuery(queryStr string) ([]*Query, error) {
 	return []*Query{query}, nil
 }
 
+// selectQueries picks the queries for a run: an explicit query string (flag
+// or inline profile query) first, then a profile's query file, then the
+// config's default_query. Query files are read for blocks fenced with label.
+func selectQueries(queryStr, queryFile, vaultPath, defaultQuery, label string) ([]*Query, error) {
+	if queryStr != "" {
+		return resolveQuery(queryStr, vaultPath, label)
+	}
+	if queryFile != "" {
+		return parseAllQueryBlocks(queryFile, label)
+	}
+	return defaultQueries(defaultQuery), nil
+}
+
+// defaultQueries parses default_query, falling back to "not done" tasks
+// sorted by priority when it isn't set
+func defaultQueries(defaultQuery string) []*Query {
+	if strings.TrimSpace(defaultQuery) == "" {
+		return []*Query{{NotDone: true, SortBy: "priority"}}
+	}
+
+	queries, _ := parseInlineQuery(defaultQuery)
+	return queries
+}
+
 // Filter returns elements from slice that satisfy the predicate
 func Filter[T any](slice []T, predicate func(T) bool) []T {
 	var result []T
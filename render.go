@@ -1,16 +1,93 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 const defaultTheme = "dracula"
 
+// wrapIndent is the hanging indent of wrapped continuation lines, past the
+// line's own prefix
+const wrapIndent = 2
+
 var glamourRenderer *glamour.TermRenderer
 
+// maxRenderedLines bounds renderedLines; it's emptied when full
+const maxRenderedLines = 4096
+
+// renderedLines caches Glamour's output per checkbox line, since rendering
+// is slow and the same lines are drawn on every rebuild. It's reset with the
+// renderer.
+var (
+	renderedMu    sync.Mutex
+	renderedLines = make(map[string]string)
+)
+
+// Glamour's checkboxes, swapped for checkbox_todo/checkbox_done when set
+const (
+	glamourTodo = "[ ]"
+	glamourDone = "[✓]"
+)
+
+// checkboxGlyphs are the display glyphs from the config (checkbox_todo,
+// checkbox_done), empty for Glamour's own; the files on disk keep "[ ]" and
+// "[x]"
+type checkboxGlyphs struct {
+	todo string
+	done string
+}
+
+// ErrCheckboxWidth is returned when the checkbox glyphs would misalign lines
+var ErrCheckboxWidth = errors.New("checkbox_todo and checkbox_done have different widths")
+
+// checkCheckboxGlyphs checks that both glyphs, or the default for an unset
+// one, take the same number of cells
+func checkCheckboxGlyphs(todo, done string) error {
+	if todo == "" {
+		todo = glamourTodo
+	}
+	if done == "" {
+		done = glamourDone
+	}
+
+	if tw, dw := lipgloss.Width(todo), lipgloss.Width(done); tw != dw {
+		return fmt.Errorf("%w: %q is %d, %q is %d", ErrCheckboxWidth, todo, tw, done, dw)
+	}
+	return nil
+}
+
+// apply swaps the first checkbox of a rendered task line for the configured
+// glyph. Without colors a done task falls back to "[x]".
+func (g checkboxGlyphs) apply(line string, done bool) string {
+	if done {
+		glyph := g.done
+		if glyph == "" && noColor {
+			glyph = "[x]"
+		}
+		if glyph == "" {
+			return line
+		}
+		if strings.Contains(line, glamourDone+" ") {
+			return strings.Replace(line, glamourDone+" ", glyph+" ", 1)
+		}
+		return strings.Replace(line, "[x] ", glyph+" ", 1)
+	}
+
+	if g.todo == "" {
+		return line
+	}
+	return strings.Replace(line, glamourTodo+" ", g.todo+" ", 1)
+}
+
 func init() {
 	initRenderer(defaultTheme)
 }
@@ -23,10 +100,15 @@ func initRenderer(theme string) {
 		glamour.WithStandardStyle(theme),
 		glamour.WithWordWrap(0),
 	)
+
+	renderedMu.Lock()
+	clear(renderedLines)
+	renderedMu.Unlock()
 }
 
-// renderTask renders a full task line with checkbox using Glamour
-func renderTask(done bool, description string) string {
+// renderCheckboxLine renders the checkbox and description using Glamour,
+// drawing the checkbox with glyphs
+func renderCheckboxLine(done bool, description string, glyphs checkboxGlyphs) string {
 	checkbox := "- [ ]"
 	if done {
 		checkbox = "- [x]"
@@ -35,15 +117,154 @@ func renderTask(done bool, description string) string {
 	taskLine := fmt.Sprintf("%s %s", checkbox, description)
 
 	if glamourRenderer == nil {
-		return taskLine
+		return glyphs.apply(taskLine, done)
+	}
+
+	renderedMu.Lock()
+	defer renderedMu.Unlock()
+
+	if rendered, ok := renderedLines[taskLine]; ok {
+		return glyphs.apply(rendered, done)
 	}
 
 	rendered, err := glamourRenderer.Render(taskLine)
 	if err != nil {
-		return taskLine
+		return glyphs.apply(taskLine, done)
 	}
 
 	// Keep as single line
 	rendered = strings.TrimSpace(rendered)
-	return rendered
+	if len(renderedLines) >= maxRenderedLines {
+		clear(renderedLines)
+	}
+	renderedLines[taskLine] = rendered
+	return glyphs.apply(rendered, done)
+}
+
+// renderMarkdown renders a markdown snippet with Glamour, falling back to the
+// raw text
+func renderMarkdown(markdown string) string {
+	if glamourRenderer == nil {
+		return markdown
+	}
+
+	rendered, err := glamourRenderer.Render(markdown)
+	if err != nil {
+		return markdown
+	}
+	return strings.Trim(rendered, "\n")
+}
+
+// renderContext describes where and how a task row is drawn
+type renderContext struct {
+	prefix   string // Indent, cursor and search markers before the checkbox
+	location string // File suffix such as "notes/a.md:3", empty to omit it
+	selected bool
+	short    bool // Fit to width and drop the location
+	wrap     bool // Soft-wrap instead of truncating
+	width    int
+	scroll   int // Cells scrolled off the left of the selected row
+	now      time.Time
+	hide     map[string]bool // Query "hide" fields
+	glyphs   checkboxGlyphs
+}
+
+// tagRe matches #tags in a description
+var tagRe = regexp.MustCompile(`(^|\s)#[\p{L}\p{N}_/-]+`)
+
+// hideMetadata removes the markers of hidden fields from a description whose
+// dates are written in format
+func hideMetadata(description string, format *taskFormat, hide map[string]bool) string {
+	if len(hide) == 0 {
+		return description
+	}
+	if hide["due date"] {
+		description = format.dueDateRe.ReplaceAllString(description, "")
+	}
+	if hide["priority"] {
+		description = priorityRe.ReplaceAllString(description, "")
+	}
+	if hide["tags"] {
+		description = tagRe.ReplaceAllString(description, "$1")
+	}
+	return strings.Join(strings.Fields(description), " ")
+}
+
+// renderTask renders a full task row: prefix, checkbox, description, date
+// badges and location, fitted to the context's width
+func renderTask(task *Task, ctx renderContext) string {
+	prefixWidth := lipgloss.Width(ctx.prefix)
+	line := renderCheckboxLine(task.Done, hideMetadata(task.Description, task.lineFormat(), ctx.hide), ctx.glyphs) + dateBadges(task, ctx.now)
+
+	fileInfo := ""
+	if ctx.short && ctx.width > 0 {
+		line = truncateToWidth(line, max(1, ctx.width-prefixWidth))
+	} else if ctx.location != "" && !ctx.hide["backlink"] {
+		fileInfo = fileStyle.Render(" (" + ctx.location + ")")
+	}
+
+	if ctx.selected {
+		line = selectedStyle.Render(line)
+	}
+
+	if ctx.wrap && !ctx.short {
+		return ctx.prefix + wrapToWidth(line+fileInfo, ctx.width-prefixWidth, prefixWidth+wrapIndent)
+	}
+
+	row := line + fileInfo
+	if ctx.selected {
+		row = scrollLeft(row, ctx.scroll)
+	}
+	return truncateToWidth(ctx.prefix+row, ctx.width)
+}
+
+// dateBadges renders compact relative badges for a task's planning dates
+// and the age of open tasks, only for the dates it has
+func dateBadges(task *Task, now time.Time) string {
+	var b strings.Builder
+	if task.ScheduledDate != nil {
+		b.WriteString(scheduledBadgeStyle.Render(" ⏳ " + humanizeDate(*task.ScheduledDate, now)))
+	}
+	if task.StartDate != nil {
+		b.WriteString(startBadgeStyle.Render(" 🛫 " + humanizeDate(*task.StartDate, now)))
+	}
+	if age := taskAge(task, now); age != "" {
+		b.WriteString(ageBadgeStyle.Render(" " + age))
+	}
+	return b.String()
+}
+
+// truncateToWidth cuts a (possibly styled) line to fit width cells, ending it
+// with an ellipsis when anything was dropped. A width of 0 or less, as before
+// the terminal size is known, means no limit.
+func truncateToWidth(line string, width int) string {
+	if width <= 0 {
+		return line
+	}
+	return ansi.Truncate(line, width, "…")
+}
+
+// scrollLeft drops the first offset cells of a (possibly styled) line, marking
+// the cut with an ellipsis. The last cell is always kept.
+func scrollLeft(line string, offset int) string {
+	if offset <= 0 {
+		return line
+	}
+	offset = min(offset, lipgloss.Width(line)-2)
+	if offset <= 0 {
+		return line
+	}
+	return ansi.TruncateLeft(line, offset+1, "…")
+}
+
+// wrapToWidth soft-wraps a (possibly styled) line that has width cells to
+// fit in, joining the rows with a hanging indent of indent cells
+func wrapToWidth(line string, width, indent int) string {
+	textWidth := width - wrapIndent
+	if textWidth < 1 || lipgloss.Width(line) <= width {
+		return line
+	}
+
+	wrapped := lipgloss.NewStyle().Width(textWidth).Render(line)
+	return strings.ReplaceAll(wrapped, "\n", "\n"+strings.Repeat(" ", indent))
 }
@@ -1,6 +1,9 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 // Theme defines the color scheme for the application
 type Theme struct {
@@ -34,169 +37,276 @@ var theme = Theme{
 	Overlay:   lipgloss.Color("#252526"), // Sidebar background
 }
 
+// colorblindTheme uses the Okabe-Ito palette, which stays distinguishable
+// with the common kinds of color blindness
+var colorblindTheme = Theme{
+	Primary:   lipgloss.Color("#0072b2"), // Blue
+	Accent:    lipgloss.Color("#56b4e9"), // Sky blue
+	Highlight: lipgloss.Color("#f0e442"), // Yellow
+	Success:   lipgloss.Color("#009e73"), // Bluish green
+	Warning:   lipgloss.Color("#e69f00"), // Orange
+	Danger:    lipgloss.Color("#d55e00"), // Vermillion
+	Text:      theme.Text,
+	Muted:     theme.Muted,
+	Subtle:    theme.Subtle,
+	Dim:       theme.Dim,
+	Surface:   theme.Surface,
+	Overlay:   theme.Overlay,
+}
+
+// palettes are the color schemes accepted by the palette option
+var palettes = map[string]Theme{
+	"default":    theme,
+	"colorblind": colorblindTheme,
+}
+
+// noColor strips colors and styling from the UI (--no-color, NO_COLOR)
+var noColor bool
+
+// noColorRequested reports whether --no-color, NO_COLOR or CLICOLOR=0 ask
+// for plain output
+func noColorRequested(flagSet bool, getenv func(string) string) bool {
+	return flagSet || getenv("NO_COLOR") != "" || getenv("CLICOLOR") == "0"
+}
+
+// setNoColor switches to unstyled rendering: no colors, a plain Glamour
+// style and "[x]" for done tasks
+func setNoColor() {
+	noColor = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+	initRenderer("notty")
+}
+
 var (
+	titleStyle            lipgloss.Style
+	titleNameStyle        lipgloss.Style
+	searchModeStyle       lipgloss.Style
+	resultsModeStyle      lipgloss.Style
+	aboutStyle            lipgloss.Style
+	aboutBoxStyle         lipgloss.Style
+	selectedStyle         lipgloss.Style
+	doneStyle             lipgloss.Style
+	fileStyle             lipgloss.Style
+	helpStyle             lipgloss.Style
+	cursorStyle           lipgloss.Style
+	groupStyle            lipgloss.Style
+	sectionStyle          lipgloss.Style
+	countStyle            lipgloss.Style
+	searchStyle           lipgloss.Style
+	matchStyle            lipgloss.Style
+	searchInputStyle      lipgloss.Style
+	confirmStyle          lipgloss.Style
+	cancelStyle           lipgloss.Style
+	dangerStyle           lipgloss.Style
+	activeTabStyle        lipgloss.Style
+	inactiveTabStyle      lipgloss.Style
+	tabSeparatorStyle     lipgloss.Style
+	helpBarStyle          lipgloss.Style
+	headerBarStyle        lipgloss.Style
+	helpBarKeyStyle       lipgloss.Style
+	helpBarDescStyle      lipgloss.Style
+	helpBarSeparatorStyle lipgloss.Style
+	helpBarInfoStyle      lipgloss.Style
+	helpDialogKeyStyle    lipgloss.Style
+	helpDialogDescStyle   lipgloss.Style
+	helpDialogHeaderStyle lipgloss.Style
+	dimTextStyle          lipgloss.Style
+	warningStyle          lipgloss.Style
+	scheduledBadgeStyle   lipgloss.Style
+	startBadgeStyle       lipgloss.Style
+	ageBadgeStyle         lipgloss.Style
+	buttonDangerStyle     lipgloss.Style
+	buttonNeutralStyle    lipgloss.Style
+	dangerBoxStyle        lipgloss.Style
+	loaderTitleStyle      lipgloss.Style
+	loaderCountStyle      lipgloss.Style
+	barColor              lipgloss.Style
+)
+
+func init() {
+	applyTheme(theme)
+}
+
+// applyTheme makes t the active color scheme and rebuilds the styles from it
+func applyTheme(t Theme) {
+	theme = t
+
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Accent).
-			Background(theme.Surface)
+		Bold(true).
+		Foreground(theme.Accent).
+		Background(theme.Surface)
 
 	titleNameStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Primary).
-			Background(theme.Surface)
+		Bold(true).
+		Foreground(theme.Primary).
+		Background(theme.Surface)
 
 	searchModeStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Text).
-			Background(theme.Danger).
-			Padding(0, 1)
+		Bold(true).
+		Foreground(theme.Text).
+		Background(theme.Danger).
+		Padding(0, 1)
 
 	resultsModeStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Text).
-				Background(theme.Warning).
-				Padding(0, 1)
+		Bold(true).
+		Foreground(theme.Text).
+		Background(theme.Warning).
+		Padding(0, 1)
 
 	aboutStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Text)
+		Bold(true).
+		Foreground(theme.Text)
 
 	aboutBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(theme.Muted).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Muted).
+		Padding(1, 2)
 
 	selectedStyle = lipgloss.NewStyle().
-			Foreground(theme.Highlight).
-			Bold(true)
+		Foreground(theme.Highlight).
+		Bold(true)
 
 	doneStyle = lipgloss.NewStyle().
-			Foreground(theme.Muted).
-			Strikethrough(true)
+		Foreground(theme.Muted).
+		Strikethrough(true)
 
 	fileStyle = lipgloss.NewStyle().
-			Foreground(theme.Subtle)
+		Foreground(theme.Subtle)
 
 	helpStyle = lipgloss.NewStyle().
-			Foreground(theme.Muted).
-			MarginTop(1)
+		Foreground(theme.Muted).
+		MarginTop(1)
 
 	cursorStyle = lipgloss.NewStyle().
-			Foreground(theme.Highlight)
+		Foreground(theme.Highlight)
 
 	groupStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Primary)
+		Bold(true).
+		Foreground(theme.Primary)
 
 	sectionStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Accent)
+		Bold(true).
+		Foreground(theme.Accent)
 
 	countStyle = lipgloss.NewStyle().
-			Foreground(theme.Subtle)
+		Foreground(theme.Subtle)
 
 	searchStyle = lipgloss.NewStyle().
-			Foreground(theme.Highlight).
-			Bold(true).
-			Background(theme.Surface)
+		Foreground(theme.Highlight).
+		Bold(true).
+		Background(theme.Surface)
 
 	matchStyle = lipgloss.NewStyle().
-			Foreground(theme.Warning).
-			Bold(true)
+		Foreground(theme.Warning).
+		Bold(true)
 
 	searchInputStyle = lipgloss.NewStyle().
-				Foreground(theme.Accent).
-				Background(theme.Surface)
+		Foreground(theme.Accent).
+		Background(theme.Surface)
 
 	confirmStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Success)
+		Bold(true).
+		Foreground(theme.Success)
 
 	cancelStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Danger)
+		Bold(true).
+		Foreground(theme.Danger)
 
 	dangerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(theme.Danger)
+		Bold(true).
+		Foreground(theme.Danger)
 
 	// Tab bar styles
 	activeTabStyle = lipgloss.NewStyle().
-			Foreground(theme.Primary).
-			Background(theme.Overlay).
-			Bold(true)
+		Foreground(theme.Primary).
+		Background(theme.Overlay).
+		Bold(true)
 
 	inactiveTabStyle = lipgloss.NewStyle().
-				Foreground(theme.Subtle).
-				Background(theme.Surface)
+		Foreground(theme.Subtle).
+		Background(theme.Surface)
 
 	tabSeparatorStyle = lipgloss.NewStyle().
-				Foreground(theme.Muted).
-				Background(theme.Surface)
+		Foreground(theme.Muted).
+		Background(theme.Surface)
 
 	// Help bar styles
 	helpBarStyle = lipgloss.NewStyle().
-			Foreground(theme.Subtle).
-			Background(theme.Surface)
+		Foreground(theme.Subtle).
+		Background(theme.Surface)
 
 	headerBarStyle = lipgloss.NewStyle().
-			Foreground(theme.Primary).
-			Background(theme.Surface)
+		Foreground(theme.Primary).
+		Background(theme.Surface)
 
 	helpBarKeyStyle = lipgloss.NewStyle().
-			Foreground(theme.Primary).
-			Bold(true)
+		Foreground(theme.Primary).
+		Bold(true)
 
 	helpBarDescStyle = lipgloss.NewStyle().
-				Foreground(theme.Subtle)
+		Foreground(theme.Subtle)
 
 	helpBarSeparatorStyle = lipgloss.NewStyle().
-				Foreground(theme.Muted)
+		Foreground(theme.Muted)
 
 	helpBarInfoStyle = lipgloss.NewStyle().
-				Foreground(theme.Muted)
+		Foreground(theme.Muted)
 
 	// Help dialog styles
 	helpDialogKeyStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Accent)
+		Bold(true).
+		Foreground(theme.Accent)
 
 	helpDialogDescStyle = lipgloss.NewStyle().
-				Foreground(theme.Subtle)
+		Foreground(theme.Subtle)
 
 	helpDialogHeaderStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Primary)
+		Bold(true).
+		Foreground(theme.Primary)
 
 	dimTextStyle = lipgloss.NewStyle().
-			Foreground(theme.Dim)
+		Foreground(theme.Dim)
+
+	warningStyle = lipgloss.NewStyle().
+		Foreground(theme.Warning)
+
+	// Date badges
+	scheduledBadgeStyle = lipgloss.NewStyle().
+		Foreground(theme.Accent)
+
+	startBadgeStyle = lipgloss.NewStyle().
+		Foreground(theme.Primary)
+
+	ageBadgeStyle = lipgloss.NewStyle().
+		Foreground(theme.Dim).
+		Italic(true)
 
 	// Button styles
 	buttonDangerStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Text).
-				Background(theme.Danger).
-				Padding(0, 2)
+		Bold(true).
+		Foreground(theme.Text).
+		Background(theme.Danger).
+		Padding(0, 2)
 
 	buttonNeutralStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Text).
-				Background(theme.Overlay).
-				Padding(0, 2)
+		Bold(true).
+		Foreground(theme.Text).
+		Background(theme.Overlay).
+		Padding(0, 2)
 
 	dangerBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(theme.Danger).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Danger).
+		Padding(1, 2)
 
 	// Loader styles
 	loaderTitleStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(theme.Accent)
+		Bold(true).
+		Foreground(theme.Accent)
 
 	loaderCountStyle = lipgloss.NewStyle().
-				Foreground(theme.Accent)
+		Foreground(theme.Accent)
 
 	// Utility
 	barColor = lipgloss.NewStyle().Background(theme.Surface)
-)
+}
@@ -2,25 +2,76 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// ErrTaskLineChanged is returned when a task's line can no longer be found in its file
+var ErrTaskLineChanged = errors.New("task line changed on disk")
+
+// ErrInvalidTaskRef is returned for task references not in file:line form
+var ErrInvalidTaskRef = errors.New("expected a file:line reference")
+
+// ErrNotTaskLine is returned when a file:line reference isn't a task
+var ErrNotTaskLine = errors.New("not a task line")
+
+// ErrReadOnly is returned when a task's file or its directory can't be written
+var ErrReadOnly = errors.New("file is read-only")
+
+// ErrNoFileManager is returned when there's no known file manager opener for the OS
+var ErrNoFileManager = errors.New("no file manager opener for this platform")
+
+// tabWidth is how many columns a leading tab counts for when nesting tasks
+const tabWidth = 4
+
 var (
 	checkboxRe = regexp.MustCompile(`^(\s*-\s*)\[([ xX])\](.*)$`)
-	doneRe     = regexp.MustCompile(`\s*✅\s*\d{4}-\d{2}-\d{2}`)
 	taskRe     = regexp.MustCompile(`^\s*-\s*\[([ xX])\]\s*(.*)$`)
-	dueDateRe  = regexp.MustCompile(`📅\s*(\d{4}-\d{2}-\d{2})`)
 	priorityRe = regexp.MustCompile(`[🔺⏫🔼🔽⏬]`)
+	listItemRe = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+	headingRe  = regexp.MustCompile(`^#{1,6}(\s|$)`)
 )
 
+// taskFormat holds the config settings for reading and writing task lines.
+// Tasks keep the format they were parsed with, so edits write their dates
+// back in the same layout.
+type taskFormat struct {
+	dateLayout  string // Layout new dates are written in (config "date_format")
+	doneRe      *regexp.Regexp
+	dueDateRe   *regexp.Regexp
+	doneDateRe  *regexp.Regexp
+	createdRe   *regexp.Regexp
+	scheduledRe *regexp.Regexp
+	startRe     *regexp.Regexp
+
+	uppercaseDone bool // Write done tasks as "[X]" instead of "[x]" (config "uppercase_done")
+}
+
+// defaultTaskFormat reads and writes dates in the Obsidian Tasks layout
+var defaultTaskFormat = newTaskFormat("")
+
+// orDefault returns f, or the default format when f is nil
+func (f *taskFormat) orDefault() *taskFormat {
+	if f == nil {
+		return defaultTaskFormat
+	}
+	return f
+}
+
 // Priority levels (lower value = higher priority)
 const (
 	PriorityHighest = iota + 1
@@ -40,6 +91,15 @@ var priorityEmojis = map[int]string{
 	PriorityLowest:  "⏬",
 }
 
+var priorityNames = map[int]string{
+	PriorityHighest: "highest",
+	PriorityHigh:    "high",
+	PriorityMedium:  "medium",
+	PriorityNormal:  "normal",
+	PriorityLow:     "low",
+	PriorityLowest:  "lowest",
+}
+
 var emojiToPriority = map[string]int{
 	"🔺": PriorityHighest,
 	"⏫": PriorityHigh,
@@ -50,14 +110,31 @@ var emojiToPriority = map[string]int{
 
 // Task represents a single task from a markdown file
 type Task struct {
-	FilePath    string
-	LineNumber  int
-	RawLine     string
-	Done        bool
-	Description string
-	Modified    bool
-	DueDate     *time.Time
-	Priority    int
+	FilePath        string
+	LineNumber      int
+	RawLine         string
+	OriginalRawLine string // Line content as last read from or written to disk
+	Done            bool
+	Description     string
+	Modified        bool
+	DueDate         *time.Time
+	DoneDate        *time.Time // Completion date, kept across edits
+	CreatedDate     *time.Time
+	ScheduledDate   *time.Time
+	StartDate       *time.Time
+	Priority        int
+	Indent          int         // Nesting depth below parent tasks; RawLine keeps the original whitespace
+	NoteTitle       string      // First "# " heading of the file, if any
+	Heading         string      // Nearest heading above the task, if any
+	Parent          *Task       // Nearest less indented task above, if any
+	FileModTime     time.Time   // Modification time of the file when parsed
+	Continuation    string      // Indented lines below the checkbox, appended to Description
+	Format          *taskFormat // How the line is read and written; nil is the default
+}
+
+// lineFormat returns the format the task is read and written in
+func (t *Task) lineFormat() *taskFormat {
+	return t.Format.orDefault()
 }
 
 // Toggle switches the task between done and not done
@@ -77,14 +154,36 @@ func (t *Task) updateRawLine() {
 	prefix := matches[1]
 	content := matches[3]
 
-	content = doneRe.ReplaceAllString(content, "")
-
 	if t.Done {
-		doneDate := time.Now().Format("2006-01-02")
-		t.RawLine = fmt.Sprintf("%s[x]%s ✅ %s", prefix, content, doneDate)
+		if t.DoneDate == nil {
+			now := time.Now()
+			t.DoneDate = &now
+			content = t.lineFormat().doneRe.ReplaceAllString(content, "")
+		}
+		t.RawLine = prefix + t.lineFormat().doneCheckbox(matches[2]) + t.withDoneDate(content)
 	} else {
-		t.RawLine = fmt.Sprintf("%s[ ]%s", prefix, content)
+		t.DoneDate = nil
+		t.RawLine = fmt.Sprintf("%s[ ]%s", prefix, t.lineFormat().doneRe.ReplaceAllString(content, ""))
+	}
+}
+
+// doneCheckbox is the checkbox written for a done task whose line has the
+// given status: "[X]" with uppercase_done, or when the line already used it
+func (f *taskFormat) doneCheckbox(status string) string {
+	if f.uppercaseDone || status == "X" {
+		return "[X]"
 	}
+	return "[x]"
+}
+
+// withDoneDate appends the task's done date to content unless it already
+// carries one, so an existing completion date is never rewritten
+func (t *Task) withDoneDate(content string) string {
+	format := t.lineFormat()
+	if t.DoneDate == nil || format.doneRe.MatchString(content) {
+		return content
+	}
+	return fmt.Sprintf("%s ✅ %s", content, format.formatDate(*t.DoneDate))
 }
 
 // rebuildRawLine rebuilds the raw line with a new description
@@ -96,27 +195,171 @@ func (t *Task) rebuildRawLine() {
 
 	prefix := matches[1]
 	checkbox := "[ ]"
+	description := t.lineDescription()
 	if t.Done {
-		checkbox = "[x]"
+		checkbox = t.lineFormat().doneCheckbox(matches[2])
+		description = t.withDoneDate(description)
+	}
+
+	// Keep a tab after the checkbox instead of normalizing it to a space
+	separator := leadingWhitespace(matches[3])
+	if separator == "" {
+		separator = " "
+	}
+
+	t.Description = joinContinuation(description, t.Continuation)
+	t.RawLine = prefix + checkbox + separator + description
+}
+
+// leadingWhitespace returns the spaces and tabs s starts with, as written
+func leadingWhitespace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// lineDescription is the part of Description written on the checkbox line,
+// without the continuation lines
+func (t *Task) lineDescription() string {
+	if t.Continuation == "" {
+		return t.Description
 	}
+	return strings.TrimSpace(strings.TrimSuffix(t.Description, t.Continuation))
+}
 
-	t.RawLine = fmt.Sprintf("%s%s %s", prefix, checkbox, t.Description)
+// joinContinuation appends continuation text to a checkbox line description
+func joinContinuation(description, continuation string) string {
+	if continuation == "" {
+		return description
+	}
+	return description + " " + continuation
 }
 
 // scanVault recursively finds all .md files in a directory
-func scanVault(vaultPath string) ([]string, error) {
+func scanVault(vaultPath string, opts scanOptions) ([]string, error) {
+	return scanVaultContext(context.Background(), vaultPath, opts)
+}
+
+// scanOptions holds the config settings that decide which files a vault walk
+// visits and how their tasks are read
+type scanOptions struct {
+	followSymlinks bool        // Descend into symlinked directories (config "follow_symlinks")
+	format         *taskFormat // Date layout of the tasks; nil is the default
+	extensions     []string    // Lowercase file extensions scanned for tasks (config "extensions")
+}
+
+// normalizeExtensions reads the extensions option, normalizing each entry to
+// lowercase with a leading dot. Empty lists keep the ".md" default.
+func normalizeExtensions(exts []string) []string {
+	var normalized []string
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+
+	if len(normalized) == 0 {
+		normalized = []string{".md"}
+	}
+	return normalized
+}
+
+// hasTaskExtension reports whether name ends in one of the scanned
+// extensions, ".md" when none are set
+func (o scanOptions) hasTaskExtension(name string) bool {
+	exts := o.extensions
+	if len(exts) == 0 {
+		exts = []string{".md"}
+	}
+
+	name = strings.ToLower(name)
+	for _, ext := range exts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkTree walks root like filepath.Walk. With followSymlinks it also
+// descends into symlinked directories, reporting their contents under the
+// link's path and skipping directories it has already visited.
+func walkTree(root string, opts scanOptions, fn filepath.WalkFunc) error {
+	if !opts.followSymlinks {
+		return filepath.Walk(root, fn)
+	}
+	return walkFollowing(root, root, make(map[string]bool), fn)
+}
+
+func walkFollowing(realRoot, displayRoot string, visited map[string]bool, fn filepath.WalkFunc) error {
+	return filepath.Walk(realRoot, func(path string, info os.FileInfo, err error) error {
+		display := displayRoot + strings.TrimPrefix(path, realRoot)
+		if err != nil {
+			return fn(display, info, err)
+		}
+
+		if info.IsDir() {
+			// Symlinks pointing back up the tree would otherwise loop forever
+			if real, err := filepath.EvalSymlinks(path); err == nil {
+				if visited[real] {
+					return filepath.SkipDir
+				}
+				visited[real] = true
+			}
+			return fn(display, info, nil)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil || visited[real] {
+					return nil
+				}
+				return walkFollowing(real, display, visited, fn)
+			}
+		}
+
+		return fn(display, info, nil)
+	})
+}
+
+// scanVaultContext is scanVault that stops walking once ctx is cancelled
+func scanVaultContext(ctx context.Context, vaultPath string, opts scanOptions) ([]string, error) {
 	var files []string
 
-	err := filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
+	// Ignore rules are read once, from the root of a directory vault
+	var ignore *ignoreMatcher
+	if info, err := os.Stat(vaultPath); err == nil && info.IsDir() {
+		ignore, err = loadIgnoreFile(filepath.Join(vaultPath, ignoreFileName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := walkTree(vaultPath, opts, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != vaultPath {
 			return filepath.SkipDir
 		}
 
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".md") {
+		if rel, err := filepath.Rel(vaultPath, path); err == nil && path != vaultPath && ignore.match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() && opts.hasTaskExtension(info.Name()) {
 			files = append(files, path)
 		}
 
@@ -127,18 +370,53 @@ func scanVault(vaultPath string) ([]string, error) {
 }
 
 // parseDueDate extracts due date from task description
-func parseDueDate(description string) *time.Time {
-	matches := dueDateRe.FindStringSubmatch(description)
+func (f *taskFormat) parseDueDate(description string) *time.Time {
+	return f.parseMarkedDate(f.dueDateRe, description)
+}
+
+// parseDoneDate extracts the completion date from task description
+func (f *taskFormat) parseDoneDate(description string) *time.Time {
+	return f.parseMarkedDate(f.doneDateRe, description)
+}
+
+// parseCreatedDate extracts the creation date from task description
+func (f *taskFormat) parseCreatedDate(description string) *time.Time {
+	return f.parseMarkedDate(f.createdRe, description)
+}
+
+// parseScheduledDate extracts the scheduled date from task description
+func (f *taskFormat) parseScheduledDate(description string) *time.Time {
+	return f.parseMarkedDate(f.scheduledRe, description)
+}
+
+// parseStartDate extracts the start date from task description
+func (f *taskFormat) parseStartDate(description string) *time.Time {
+	return f.parseMarkedDate(f.startRe, description)
+}
+
+// parseMarkedDate parses the date captured by an emoji marker regexp
+func (f *taskFormat) parseMarkedDate(re *regexp.Regexp, description string) *time.Time {
+	matches := re.FindStringSubmatch(description)
 	if matches == nil {
 		return nil
 	}
-	date, err := time.Parse("2006-01-02", matches[1])
+	date, err := f.parseDate(matches[1])
 	if err != nil {
 		return nil
 	}
 	return &date
 }
 
+// dateMarkerRes are the regexps of every emoji date marker
+func (f *taskFormat) dateMarkerRes() []*regexp.Regexp {
+	return []*regexp.Regexp{f.dueDateRe, f.doneDateRe, f.createdRe, f.scheduledRe, f.startRe}
+}
+
+// withCreatedDate stamps a new task's description with today's date
+func withCreatedDate(description string, format *taskFormat) string {
+	return fmt.Sprintf("%s ➕ %s", description, format.orDefault().formatDate(time.Now()))
+}
+
 // parsePriority extracts priority from task description
 func parsePriority(description string) int {
 	match := priorityRe.FindString(description)
@@ -161,18 +439,52 @@ func (t *Task) SetPriority(priority int) {
 	}
 
 	// Remove existing priority emoji from description
-	t.Description = strings.TrimSpace(priorityRe.ReplaceAllString(t.Description, ""))
+	description := strings.TrimSpace(priorityRe.ReplaceAllString(t.lineDescription(), ""))
 
 	// Add new priority emoji if not normal
 	if emoji := priorityEmojis[priority]; emoji != "" {
-		t.Description = t.Description + " " + emoji
+		description = description + " " + emoji
 	}
+	t.Description = joinContinuation(description, t.Continuation)
 
 	t.Priority = priority
 	t.Modified = true
 	t.rebuildRawLine()
 }
 
+// SetDueDate rewrites the task's 📅 marker in place, appending one when the
+// task has none; nil removes it
+func (t *Task) SetDueDate(date *time.Time) {
+	description := t.lineDescription()
+	format := t.lineFormat()
+
+	if loc := format.dueDateRe.FindStringIndex(description); loc != nil {
+		if date == nil {
+			description = strings.TrimSpace(strings.TrimRight(description[:loc[0]], " ") + description[loc[1]:])
+		} else {
+			description = description[:loc[0]] + "📅 " + format.formatDate(*date) + description[loc[1]:]
+		}
+	} else if date != nil {
+		description = strings.TrimSpace(description + " 📅 " + format.formatDate(*date))
+	}
+	t.Description = joinContinuation(description, t.Continuation)
+
+	t.DueDate = date
+	t.Modified = true
+	t.rebuildRawLine()
+}
+
+// ShiftDueDate moves the due date by days, starting from today when the
+// task has no due date yet
+func (t *Task) ShiftDueDate(days int, today time.Time) {
+	base := today
+	if t.DueDate != nil {
+		base = *t.DueDate
+	}
+	due := time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, days)
+	t.SetDueDate(&due)
+}
+
 // CyclePriorityUp increases priority (towards highest)
 func (t *Task) CyclePriorityUp() {
 	t.SetPriority(t.Priority - 1)
@@ -183,8 +495,10 @@ func (t *Task) CyclePriorityDown() {
 	t.SetPriority(t.Priority + 1)
 }
 
-// parseFile extracts tasks from a markdown file
-func parseFile(filePath string) ([]*Task, error) {
+// parseFile extracts tasks from a markdown file, reading their dates in
+// format; nil uses the default
+func parseFile(filePath string, format *taskFormat) ([]*Task, error) {
+	format = format.orDefault()
 	file, err := os.Open(filePath)
 
 	if err != nil {
@@ -193,7 +507,21 @@ func parseFile(filePath string) ([]*Task, error) {
 
 	defer file.Close()
 
+	// Cached tasks keep the mtime they were parsed with
+	var modTime time.Time
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+
 	var tasks []*Task
+	var open []*Task // Chain of possible parents, outermost first
+	var openColumns []int
+
+	// Task whose text may continue on the following indented lines
+	var continued *Task
+	var continuedColumn int
+
+	var title, heading string
 
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
@@ -202,27 +530,217 @@ func parseFile(filePath string) ([]*Task, error) {
 		lineNum++
 		line := scanner.Text()
 
+		// A heading starts a new list under its name; a line of tags such as
+		// #work is not a heading
+		if headingRe.MatchString(line) {
+			open, openColumns = nil, nil
+			continued = nil
+			heading = strings.TrimSpace(strings.TrimLeft(line, "#"))
+
+			if title == "" && strings.HasPrefix(line, "# ") {
+				title = strings.TrimSpace(line[2:])
+			}
+		}
+
 		matches := taskRe.FindStringSubmatch(line)
 
 		if matches != nil {
 			status := strings.ToLower(matches[1])
 			description := strings.TrimSpace(matches[2])
 
-			tasks = append(tasks, &Task{
-				FilePath:    filePath,
-				LineNumber:  lineNum,
-				RawLine:     line,
-				Done:        status == "x",
-				Description: description,
-				DueDate:     parseDueDate(description),
-				Priority:    parsePriority(description),
-			})
+			task := &Task{
+				FilePath:        filePath,
+				LineNumber:      lineNum,
+				RawLine:         line,
+				OriginalRawLine: line,
+				Done:            status == "x",
+				Description:     description,
+				Heading:         heading,
+				DueDate:         format.parseDueDate(description),
+				CreatedDate:     format.parseCreatedDate(description),
+				ScheduledDate:   format.parseScheduledDate(description),
+				StartDate:       format.parseStartDate(description),
+				Priority:        parsePriority(description),
+				Format:          format,
+			}
+			if task.Done {
+				task.DoneDate = format.parseDoneDate(description)
+			}
+
+			column := indentColumns(line)
+			for len(open) > 0 && openColumns[len(openColumns)-1] >= column {
+				open = open[:len(open)-1]
+				openColumns = openColumns[:len(openColumns)-1]
+			}
+			if len(open) > 0 {
+				task.Parent = open[len(open)-1]
+				task.Indent = task.Parent.Indent + 1
+			}
+			open = append(open, task)
+			openColumns = append(openColumns, column)
+
+			tasks = append(tasks, task)
+			continued, continuedColumn = task, column
+			continue
+		}
+
+		// More indented plain text right below a task continues its
+		// description; blank lines and list items end it
+		if continued == nil {
+			continue
+		}
+		if !isContinuation(line, continuedColumn) {
+			continued = nil
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if continued.Continuation != "" {
+			continued.Continuation += " "
 		}
+		continued.Continuation += text
+		continued.Description = joinContinuation(continued.Description, text)
+	}
+
+	// The title may come after the first tasks
+	for _, task := range tasks {
+		task.NoteTitle = title
+		task.FileModTime = modTime
 	}
 
 	return tasks, scanner.Err()
 }
 
+// isContinuation reports whether line continues the description of a task
+// indented by column: more indented plain text, not blank or a list item
+func isContinuation(line string, column int) bool {
+	return strings.TrimSpace(line) != "" && indentColumns(line) > column && !listItemRe.MatchString(line)
+}
+
+// continuationEnd returns the index of the first line after the task on
+// 1-based lineNumber and the lines continuing its text
+func continuationEnd(lines []string, lineNumber int) int {
+	end := lineNumber
+	column := indentColumns(lines[lineNumber-1])
+	for end < len(lines) && isContinuation(lines[end], column) {
+		end++
+	}
+	return end
+}
+
+// indentColumns returns the width of a line's leading whitespace, counting
+// tabs as tabWidth columns
+func indentColumns(line string) int {
+	columns := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			columns++
+		case '\t':
+			columns += tabWidth
+		default:
+			return columns
+		}
+	}
+	return columns
+}
+
+// locateTaskLine returns the current line number of the task in lines.
+// If the file changed on disk since parsing, the closest line matching the
+// original content is used instead.
+func locateTaskLine(lines []string, task *Task) (int, error) {
+	return locateFreeTaskLine(lines, task, nil)
+}
+
+// locateFreeTaskLine is locateTaskLine skipping the line numbers in taken,
+// so tasks with identical lines each find their own
+func locateFreeTaskLine(lines []string, task *Task, taken map[int]bool) (int, error) {
+	if task.OriginalRawLine == "" && !taken[task.LineNumber] {
+		// No snapshot to verify against, trust the parsed line number
+		return task.LineNumber, nil
+	}
+
+	matches := func(idx int) bool {
+		return idx >= 0 && idx < len(lines) && !taken[idx+1] && lines[idx] == task.OriginalRawLine
+	}
+
+	idx := task.LineNumber - 1
+	if matches(idx) {
+		return task.LineNumber, nil
+	}
+
+	for offset := 1; offset < len(lines); offset++ {
+		if matches(idx - offset) {
+			return idx - offset + 1, nil
+		}
+		if matches(idx + offset) {
+			return idx + offset + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s:%d", ErrTaskLineChanged, task.FilePath, task.LineNumber)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, syncs it
+// and renames it over path so readers never observe a partial file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	// The rename would replace a read-only file, so check it up front
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf("%w: %s", ErrReadOnly, path)
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return readOnlyError(path, err)
+	}
+
+	tempPath := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Chmod(tempPath, 0644); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return readOnlyError(path, err)
+	}
+
+	// Persist the rename itself; not supported everywhere, so best effort
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// readOnlyError reports permission and read-only mount errors as ErrReadOnly
+func readOnlyError(path string, err error) error {
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		return fmt.Errorf("%w: %s", ErrReadOnly, path)
+	}
+	return err
+}
+
 // saveTask writes the modified task back to its source file
 func saveTask(task *Task) error {
 	content, err := os.ReadFile(task.FilePath)
@@ -233,46 +751,258 @@ func saveTask(task *Task) error {
 
 	lines := strings.Split(string(content), "\n")
 
-	if task.LineNumber > 0 && task.LineNumber <= len(lines) {
-		lines[task.LineNumber-1] = task.RawLine
+	lineNumber, err := locateTaskLine(lines, task)
+	if err != nil {
+		return err
 	}
 
-	tempPath := task.FilePath + ".tmp"
-	err = os.WriteFile(tempPath, []byte(strings.Join(lines, "\n")), 0644)
+	if lineNumber > 0 && lineNumber <= len(lines) {
+		lines[lineNumber-1] = task.RawLine
+	}
 
-	if err != nil {
+	if err := writeFileAtomic(task.FilePath, []byte(strings.Join(lines, "\n"))); err != nil {
 		return err
 	}
 
-	return os.Rename(tempPath, task.FilePath)
+	task.LineNumber = lineNumber
+	task.OriginalRawLine = task.RawLine
+
+	return nil
 }
 
-// deleteTask removes a task line from its source file
-func deleteTask(task *Task) error {
+// deleteTask removes a task line and its continuation lines from its source
+// file, returning the removed lines
+func deleteTask(task *Task) ([]string, error) {
 	content, err := os.ReadFile(task.FilePath)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	lines := strings.Split(string(content), "\n")
 
-	if task.LineNumber > 0 && task.LineNumber <= len(lines) {
-		lines = append(lines[:task.LineNumber-1], lines[task.LineNumber:]...)
+	lineNumber, err := locateTaskLine(lines, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	if lineNumber > 0 && lineNumber <= len(lines) {
+		end := continuationEnd(lines, lineNumber)
+		removed = slices.Clone(lines[lineNumber-1 : end])
+		lines = append(lines[:lineNumber-1], lines[end:]...)
+	}
+
+	if err := writeFileAtomic(task.FilePath, []byte(strings.Join(lines, "\n"))); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// taskEdit is a change to a task's line for applyTaskEdits: writing its
+// RawLine followed by the Insert lines, or removing it when Delete is set
+type taskEdit struct {
+	Task   *Task
+	Delete bool     // Remove the line and the lines continuing it
+	Insert []string // Lines to add after the task and its continuation lines
+}
+
+// saveTasks writes several modified tasks back, reading and writing each
+// file once instead of once per task. It stops at the first file that fails,
+// returning the changes written until then.
+func saveTasks(tasks []*Task) ([]LineChange, error) {
+	edits := make([]taskEdit, len(tasks))
+	for i, task := range tasks {
+		edits[i] = taskEdit{Task: task}
+	}
+	return applyTaskEdits(edits)
+}
+
+// LineChange is a line a task edit changes: Old becomes New, is removed
+// when Deleted or is added before Line when Inserted
+type LineChange struct {
+	Path string
+	Line int
+	Old  string
+	New  string
+
+	Deleted  bool
+	Inserted bool
+}
+
+// applyTaskEdits groups edits by file and applies each file's edits with a
+// single read and write, stopping at the first file that fails
+func applyTaskEdits(edits []taskEdit) ([]LineChange, error) {
+	return editFiles(edits, true)
+}
+
+// planTaskEdits returns the line changes applyTaskEdits would make, without
+// writing any file or updating the tasks
+func planTaskEdits(edits []taskEdit) ([]LineChange, error) {
+	return editFiles(edits, false)
+}
+
+// editFiles computes the line changes of edits file by file, writing them
+// when write is set
+func editFiles(edits []taskEdit, write bool) ([]LineChange, error) {
+	var files []string
+	byFile := make(map[string][]taskEdit)
+	for _, edit := range edits {
+		path := edit.Task.FilePath
+		if _, ok := byFile[path]; !ok {
+			files = append(files, path)
+		}
+		// The same task twice is edited once
+		if !slices.ContainsFunc(byFile[path], func(e taskEdit) bool { return e.Task == edit.Task }) {
+			byFile[path] = append(byFile[path], edit)
+		}
 	}
 
-	tempPath := task.FilePath + ".tmp"
-	err = os.WriteFile(tempPath, []byte(strings.Join(lines, "\n")), 0644)
+	var changes []LineChange
+	for _, path := range files {
+		fileChanges, err := applyFileEdits(path, byFile[path], write)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, fileChanges...)
+	}
+	return changes, nil
+}
 
+// applyFileEdits edits the lines of one file. Every line is located in the
+// file as read, tasks with identical lines each taking their own occurrence,
+// then the deletes and inserts are applied from the bottom up so none of
+// them shifts the lines still to be edited. Without write only the changes
+// are returned.
+func applyFileEdits(path string, edits []taskEdit, write bool) ([]LineChange, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return os.Rename(tempPath, task.FilePath)
+	lines := strings.Split(string(content), "\n")
+
+	// Each edit spans its task's line and the lines continuing it
+	starts := make([]int, len(edits))
+	ends := make([]int, len(edits))
+	taken := make(map[int]bool)
+	for i, edit := range edits {
+		lineNumber, err := locateFreeTaskLine(lines, edit.Task, taken)
+		if err != nil {
+			return nil, err
+		}
+		if lineNumber < 1 || lineNumber > len(lines) {
+			return nil, fmt.Errorf("%w: %s:%d", ErrTaskLineChanged, path, edit.Task.LineNumber)
+		}
+		starts[i] = lineNumber - 1
+		ends[i] = continuationEnd(lines, lineNumber)
+		taken[lineNumber] = true
+	}
+
+	var changes []LineChange
+	for i, edit := range edits {
+		if edit.Delete {
+			for idx := starts[i]; idx < ends[i]; idx++ {
+				changes = append(changes, LineChange{Path: path, Line: idx + 1, Old: lines[idx], Deleted: true})
+			}
+			continue
+		}
+		changes = append(changes, LineChange{Path: path, Line: starts[i] + 1, Old: lines[starts[i]], New: edit.Task.RawLine})
+		for _, line := range edit.Insert {
+			changes = append(changes, LineChange{Path: path, Line: ends[i] + 1, New: line, Inserted: true})
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b LineChange) int { return a.Line - b.Line })
+	if !write {
+		return changes, nil
+	}
+
+	// Rewritten lines stay in place. The splices run bottom up, a delete
+	// before an insert at the same line so it can't remove the new lines.
+	type splice struct {
+		order  int
+		at     int
+		remove int
+		insert []string
+	}
+	var splices []splice
+	for i, edit := range edits {
+		if edit.Delete {
+			splices = append(splices, splice{order: 2 * starts[i], at: starts[i], remove: ends[i] - starts[i]})
+			continue
+		}
+		lines[starts[i]] = edit.Task.RawLine
+		if len(edit.Insert) > 0 {
+			splices = append(splices, splice{order: 2*ends[i] - 1, at: ends[i], insert: edit.Insert})
+		}
+	}
+	slices.SortFunc(splices, func(a, b splice) int { return b.order - a.order })
+	for _, s := range splices {
+		lines = slices.Delete(lines, s.at, s.at+s.remove)
+		lines = slices.Insert(lines, s.at, s.insert...)
+	}
+
+	if err := writeFileAtomic(path, []byte(strings.Join(lines, "\n"))); err != nil {
+		return nil, err
+	}
+
+	// Kept tasks move by the lines removed and added above them
+	for i, edit := range edits {
+		if edit.Delete {
+			continue
+		}
+		shift := 0
+		for _, s := range splices {
+			if s.at <= starts[i] {
+				shift += len(s.insert) - s.remove
+			}
+		}
+		edit.Task.LineNumber = starts[i] + 1 + shift
+		edit.Task.OriginalRawLine = edit.Task.RawLine
+	}
+
+	return changes, nil
+}
+
+// parseTaskRef splits a "notes/x.md:42" reference into the file, relative to
+// vaultPath unless absolute, and its line number
+func parseTaskRef(ref, vaultPath string) (string, int, error) {
+	i := strings.LastIndex(ref, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("%w: %q", ErrInvalidTaskRef, ref)
+	}
+	file := ref[:i]
+	line, err := strconv.Atoi(ref[i+1:])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("%w: %q", ErrInvalidTaskRef, ref)
+	}
+
+	file, err = expandPath(file)
+	if err != nil {
+		return "", 0, err
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(vaultPath, file)
+	}
+	return file, line, nil
+}
+
+// taskAtLine returns the task on the given line of a file
+func taskAtLine(path string, line int, format *taskFormat) (*Task, error) {
+	tasks, err := parseFile(path, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if task.LineNumber == line {
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s:%d", ErrNotTaskLine, path, line)
 }
 
-// restoreTaskLine inserts a line back into the file at the specified line number
-func restoreTaskLine(filePath string, lineNumber int, line string) error {
+// restoreTaskLine inserts lines back into the file at the specified line number
+func restoreTaskLine(filePath string, lineNumber int, restored ...string) error {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
@@ -288,18 +1018,12 @@ func restoreTaskLine(filePath string, lineNumber int, line string) error {
 		insertAt = len(lines)
 	}
 
-	newLines := make([]string, 0, len(lines)+1)
+	newLines := make([]string, 0, len(lines)+len(restored))
 	newLines = append(newLines, lines[:insertAt]...)
-	newLines = append(newLines, line)
+	newLines = append(newLines, restored...)
 	newLines = append(newLines, lines[insertAt:]...)
 
-	tempPath := filePath + ".tmp"
-	err = os.WriteFile(tempPath, []byte(strings.Join(newLines, "\n")), 0644)
-	if err != nil {
-		return err
-	}
-
-	return os.Rename(tempPath, filePath)
+	return writeFileAtomic(filePath, []byte(strings.Join(newLines, "\n")))
 }
 
 // addTask inserts a new task line after the reference task in its source file
@@ -311,13 +1035,24 @@ func addTask(refTask *Task, description string) (*Task, error) {
 	}
 
 	lines := strings.Split(string(content), "\n")
-	newLine := "- [ ] " + description
 
-	// Insert after the reference task's line
-	insertAt := refTask.LineNumber
-	if insertAt > len(lines) {
-		insertAt = len(lines)
+	// Insert after the reference task's line and any lines continuing it
+	lineNumber, err := locateTaskLine(lines, refTask)
+	if err != nil {
+		return nil, err
 	}
+	lineNumber = min(lineNumber, len(lines))
+	insertAt := lineNumber
+
+	// Reuse the reference's indentation and list marker so subtasks stay grouped
+	prefix := "- "
+	if lineNumber > 0 {
+		if matches := checkboxRe.FindStringSubmatch(lines[lineNumber-1]); matches != nil {
+			prefix = matches[1]
+		}
+		insertAt = continuationEnd(lines, lineNumber)
+	}
+	newLine := prefix + "[ ] " + description
 
 	// Insert the new line
 	newLines := make([]string, 0, len(lines)+1)
@@ -325,24 +1060,85 @@ func addTask(refTask *Task, description string) (*Task, error) {
 	newLines = append(newLines, newLine)
 	newLines = append(newLines, lines[insertAt:]...)
 
-	tempPath := refTask.FilePath + ".tmp"
-	err = os.WriteFile(tempPath, []byte(strings.Join(newLines, "\n")), 0644)
+	if err := writeFileAtomic(refTask.FilePath, []byte(strings.Join(newLines, "\n"))); err != nil {
+		return nil, err
+	}
 
-	if err != nil {
+	return &Task{
+		FilePath:        refTask.FilePath,
+		LineNumber:      insertAt + 1,
+		RawLine:         newLine,
+		OriginalRawLine: newLine,
+		Done:            false,
+		Description:     description,
+		CreatedDate:     refTask.lineFormat().parseCreatedDate(description),
+		Priority:        PriorityNormal,
+		Format:          refTask.Format,
+	}, nil
+}
+
+// expandDateTokens fills {YYYY}, {YY}, {MM} and {DD} in a daily note template
+func expandDateTokens(template string, now time.Time) string {
+	return strings.NewReplacer(
+		"{YYYY}", now.Format("2006"),
+		"{YY}", now.Format("06"),
+		"{MM}", now.Format("01"),
+		"{DD}", now.Format("02"),
+	).Replace(template)
+}
+
+// ensureNoteHeader creates path with header as its first line unless the
+// file already exists
+func ensureNoteHeader(path string, header string) error {
+	if _, err := os.Stat(path); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(header+"\n\n"))
+}
+
+// appendTask adds a new task line at the end of path, creating the file and
+// its directory if needed
+func appendTask(path string, description string, format *taskFormat) (*Task, error) {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
-	if err := os.Rename(tempPath, refTask.FilePath); err != nil {
+	if err != nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	text := string(content)
+	newLine := "- [ ] " + description
+
+	lineNumber := 1
+	if text != "" {
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		lineNumber = strings.Count(text, "\n") + 1
+	}
+	text += newLine + "\n"
+
+	if err := writeFileAtomic(path, []byte(text)); err != nil {
 		return nil, err
 	}
 
 	return &Task{
-		FilePath:    refTask.FilePath,
-		LineNumber:  insertAt + 1,
-		RawLine:     newLine,
-		Done:        false,
-		Description: description,
-		Priority:    PriorityNormal,
+		FilePath:        path,
+		LineNumber:      lineNumber,
+		RawLine:         newLine,
+		OriginalRawLine: newLine,
+		Description:     description,
+		CreatedDate:     format.orDefault().parseCreatedDate(description),
+		Priority:        PriorityNormal,
+		Format:          format,
 	}, nil
 }
 
@@ -367,23 +1163,166 @@ func openNewTaskInEditor(refTask *Task) tea.Cmd {
 type editorFinishedMsg struct {
 	err  error
 	task *Task
+	diff TaskDiff // Changes to the edited file's tasks
 }
 
-// openInEditor opens the task file in an external editor at the correct line
-func openInEditor(task *Task) tea.Cmd {
+// editorCommand builds the $EDITOR command that opens the task's file at its line
+func editorCommand(task *Task) *exec.Cmd {
 	editor := os.Getenv("EDITOR")
 	if editor == "" {
 		editor = "vi"
 	}
 
 	lineArg := fmt.Sprintf("+%d", task.LineNumber)
-	c := exec.Command(editor, lineArg, task.FilePath)
+	return exec.Command(editor, lineArg, task.FilePath)
+}
+
+// openInEditor opens the task file in an external editor at the correct line,
+// reporting how the file's tasks changed once it closes
+func openInEditor(task *Task) tea.Cmd {
+	c := editorCommand(task)
+	before, _ := parseFile(task.FilePath, task.Format)
 
 	return tea.ExecProcess(c, func(err error) tea.Msg {
-		return editorFinishedMsg{err: err, task: task}
+		msg := editorFinishedMsg{err: err, task: task}
+		if after, parseErr := parseFile(task.FilePath, task.Format); err == nil && parseErr == nil {
+			msg.diff = diffTasks(before, after)
+		}
+		return msg
 	})
 }
 
+// TaskDiff counts how the tasks of a file changed across an edit
+type TaskDiff struct {
+	Added     int
+	Removed   int
+	Edited    int
+	Completed int
+	Reopened  int
+}
+
+// diffTasks compares a file's tasks before and after an edit. Tasks match by
+// description, ignoring the done date, so toggling isn't counted as an edit;
+// leftovers on the same line count as edited, the rest as added or removed.
+func diffTasks(before, after []*Task) TaskDiff {
+	var diff TaskDiff
+
+	key := func(task *Task) string {
+		return strings.TrimSpace(task.lineFormat().doneRe.ReplaceAllString(task.Description, ""))
+	}
+
+	unmatched := make(map[string][]*Task)
+	for _, task := range before {
+		unmatched[key(task)] = append(unmatched[key(task)], task)
+	}
+
+	var added []*Task
+	for _, task := range after {
+		candidates := unmatched[key(task)]
+		if len(candidates) == 0 {
+			added = append(added, task)
+			continue
+		}
+		previous := candidates[0]
+		unmatched[key(task)] = candidates[1:]
+
+		switch {
+		case task.Done && !previous.Done:
+			diff.Completed++
+		case !task.Done && previous.Done:
+			diff.Reopened++
+		}
+	}
+
+	removedLines := make(map[int]int)
+	for _, tasks := range unmatched {
+		for _, task := range tasks {
+			removedLines[task.LineNumber]++
+			diff.Removed++
+		}
+	}
+
+	for _, task := range added {
+		if removedLines[task.LineNumber] > 0 {
+			removedLines[task.LineNumber]--
+			diff.Removed--
+			diff.Edited++
+			continue
+		}
+		diff.Added++
+	}
+
+	return diff
+}
+
+// String summarizes the diff, e.g. "3 tasks added, 1 completed", or "" when
+// nothing changed
+func (d TaskDiff) String() string {
+	var parts []string
+	for _, change := range []struct {
+		count int
+		label string
+	}{
+		{d.Added, "added"},
+		{d.Removed, "removed"},
+		{d.Edited, "edited"},
+		{d.Completed, "completed"},
+		{d.Reopened, "reopened"},
+	} {
+		if change.count == 0 {
+			continue
+		}
+		// Only the first count names the noun
+		noun := ""
+		if len(parts) == 0 {
+			noun = " tasks"
+			if change.count == 1 {
+				noun = " task"
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%d%s %s", change.count, noun, change.label))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// revealFinishedMsg is sent once the file manager has been launched
+type revealFinishedMsg struct {
+	err error
+}
+
+// fileManagerCommand builds the command that opens dir in the file manager for goos
+func fileManagerCommand(goos, dir string) (*exec.Cmd, error) {
+	switch goos {
+	case "darwin":
+		return exec.Command("open", dir), nil
+	case "windows":
+		return exec.Command("explorer", dir), nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return exec.Command("xdg-open", dir), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoFileManager, goos)
+}
+
+// revealInFileManager opens the folder containing the task's file without
+// waiting for the file manager to exit
+func revealInFileManager(task *Task) tea.Cmd {
+	dir := filepath.Dir(task.FilePath)
+
+	return func() tea.Msg {
+		c, err := fileManagerCommand(runtime.GOOS, dir)
+		if err != nil {
+			return revealFinishedMsg{err: err}
+		}
+		if err := c.Start(); err != nil {
+			return revealFinishedMsg{err: err}
+		}
+		go c.Wait()
+
+		return revealFinishedMsg{}
+	}
+}
+
 // createTasksFile creates a tasks.md file with an empty task in the current directory
 func createTasksFile() error {
 	filename := "tasks.md"
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 const (
@@ -20,7 +26,11 @@ const (
 	maxInputWidth        = 70
 	minInputWidth        = 30
 	prioritySaveDebounce = 500 * time.Millisecond
+	selfModifiedWindow   = 500 * time.Millisecond
 	cursorCharacter      = ">"
+	checkboxClickWidth   = 5 // leading padding plus "[ ]" as rendered by Glamour
+	hScrollStep          = 8 // cells scrolled per h/l press
+	defaultInboxFile     = "inbox.md"
 )
 
 type prioritySaveMsg struct {
@@ -36,6 +46,8 @@ const (
 	OpToggle OperationType = iota
 	OpDelete
 	OpPriorityChange
+	OpDueDateChange
+	OpBatch
 )
 
 // UndoEntry represents a single undoable operation
@@ -44,23 +56,36 @@ type UndoEntry struct {
 	Timestamp        time.Time
 	FilePath         string
 	LineNumber       int
-	DeletedLine      string // For deletion undo
-	PreviousPriority int    // For priority undo
-	WasDone          bool   // For toggle undo
+	DeletedLine      string     // For deletion undo
+	DeletedContinued []string   // Continuation lines deleted with DeletedLine
+	PreviousPriority int        // For priority undo
+	PreviousDueDate  *time.Time // For due date undo
+	WasDone          bool       // For toggle undo
+
+	// For batch undo: the operations undone together
+	Entries []UndoEntry
 }
 
 const maxUndoStackSize = 50
 
+// snoozeDays is how far each snooze key moves a due date
+var snoozeDays = map[string]int{">": 1, "<": -1, "]": 7, "[": -7}
+
+// runtimeSortOrder is the cycle of sort fields for the s key ("" keeps the query's own)
+var runtimeSortOrder = []string{"", "due", "priority", "description"}
+
 // ProfileTab holds per-profile state for tabbed mode
 type ProfileTab struct {
 	Profile   *ResolvedProfile
 	Sections  []QuerySection
 	Tasks     []*Task
+	AllTasks  []*Task
 	Cursor    int
 	Cache     *TaskCache
 	Watcher   *Watcher
 	Debouncer *Debouncer
 	Queries   []*Query
+	Warnings  []ParseWarning
 }
 
 // model is the BubbleTea model
@@ -76,18 +101,48 @@ type model struct {
 	vaultPath    string
 	titleName    string
 	queryFile    string
+	queryFiles   []string // Further query files merged after queryFile
+	queryBlock   string   // Fence label of query blocks (config "query_block")
+	checkboxes   checkboxGlyphs
+	fullPaths    bool // Show absolute task locations (config "show_absolute_paths")
 	queries      []*Query
 	quitting     bool
 	err          error
+	warning      string
+	refreshing   bool
+	refreshGen   int      // Bumped by each refresh; results of older ones are dropped
+	allTasks     []*Task  // Every task in the vault, before query filtering
+	showDone     bool     // Ignore "not done" filters
+	runtimeSort  string   // Overrides the queries' sort field when set
+	reversed     bool     // Tasks within each group are shown in reverse
+	todayView    bool     // The built-in Today query replaces the queries
+	focusSection string   // Only this section is shown while set (F)
+	savedQueries []*Query // Queries restored when leaving the Today view
+	pins         []Pin
+	scan         scanOptions // How vaults are walked on refresh
+	short        bool        // Compact lines for every section (config "short")
+	keys         Keymap
+	wrap         bool  // Soft-wrap long lines for every section (config "wrap")
+	hOffset      int   // Horizontal scroll of the selected line
+	hOffsetTask  *Task // Task the scroll applies to; moving away resets it
+	pinsPath     string
 	windowHeight int
 	windowWidth  int
 	aboutOpen    bool
-	viewport     viewport.Model
+	aboutScroll  int
+	aboutFilter  string
+	// Typing into the help filter
+	aboutFiltering bool
+	viewport       viewport.Model
+	lines          *lineCache // Rendered section lines, see sectionLines
 
 	searching        bool
 	searchQuery      string
 	searchNavigating bool
 	filteredTasks    []*Task
+	searchHistory    []string // Past search queries, oldest first
+	historyPath      string
+	historyPos       int // Entries back from the newest while recalling; 0 is the typed query
 	taskToSection    map[*Task]string
 	taskToGroup      map[*Task]string
 
@@ -96,18 +151,66 @@ type model struct {
 	editingTask *Task
 	textInput   textinput.Model
 
+	defaultGroup string   // Profile group for queries without a group by
+	defaultSort  string   // Profile sort for queries without a sort by
+	sectionOrder []string // Section names shown first, in this order
+
 	deleting     bool
 	deletingTask *Task
 
+	// Open tasks of the section X marks done, waiting for confirmation
+	completing        []*Task
+	completingSection string
+
 	adding      bool
 	addingRef   *Task
 	addingInput textinput.Model
 
+	// New task modal (N): target file and description
+	creating       bool
+	creatingOnFile bool
+	creatingFile   textinput.Model
+	creatingInput  textinput.Model
+	inboxFile      string // Default target for N, relative to the vault
+	creatingHeader string // First line of the target file if it has to be created
+	addCreatedDate bool   // Stamp new tasks with ➕ and today's date
+
+	// Daily note capture (D), from daily_note_format/daily_note_header
+	dailyNoteFormat string
+	dailyNoteHeader string
+
+	statusBar bool // Show the selected task's location above the help line
+
+	quickDelete bool   // Delete without confirming (config "confirm_delete = false")
+	notice      string // Transient footer message, cleared by the next key
+
+	// Sections over their query's limit end in a "… N more" line
+	expanded     map[int]bool // Sections shown in full, by index in queries
+	moreSelected bool         // Cursor is on the "more" line below the selected task
+
+	// Files that failed to parse, listed with W
+	parseWarnings []ParseWarning
+	warningsOpen  bool
+	duplicates    []DuplicateGroup // Shown in the duplicates modal while non-nil
+
+	// Copy menu for the selected task, opened with Y
+	copyMenuOpen bool
+
+	// Digits typed ahead of %, as in vim's 50%
+	countPrefix string
+
+	// Next action view, opened with ctrl+n
+	nextOpen    bool
+	nextIndex   int    // Next actions skipped with j; wraps around
+	nextTask    *Task  // Task nextContext was loaded for
+	nextContext string // Rendered note lines around nextTask
+
 	// File watching and caching
 	cache             *TaskCache
 	watcher           *Watcher
 	debouncer         *Debouncer
 	selfModifiedFiles map[string]time.Time
+	pollInterval      time.Duration // Checks cached files for changes when set
 
 	// Undo stack for all operations
 	undoStack []UndoEntry
@@ -137,6 +240,7 @@ func newModel(sections []QuerySection, vaultPath string, titleName string, query
 		titleName:           titleName,
 		queryFile:           queryFile,
 		queries:             queries,
+		warning:             queriesWarning(queries),
 		windowHeight:        defaultWindowHeight,
 		windowWidth:         defaultWindowWidth,
 		viewport:            viewport.New(defaultWindowWidth, defaultWindowHeight),
@@ -149,6 +253,8 @@ func newModel(sections []QuerySection, vaultPath string, titleName string, query
 		selfModifiedFiles:   make(map[string]time.Time),
 		undoStack:           make([]UndoEntry, 0),
 		prioritySavePending: make(map[string]time.Time),
+		keys:                defaultKeymap(),
+		lines:               &lineCache{},
 	}
 }
 
@@ -161,6 +267,7 @@ func newModelWithTabs(tabs []ProfileTab) model {
 			selfModifiedFiles:   make(map[string]time.Time),
 			undoStack:           make([]UndoEntry, 0),
 			prioritySavePending: make(map[string]time.Time),
+			keys:                defaultKeymap(),
 		}
 	}
 
@@ -188,23 +295,30 @@ func newModelWithTabs(tabs []ProfileTab) model {
 		activeTab:           0,
 		sections:            firstTab.Sections,
 		tasks:               firstTab.Tasks,
+		allTasks:            firstTab.AllTasks,
 		cursor:              firstTab.Cursor,
 		vaultPath:           firstTab.Profile.VaultPath,
 		titleName:           firstTab.Profile.Name,
 		queryFile:           queryFile,
 		queries:             firstTab.Queries,
+		warning:             queriesWarning(firstTab.Queries),
 		windowHeight:        defaultWindowHeight,
 		windowWidth:         defaultWindowWidth,
 		viewport:            viewport.New(defaultWindowWidth, defaultWindowHeight),
 		taskToSection:       taskToSection,
 		taskToGroup:         taskToGroup,
 		editorMode:          firstTab.Profile.EditorMode,
+		defaultGroup:        firstTab.Profile.Group,
+		defaultSort:         firstTab.Profile.Sort,
 		cache:               firstTab.Cache,
 		watcher:             firstTab.Watcher,
 		debouncer:           firstTab.Debouncer,
+		parseWarnings:       firstTab.Warnings,
 		selfModifiedFiles:   make(map[string]time.Time),
 		undoStack:           make([]UndoEntry, 0),
 		prioritySavePending: make(map[string]time.Time),
+		keys:                defaultKeymap(),
+		lines:               &lineCache{},
 	}
 }
 
@@ -220,18 +334,44 @@ func (m model) Init() tea.Cmd {
 	} else if m.watcher != nil {
 		cmds = append(cmds, m.watcher.WatchCmd())
 	}
+	if m.pollInterval > 0 {
+		cmds = append(cmds, m.pollCmd())
+	}
 	return tea.Batch(cmds...)
 }
 
+// pollCmd checks the active tab's cached files for changes once the poll
+// interval has passed, for mounts where the watcher misses them
+func (m *model) pollCmd() tea.Cmd {
+	cache, vaultPath := m.cache, m.vaultPath
+	return tea.Tick(m.pollInterval, func(time.Time) tea.Msg {
+		msg := pollMsg{vaultPath: vaultPath}
+		if cache != nil {
+			msg.changed = cache.Changed()
+		}
+		return msg
+	})
+}
+
 func (m *model) switchTab(newTab int) {
 	if !m.tabsEnabled || newTab < 0 || newTab >= len(m.tabs) || newTab == m.activeTab {
 		return
 	}
 
+	// The Today view belongs to the tab it was opened in
+	if m.todayView {
+		m.toggleTodayView()
+	}
+	if m.focusSection != "" {
+		m.toggleFocus()
+	}
+
 	// Save current tab state
 	m.tabs[m.activeTab].Cursor = m.cursor
 	m.tabs[m.activeTab].Sections = m.sections
 	m.tabs[m.activeTab].Tasks = m.tasks
+	m.tabs[m.activeTab].AllTasks = m.allTasks
+	m.tabs[m.activeTab].Warnings = m.parseWarnings
 
 	// Switch to new tab
 	m.activeTab = newTab
@@ -240,14 +380,22 @@ func (m *model) switchTab(newTab int) {
 	// Load new tab state
 	m.sections = tab.Sections
 	m.tasks = tab.Tasks
+	m.invalidateLines()
 	m.cursor = tab.Cursor
 	m.vaultPath = tab.Profile.VaultPath
 	m.titleName = tab.Profile.Name
 	m.queries = tab.Queries
+	m.expanded = nil
 	m.editorMode = tab.Profile.EditorMode
+	m.defaultGroup = tab.Profile.Group
+	m.defaultSort = tab.Profile.Sort
 	m.cache = tab.Cache
 	m.watcher = tab.Watcher
 	m.debouncer = tab.Debouncer
+	m.parseWarnings = tab.Warnings
+	m.warning = queriesWarning(tab.Queries)
+	m.warningsOpen = false
+	m.allTasks = tab.AllTasks
 
 	if tab.Profile.QueryIsFile {
 		m.queryFile = tab.Profile.Query
@@ -289,7 +437,7 @@ func (m model) renderTabBar() string {
 
 	for i, tab := range m.tabs {
 		name := tab.Profile.Name
-		count := len(tab.Tasks)
+		count := countUniqueTasks(tab.Tasks)
 		label := fmt.Sprintf("%s (%d)", name, count)
 
 		if i == m.activeTab {
@@ -323,12 +471,22 @@ func (m model) renderFooterRight(rightInfo string, applyInfoStyle bool) string {
 	return helpBarStyle.Width(m.windowWidth).Render(strings.Repeat(" ", spacing) + rightPart)
 }
 
+// renderFooterSplit lays out left and right across the footer, measuring
+// in cells so wide runes such as emoji keep right flush with the edge. A
+// left part too long to fit is cut short.
 func (m model) renderFooterSplit(left, right string) string {
 	if left == "" && right == "" {
 		return helpBarStyle.Width(m.windowWidth).Render("")
 	}
-	leftWidth := lipgloss.Width(left)
 	rightWidth := lipgloss.Width(right)
+	if right != "" && m.windowWidth > 0 && lipgloss.Width(left)+rightWidth >= m.windowWidth {
+		if avail := m.windowWidth - rightWidth - 1; avail > 0 {
+			left = truncateToWidth(left, avail)
+		} else {
+			left = ""
+		}
+	}
+	leftWidth := lipgloss.Width(left)
 	spacing := m.windowWidth - leftWidth - rightWidth
 	if spacing < 0 {
 		spacing = 0
@@ -364,15 +522,10 @@ func (m model) buildViewport(lines []viewLine, cursorLineIdx int, contentHeight
 	}
 
 	contentLines := make([]string, len(lines))
-	lineHeights := make([]int, len(lines))
-	totalRenderedLines := 0
-
 	for i, line := range lines {
 		contentLines[i] = line.content
-		height := 1 + strings.Count(line.content, "\n")
-		lineHeights[i] = height
-		totalRenderedLines += height
 	}
+	lineHeights, totalRenderedLines := viewLineHeights(lines)
 
 	if cursorLineIdx < 0 {
 		cursorLineIdx = 0
@@ -414,6 +567,43 @@ func normalizeViewHeight(view string, height int) string {
 	return strings.Join(lines, "\n")
 }
 
+// footerView stacks the status bar, when enabled, above the help line
+func (m model) footerView(footerLine string, height int) string {
+	if !m.statusBar {
+		return buildFooterView([]string{footerLine}, height)
+	}
+	return buildFooterView([]string{m.statusLine(), footerLine}, height)
+}
+
+// statusLine shows the title, the selected task's absolute location and the
+// cursor position, cutting the path from the left to fit one line
+func (m model) statusLine() string {
+	tasks := m.activeTasks()
+
+	left := helpBarKeyStyle.Render(m.titleName)
+	right := helpBarInfoStyle.Render(fmt.Sprintf("%d tasks", len(tasks)))
+
+	if len(tasks) == 0 || m.cursor >= len(tasks) {
+		return m.renderFooterSplit(left, right)
+	}
+
+	task := tasks[m.cursor]
+	right = helpBarInfoStyle.Render(fmt.Sprintf("%d/%d", m.cursor+1, len(tasks)))
+
+	path := task.FilePath
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	location := fmt.Sprintf("%s:%d", path, task.LineNumber)
+
+	available := m.windowWidth - lipgloss.Width(left) - lipgloss.Width(right) - 2
+	if overflow := lipgloss.Width(location) - available; overflow > 0 {
+		location = ansi.TruncateLeft(location, overflow+1, "…")
+	}
+
+	return m.renderFooterSplit(left+" "+helpBarDescStyle.Render(location), right)
+}
+
 func buildFooterView(lines []string, height int) string {
 	if height <= 0 {
 		return ""
@@ -480,6 +670,32 @@ func taskKey(task *Task) string {
 	return fmt.Sprintf("%s:%d", task.FilePath, task.LineNumber)
 }
 
+// findTask locates target in tasks. It prefers the same content at the same
+// file and line, then the same content elsewhere in the file (lines shifted),
+// then whatever is now at the same file and line (edited in place).
+func findTask(tasks []*Task, target *Task) int {
+	key := taskKey(target)
+	for i, task := range tasks {
+		if taskKey(task) == key && task.RawLine == target.RawLine {
+			return i
+		}
+	}
+
+	for i, task := range tasks {
+		if task.FilePath == target.FilePath && task.RawLine == target.RawLine {
+			return i
+		}
+	}
+
+	for i, task := range tasks {
+		if taskKey(task) == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // pushUndo adds an entry to the undo stack
 func (m *model) pushUndo(entry UndoEntry) {
 	entry.Timestamp = time.Now()
@@ -507,34 +723,57 @@ func (m *model) isRecentlyToggled(task *Task) bool {
 		if entry.Type == OpToggle && entry.FilePath == task.FilePath && entry.LineNumber == task.LineNumber {
 			return true
 		}
+		for _, batched := range entry.Entries {
+			if batched.Type == OpToggle && batched.FilePath == task.FilePath && batched.LineNumber == task.LineNumber {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-// undoLastOperation undoes the most recent operation
-func (m *model) undoLastOperation() {
+// undoLastOperation undoes the most recent operation, returning the
+// refresh it needs, if any
+func (m *model) undoLastOperation() tea.Cmd {
 	entry := m.popUndo()
 	if entry == nil {
-		return
+		return nil
 	}
 
+	return m.undoEntry(entry)
+}
+
+// undoEntry undoes a single operation, or every operation of a batch
+func (m *model) undoEntry(entry *UndoEntry) tea.Cmd {
 	switch entry.Type {
+	case OpBatch:
+		var cmds []tea.Cmd
+		for i := range slices.Backward(entry.Entries) {
+			cmds = append(cmds, m.undoEntry(&entry.Entries[i]))
+		}
+		return tea.Batch(cmds...)
 	case OpToggle:
 		m.undoToggle(entry)
 	case OpDelete:
-		m.undoDelete(entry)
+		return m.undoDelete(entry)
 	case OpPriorityChange:
 		m.undoPriorityChange(entry)
+	case OpDueDateChange:
+		m.undoDueDateChange(entry)
 	}
+	return nil
 }
 
 // undoToggle restores a task's previous toggle state
 func (m *model) undoToggle(entry *UndoEntry) {
 	for _, task := range m.tasks {
 		if task.FilePath == entry.FilePath && task.LineNumber == entry.LineNumber {
+			previous := *task
 			task.Toggle()
+			m.invalidateLines()
 			if err := saveTask(task); err != nil {
-				m.err = err
+				*task = previous // Match what's on disk
+				m.saveFailed(err)
 			} else {
 				m.selfModifiedFiles[task.FilePath] = time.Now()
 			}
@@ -543,23 +782,85 @@ func (m *model) undoToggle(entry *UndoEntry) {
 	}
 }
 
-// undoDelete restores a deleted task line
-func (m *model) undoDelete(entry *UndoEntry) {
-	if err := restoreTaskLine(entry.FilePath, entry.LineNumber, entry.DeletedLine); err != nil {
-		m.err = err
+// undoDelete restores a deleted task line, returning the refresh that
+// lists it again
+func (m *model) undoDelete(entry *UndoEntry) tea.Cmd {
+	restored := append([]string{entry.DeletedLine}, entry.DeletedContinued...)
+	if err := restoreTaskLine(entry.FilePath, entry.LineNumber, restored...); err != nil {
+		m.saveFailed(err)
 	} else {
 		m.selfModifiedFiles[entry.FilePath] = time.Now()
 	}
-	m.refresh()
+	return m.refreshCmd()
+}
+
+// startDelete asks to confirm deleting task, or deletes it right away when
+// confirm_delete is off, returning the refresh that drops it from the list
+func (m *model) startDelete(task *Task) tea.Cmd {
+	if !m.quickDelete {
+		m.deleting = true
+		m.deletingTask = task
+		return nil
+	}
+
+	if m.deleteWithUndo(task) {
+		m.notice = "deleted — u to undo"
+	}
+	return m.refreshCmd()
+}
+
+// deleteWithUndo removes the task's lines, recording them on the undo stack,
+// and reports whether they were removed
+func (m *model) deleteWithUndo(task *Task) bool {
+	lineNumber := task.LineNumber
+	removed, err := deleteTask(task)
+	if err != nil {
+		m.saveFailed(err)
+		return false
+	}
+	m.selfModifiedFiles[task.FilePath] = time.Now()
+
+	if len(removed) > 0 {
+		m.pushUndo(UndoEntry{
+			Type:             OpDelete,
+			FilePath:         task.FilePath,
+			LineNumber:       lineNumber,
+			DeletedLine:      removed[0],
+			DeletedContinued: removed[1:],
+		})
+	}
+	return true
 }
 
 // undoPriorityChange restores a task's previous priority
 func (m *model) undoPriorityChange(entry *UndoEntry) {
 	for _, task := range m.tasks {
 		if task.FilePath == entry.FilePath && task.LineNumber == entry.LineNumber {
+			previous := *task
 			task.SetPriority(entry.PreviousPriority)
+			m.invalidateLines()
+			if err := saveTask(task); err != nil {
+				*task = previous // Match what's on disk
+				m.saveFailed(err)
+			} else {
+				m.selfModifiedFiles[task.FilePath] = time.Now()
+			}
+			return
+		}
+	}
+}
+
+// undoDueDateChange restores a task's previous due date, or removes the one
+// a snooze added
+func (m *model) undoDueDateChange(entry *UndoEntry) {
+	for _, task := range m.tasks {
+		if task.FilePath == entry.FilePath && task.LineNumber == entry.LineNumber {
+			previous := *task
+			task.SetDueDate(entry.PreviousDueDate)
+			m.invalidateLines()
 			if err := saveTask(task); err != nil {
-				m.err = err
+				*task = previous // Match what's on disk
+				m.saveFailed(err)
 			} else {
 				m.selfModifiedFiles[task.FilePath] = time.Now()
 			}
@@ -573,7 +874,10 @@ func (m *model) filterTasksWithRecent(allTasks []*Task, query *Query) []*Task {
 	return Filter(allTasks, func(task *Task) bool {
 		// Date filters always apply - a task must match the date criteria
 		// regardless of whether it was recently toggled
-		if len(query.DateFilters) > 0 && !matchAllDateFilters(task, query.DateFilters) {
+		if !matchQueryDates(task, query) {
+			return false
+		}
+		if !matchHeadingFilters(task, query) {
 			return false
 		}
 		// Recently toggled tasks bypass the "not done" filter (for undo capability)
@@ -581,75 +885,361 @@ func (m *model) filterTasksWithRecent(allTasks []*Task, query *Query) []*Task {
 		if m.isRecentlyToggled(task) {
 			return true
 		}
-		// Apply normal "not done" filtering
+		// Apply normal "not done" / "done" filtering
 		if query.NotDone && task.Done {
 			return false
 		}
+		if query.DoneOnly && !task.Done {
+			return false
+		}
 		return true
 	})
 }
 
-func (m *model) refresh() {
-	m.refreshWithCache()
+// refreshDoneMsg carries the result of loading tasks for a refresh
+type refreshDoneMsg struct {
+	gen       int // refreshGen when the refresh started
+	vaultPath string
+	queries   []*Query // Re-parsed queries when a query file is used
+	queryErr  error
+	tasks     []*Task
+	warnings  []ParseWarning
+	err       error
 }
 
-func (m *model) refreshWithCache() {
-	// If we have a query file, re-parse it; otherwise reuse existing queries
-	if m.queryFile != "" {
-		queries, err := parseAllQueryBlocks(m.queryFile)
+// loadTasks re-reads the query files for blocks fenced with queryBlock and
+// rescans the vault, reusing cached files. It doesn't touch the model so it
+// can run in the background.
+func loadTasks(vaultPath string, queryFiles []string, queryBlock string, opts scanOptions, cache *TaskCache) refreshDoneMsg {
+	result := refreshDoneMsg{vaultPath: vaultPath}
+
+	// If we have query files, re-parse them; otherwise reuse existing queries
+	if len(queryFiles) > 0 {
+		queries, warnings, err := parseQueryFiles(queryFiles, queryBlock)
 		if err != nil {
-			m.err = err
-			return
+			result.queryErr = err
+			return result
 		}
-		m.queries = queries
+		result.queries = queries
+		result.warnings = warnings
+	}
+
+	scan := scanAndParse(context.Background(), vaultPath, opts, cache, nil)
+	if scan.Error != nil {
+		result.err = scan.Error
+		return result
 	}
-	// For inline queries, m.queries is already set and doesn't change
 
-	files, err := scanVault(m.vaultPath)
+	result.tasks = scan.Tasks
+	result.warnings = append(result.warnings, scan.Warnings...)
 
-	if err != nil {
-		m.err = err
+	return result
+}
+
+// refresh synchronously reloads tasks. Writes refresh with refreshCmd
+// instead; this is left for rebuilding before any task was loaded.
+func (m *model) refresh() {
+	m.refreshGen++
+	msg := loadTasks(m.vaultPath, m.allQueryFiles(), m.queryBlock, m.scan, m.cache)
+	msg.gen = m.refreshGen
+	m.applyRefresh(msg)
+}
+
+// allQueryFiles lists the query files reloaded on refresh, if any
+func (m *model) allQueryFiles() []string {
+	if m.queryFile == "" {
+		return nil
+	}
+	return append([]string{m.queryFile}, m.queryFiles...)
+}
+
+// refreshCmd reloads tasks in the background so large vaults don't block
+// input; the result arrives as a refreshDoneMsg. It changes the model, so
+// call it in its own statement before returning the model.
+func (m *model) refreshCmd() tea.Cmd {
+	m.refreshing = true
+	m.refreshGen++
+
+	gen, vaultPath, queryFiles, queryBlock, opts, cache := m.refreshGen, m.vaultPath, m.allQueryFiles(), m.queryBlock, m.scan, m.cache
+
+	return func() tea.Msg {
+		msg := loadTasks(vaultPath, queryFiles, queryBlock, opts, cache)
+		msg.gen = gen
+		return msg
+	}
+}
+
+// applyRefresh rebuilds sections from freshly loaded tasks
+func (m *model) applyRefresh(msg refreshDoneMsg) {
+	// Superseded by a later refresh, which may still be running
+	if msg.gen != m.refreshGen {
 		return
 	}
+	m.refreshing = false
 
-	var allTasks []*Task
+	// Results for a tab that is no longer active
+	if msg.vaultPath != m.vaultPath {
+		return
+	}
 
-	for _, file := range files {
-		// Try cache first
-		if m.cache != nil {
-			if tasks, ok := m.cache.Get(file); ok {
-				allTasks = append(allTasks, tasks...)
-				continue
+	if msg.queryErr != nil {
+		// Keep the last good sections and retry on the next refresh
+		m.warning = fmt.Sprintf("query file: %v", msg.queryErr)
+		return
+	}
+
+	if msg.queries != nil {
+		if m.todayView {
+			m.savedQueries = msg.queries
+		} else {
+			m.queries = msg.queries
+		}
+		m.warning = queriesWarning(msg.queries)
+	}
+
+	if msg.err != nil {
+		m.err = msg.err
+		return
+	}
+
+	m.parseWarnings = msg.warnings
+
+	// Non-nil even for an empty vault, so rebuildSections doesn't reload
+	m.allTasks = msg.tasks
+	if m.allTasks == nil {
+		m.allTasks = []*Task{}
+	}
+	m.rebuildSections()
+}
+
+// viewQuery returns the query used for display, applying profile defaults
+// and runtime toggles to a copy so the parsed query stays untouched
+func (m *model) viewQuery(query *Query) *Query {
+	query = withProfileDefaults(query, m.defaultGroup, m.defaultSort)
+	if !m.showDone && m.runtimeSort == "" {
+		return query
+	}
+
+	q := *query
+	if m.showDone {
+		q.NotDone = false
+	}
+	if m.runtimeSort != "" {
+		q.SortBy = m.runtimeSort
+	}
+	return &q
+}
+
+// pinnedSection builds the synthetic section listing pinned tasks, moving
+// pins along with their tasks and pruning those that no longer point at one
+func (m *model) pinnedSection() *QuerySection {
+	if len(m.pins) == 0 {
+		return nil
+	}
+
+	if pruned, changed := prunePins(m.pins, m.vaultPath, m.allTasks); changed {
+		m.pins = pruned
+		m.savePins()
+	}
+
+	var pinned []*Task
+	for _, pin := range m.pins {
+		for _, task := range m.allTasks {
+			if task.FilePath == pin.FilePath && task.LineNumber == pin.LineNumber {
+				pinned = append(pinned, task)
+				break
 			}
 		}
+	}
 
-		// Parse and cache
-		tasks, err := parseFile(file)
-		if err != nil {
-			continue
+	if len(pinned) == 0 {
+		return nil
+	}
+
+	return &QuerySection{
+		Name:   pinnedSectionName,
+		Query:  &Query{},
+		Groups: []TaskGroup{{Tasks: pinned}},
+		Tasks:  pinned,
+	}
+}
+
+// togglePin pins or unpins task and rebuilds the view
+func (m *model) togglePin(task *Task) {
+	if isPinned(m.pins, task) {
+		var kept []Pin
+		for _, pin := range m.pins {
+			if pin.FilePath != task.FilePath || pin.LineNumber != task.LineNumber {
+				kept = append(kept, pin)
+			}
 		}
+		m.pins = kept
+	} else {
+		m.pins = append(m.pins, Pin{FilePath: task.FilePath, LineNumber: task.LineNumber, Text: task.RawLine})
+	}
 
-		if m.cache != nil {
-			m.cache.Set(file, tasks)
+	m.savePins()
+	m.rebuildSections()
+}
+
+// loadPins reads persisted pins; without a pins path they live in memory only
+func (m *model) loadPins() {
+	path, err := pinsPath()
+	if err != nil {
+		return
+	}
+
+	m.pinsPath = path
+
+	pins, err := loadPins(path)
+	if err != nil {
+		m.warning = fmt.Sprintf("pins: %v", err)
+		return
+	}
+
+	m.pins = pins
+}
+
+// loadSearchHistory reads persisted search queries; without a path they live
+// in memory only
+func (m *model) loadSearchHistory() {
+	path, err := searchHistoryPath()
+	if err != nil {
+		return
+	}
+
+	m.historyPath = path
+
+	history, err := loadSearchHistory(path)
+	if err != nil {
+		m.warning = fmt.Sprintf("search history: %v", err)
+		return
+	}
+
+	m.searchHistory = history
+}
+
+// recordSearch remembers the current search query and persists the history
+func (m *model) recordSearch() {
+	m.historyPos = 0
+
+	history := addSearchHistory(m.searchHistory, m.searchQuery)
+	if len(history) == len(m.searchHistory) {
+		return
+	}
+	m.searchHistory = history
+
+	if m.historyPath == "" {
+		return
+	}
+
+	if err := saveSearchHistory(m.historyPath, m.searchHistory); err != nil {
+		m.warning = fmt.Sprintf("search history: %v", err)
+	}
+}
+
+// recallSearch moves delta entries back (positive) or forward through the
+// search history, restoring the empty query past the newest entry
+func (m *model) recallSearch(delta int) {
+	pos := m.historyPos + delta
+	if pos < 0 || pos > len(m.searchHistory) {
+		return
+	}
+
+	m.historyPos = pos
+	if pos == 0 {
+		m.searchQuery = ""
+	} else {
+		m.searchQuery = m.searchHistory[len(m.searchHistory)-pos]
+	}
+	m.cursor = 0
+	m.filterBySearch()
+}
+
+func (m *model) savePins() {
+	if m.pinsPath == "" {
+		return
+	}
+
+	if err := savePins(m.pinsPath, m.pins); err != nil {
+		m.warning = fmt.Sprintf("pins: %v", err)
+	}
+}
+
+// cycleRuntimeSort advances to the next sort field in runtimeSortOrder
+func (m *model) cycleRuntimeSort() {
+	next := 0
+	for i, field := range runtimeSortOrder {
+		if field == m.runtimeSort {
+			next = (i + 1) % len(runtimeSortOrder)
+			break
 		}
+	}
+	m.runtimeSort = runtimeSortOrder[next]
+	m.rebuildSections()
+}
+
+// rebuildSections re-runs filtering and grouping over the loaded tasks
+func (m *model) rebuildSections() {
+	// Only until the first refresh has loaded every task: the sections must
+	// be rebuilt before this returns
+	if m.allTasks == nil {
+		m.refresh()
+		return
+	}
 
-		allTasks = append(allTasks, tasks...)
+	// Remember the selected task so the cursor can follow it to its new position
+	var selected *Task
+	if active := m.activeTasks(); m.cursor >= 0 && m.cursor < len(active) {
+		selected = active[m.cursor]
 	}
 
 	var sections []QuerySection
 
-	for _, query := range m.queries {
-		filtered := m.filterTasksWithRecent(allTasks, query)
-		groups := groupTasks(filtered, query.GroupBy, query.SortBy, m.vaultPath)
+	for i, query := range m.queries {
+		query = m.viewQuery(query)
+		filtered := m.filterTasksWithRecent(m.allTasks, query)
+		groups := groupTasksBy(filtered, query.GroupBy, query.SortBy, m.vaultPath)
+		if m.reversed {
+			groups = reverseGroups(groups)
+		}
+		if query.CompletedLast {
+			groups = completedLast(groups)
+		}
+
+		hidden := 0
+		if !m.expanded[i] {
+			groups, hidden = limitGroups(groups, query.Limit)
+		}
 
 		sections = append(sections, QuerySection{
 			Name:   query.Name,
 			Query:  query,
+			Index:  i,
 			Groups: groups,
 			Tasks:  filtered,
+			Hidden: hidden,
 		})
 	}
 
+	sections = orderSections(sections, m.sectionOrder)
+	if pinned := m.pinnedSection(); pinned != nil {
+		sections = append([]QuerySection{*pinned}, sections...)
+	}
+
+	if m.focusSection != "" {
+		sections = focusedSections(sections, m.focusSection)
+		// The focused section is gone, e.g. renamed in the query file
+		if len(sections) == 0 {
+			m.focusSection = ""
+			m.rebuildSections()
+			return
+		}
+	}
+
+	// A task matching several queries is listed, and navigable, under each
+	// of them, so m.tasks can hold it more than once. Totals shown to the
+	// user go through countUniqueTasks.
 	var tasks []*Task
 	taskToSection := make(map[*Task]string)
 	taskToGroup := make(map[*Task]string)
@@ -665,6 +1255,7 @@ func (m *model) refreshWithCache() {
 
 	m.sections = sections
 	m.tasks = tasks
+	m.invalidateLines()
 	m.taskToSection = taskToSection
 	m.taskToGroup = taskToGroup
 
@@ -672,7 +1263,18 @@ func (m *model) refreshWithCache() {
 		m.filterBySearch()
 	}
 
-	m.clampCursor(len(m.tasks))
+	active := m.activeTasks()
+	m.clampCursor(len(active))
+
+	if selected != nil {
+		if i := findTask(active, selected); i >= 0 {
+			m.cursor = i
+		}
+	}
+
+	if m.moreSelected && m.moreSectionAt(m.cursor) == nil {
+		m.moreSelected = false
+	}
 
 	// Sync current tab state so tab bar counters are updated
 	if m.tabsEnabled && m.activeTab >= 0 && m.activeTab < len(m.tabs) {
@@ -682,41 +1284,317 @@ func (m *model) refreshWithCache() {
 	}
 }
 
-func (m *model) useInlineEditor() bool {
-	if m.editorMode == "inline" {
-		return true
-	}
-	if m.editorMode == "external" {
-		return false
+// toggleTodayView swaps the queries for the built-in Today query, or
+// restores the saved ones
+func (m *model) toggleTodayView() {
+	if m.todayView {
+		m.queries = m.savedQueries
+		m.savedQueries = nil
+	} else {
+		m.savedQueries = m.queries
+		m.queries = []*Query{todayQuery()}
 	}
-	return os.Getenv("EDITOR") == ""
-}
-
-func (m *model) inputWidth() int {
-	return max(minInputWidth, min(maxInputWidth, m.windowWidth-10))
+	m.todayView = !m.todayView
+	m.expanded = nil
+	m.focusSection = ""
+	m.cursor = 0
+	m.rebuildSections()
 }
 
-func (m *model) clampCursor(length int) {
-	m.cursor = max(0, min(m.cursor, length-1))
-}
+// toggleFocus shows only the section under the cursor, or all sections again
+func (m *model) toggleFocus() {
+	if m.focusSection != "" {
+		m.focusSection = ""
+		m.rebuildSections()
+		return
+	}
 
-func (m *model) toggleAndSave(task *Task) {
-	m.pushUndo(UndoEntry{
-		Type:       OpToggle,
-		FilePath:   task.FilePath,
-		LineNumber: task.LineNumber,
-		WasDone:    task.Done,
-	})
-	task.Toggle()
-	if err := saveTask(task); err != nil {
-		m.err = err
-		m.popUndo() // Rollback on error
+	if m.searching && m.searchQuery != "" {
 		return
 	}
-	m.selfModifiedFiles[task.FilePath] = time.Now()
+
+	if section := m.sectionAt(m.cursor); section != nil && section.Name != "" {
+		m.focusSection = section.Name
+		m.rebuildSections()
+	}
+}
+
+// sectionAt returns the section listing the task at index i
+func (m model) sectionAt(i int) *QuerySection {
+	end := 0
+	for si := range m.sections {
+		for _, group := range m.sections[si].Groups {
+			end += len(group.Tasks)
+		}
+		if i < end {
+			return &m.sections[si]
+		}
+	}
+	return nil
+}
+
+// startCompleteSection asks to mark every open task of the section under the
+// cursor done, including those hidden by a limit
+func (m *model) startCompleteSection() {
+	if m.searching && m.searchQuery != "" {
+		return
+	}
+
+	section := m.sectionAt(m.cursor)
+	if section == nil {
+		return
+	}
+
+	var open []*Task
+	for _, task := range section.Tasks {
+		if !task.Done && !slices.Contains(open, task) {
+			open = append(open, task)
+		}
+	}
+	if len(open) == 0 {
+		m.notice = "nothing left to mark done"
+		return
+	}
+
+	m.completing = open
+	m.completingSection = section.Name
+}
+
+// completeSection marks the confirmed tasks done, writing each file once.
+// One undo entry covers them all, and keeps them visible until refresh. A
+// failed save returns the refresh reloading the files left unwritten.
+func (m *model) completeSection() tea.Cmd {
+	tasks := m.completing
+	m.completing = nil
+	m.completingSection = ""
+
+	toggles := make([]UndoEntry, len(tasks))
+	for i, task := range tasks {
+		toggles[i] = UndoEntry{
+			Type:       OpToggle,
+			FilePath:   task.FilePath,
+			LineNumber: task.LineNumber,
+			WasDone:    task.Done,
+		}
+		task.Toggle()
+	}
+	m.invalidateLines()
+
+	// Files written before a failure stay done and can still be undone
+	changes, err := saveTasks(tasks)
+	written := make(map[string]bool)
+	for _, change := range changes {
+		written[change.Path] = true
+	}
+
+	var saved []UndoEntry
+	for _, toggle := range toggles {
+		if written[toggle.FilePath] {
+			saved = append(saved, toggle)
+			m.selfModifiedFiles[toggle.FilePath] = time.Now()
+		}
+	}
+	if len(saved) > 0 {
+		m.pushUndo(UndoEntry{Type: OpBatch, Entries: saved})
+	}
+
+	if err != nil {
+		// Reload the tasks of the files left unwritten from disk
+		m.saveFailed(err)
+		return m.refreshCmd()
+	}
+	return nil
+}
+
+// focusedSections keeps the sections named name
+func focusedSections(sections []QuerySection, name string) []QuerySection {
+	var focused []QuerySection
+	for _, section := range sections {
+		if section.Name == name {
+			focused = append(focused, section)
+		}
+	}
+	return focused
+}
+
+// moreSectionAt returns the section whose "… N more" line follows the task at
+// index i, or nil when that task isn't the last one shown of a limited section
+func (m model) moreSectionAt(i int) *QuerySection {
+	if m.searching && m.searchQuery != "" {
+		return nil
+	}
+
+	end := 0
+	for si := range m.sections {
+		section := &m.sections[si]
+		for _, group := range section.Groups {
+			end += len(group.Tasks)
+		}
+		if i < end {
+			if section.Hidden > 0 && i == end-1 {
+				return section
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// sectionStarts returns the index of the first listed task of each section
+// that has any
+func (m model) sectionStarts() []int {
+	var starts []int
+
+	index := 0
+	for _, section := range m.sections {
+		count := 0
+		for _, group := range section.Groups {
+			count += len(group.Tasks)
+		}
+		if count > 0 {
+			starts = append(starts, index)
+		}
+		index += count
+	}
+
+	return starts
+}
+
+// jumpSection moves the cursor to the first task of the next (dir > 0) or
+// previous section. Going back from inside a section lands on its own start.
+func (m *model) jumpSection(dir int) {
+	starts := m.sectionStarts()
+
+	if dir > 0 {
+		for _, start := range starts {
+			if start > m.cursor {
+				m.cursor = start
+				return
+			}
+		}
+		return
+	}
+
+	for i := len(starts) - 1; i >= 0; i-- {
+		if starts[i] < m.cursor {
+			m.cursor = starts[i]
+			return
+		}
+	}
+}
+
+// expandMore shows the rest of the section whose "more" line is selected and
+// moves the cursor to the first task that was hidden
+func (m *model) expandMore() {
+	section := m.moreSectionAt(m.cursor)
+	m.moreSelected = false
+	if section == nil {
+		return
+	}
+
+	if m.expanded == nil {
+		m.expanded = make(map[int]bool)
+	}
+	m.expanded[section.Index] = true
+	m.rebuildSections()
+
+	if m.cursor < len(m.tasks)-1 {
+		m.cursor++
+	}
+}
+
+func (m *model) useInlineEditor() bool {
+	if m.editorMode == "inline" {
+		return true
+	}
+	if m.editorMode == "external" {
+		return false
+	}
+	return os.Getenv("EDITOR") == ""
+}
+
+// editorModeName is the editor used by e and a, resolving the $EDITOR fallback
+func (m *model) editorModeName() string {
+	if m.useInlineEditor() {
+		return "inline"
+	}
+	return "external"
+}
+
+// toggleEditorMode switches between inline and $EDITOR editing for the rest
+// of the session; in tabbed mode only the active tab changes
+func (m *model) toggleEditorMode() {
+	if m.useInlineEditor() {
+		m.editorMode = "external"
+	} else {
+		m.editorMode = "inline"
+	}
+	if len(m.tabs) > 0 {
+		m.tabs[m.activeTab].Profile.EditorMode = m.editorMode
+	}
+	m.notice = "editor: " + m.editorMode
+}
+
+func (m *model) inputWidth() int {
+	return max(minInputWidth, min(maxInputWidth, m.windowWidth-10))
+}
+
+func (m *model) clampCursor(length int) {
+	m.cursor = max(0, min(m.cursor, length-1))
+}
+
+func (m *model) toggleAndSave(task *Task) {
+	m.pushUndo(UndoEntry{
+		Type:       OpToggle,
+		FilePath:   task.FilePath,
+		LineNumber: task.LineNumber,
+		WasDone:    task.Done,
+	})
+	previous := *task
+	task.Toggle()
+	m.invalidateLines()
+	if err := saveTask(task); err != nil {
+		*task = previous // Match what's on disk
+		m.saveFailed(err)
+		m.popUndo() // Rollback on error
+		return
+	}
+	m.selfModifiedFiles[task.FilePath] = time.Now()
+}
+
+// saveFailed reports a failed save: a read-only file as a notice the user
+// can carry on from, anything else on the error screen
+func (m *model) saveFailed(err error) {
+	if errors.Is(err, ErrReadOnly) {
+		m.notice = "cannot save: file is read-only"
+		return
+	}
+	m.err = err
+}
+
+// snoozeAndSave moves the task's due date by days and saves it
+func (m *model) snoozeAndSave(task *Task, days int) {
+	m.pushUndo(UndoEntry{
+		Type:            OpDueDateChange,
+		FilePath:        task.FilePath,
+		LineNumber:      task.LineNumber,
+		PreviousDueDate: task.DueDate,
+	})
+	previous := *task
+	task.ShiftDueDate(days, time.Now())
+	m.invalidateLines()
+	if err := saveTask(task); err != nil {
+		*task = previous // Match what's on disk
+		m.saveFailed(err)
+		m.popUndo() // Rollback on error
+		return
+	}
+	m.selfModifiedFiles[task.FilePath] = time.Now()
 }
 
 func (m *model) schedulePrioritySave(task *Task) tea.Cmd {
+	m.invalidateLines()
 	key := taskKey(task)
 	at := time.Now()
 	m.prioritySavePending[key] = at
@@ -765,7 +1643,7 @@ func (m *model) startEdit(task *Task) tea.Cmd {
 		m.editing = true
 		m.editingTask = task
 		m.textInput = textinput.New()
-		m.textInput.SetValue(task.Description)
+		m.textInput.SetValue(task.lineDescription())
 		m.textInput.Focus()
 		m.textInput.CursorEnd()
 		m.textInput.CharLimit = 500
@@ -787,6 +1665,186 @@ func (m *model) startAdd(refTask *Task) tea.Cmd {
 	return openNewTaskInEditor(refTask)
 }
 
+// updateAbout handles keys while the help modal is open: scrolling, the "/"
+// filter and closing
+func (m model) updateAbout(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if key == "ctrl+c" {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if m.aboutFiltering {
+		switch key {
+		case "esc", "ctrl+[":
+			m.aboutFiltering = false
+			m.aboutFilter = ""
+		case "enter":
+			m.aboutFiltering = false
+		case "backspace":
+			if len(m.aboutFilter) > 0 {
+				m.aboutFilter = trimLastRune(m.aboutFilter)
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				m.aboutFilter += key
+			}
+		}
+		m.aboutScroll = 0
+		return m, nil
+	}
+
+	switch {
+	case (key == "esc" || key == "ctrl+[") && m.aboutFilter != "":
+		m.aboutFilter = ""
+		m.aboutScroll = 0
+
+	case key == "esc" || key == "ctrl+[" || m.keys.is(key, actionHelp) || m.keys.is(key, actionQuit):
+		m.aboutOpen = false
+		m.aboutFilter = ""
+		m.aboutScroll = 0
+
+	case key == "/":
+		m.aboutFiltering = true
+
+	case m.keys.is(key, actionUp):
+		m.aboutScroll = max(0, m.aboutScroll-1)
+
+	case m.keys.is(key, actionDown):
+		m.aboutScroll = min(m.aboutScroll+1, m.aboutMaxScroll())
+
+	case key == "g":
+		m.aboutScroll = 0
+
+	case key == "G":
+		m.aboutScroll = m.aboutMaxScroll()
+	}
+
+	return m, nil
+}
+
+// startCreate opens the new task modal, prefilled with the inbox file
+func (m *model) startCreate() {
+	inbox := m.inboxFile
+	if inbox == "" {
+		inbox = defaultInboxFile
+	}
+
+	m.creating = true
+	m.creatingOnFile = false
+	m.creatingHeader = ""
+	m.creatingFile = textinput.New()
+	m.creatingFile.Placeholder = "File..."
+	m.creatingFile.SetValue(inbox)
+	m.creatingFile.CharLimit = 500
+	m.creatingInput = textinput.New()
+	m.creatingInput.Placeholder = "New task description..."
+	m.creatingInput.CharLimit = 500
+	m.creatingInput.Focus()
+}
+
+// startDailyCapture opens the new task modal on today's daily note
+func (m *model) startDailyCapture() {
+	if m.dailyNoteFormat == "" {
+		m.notice = "set daily_note_format to capture to daily notes"
+		return
+	}
+
+	now := time.Now()
+	m.startCreate()
+	m.creatingFile.SetValue(expandDateTokens(m.dailyNoteFormat, now))
+	if m.dailyNoteHeader != "" {
+		m.creatingHeader = expandDateTokens(m.dailyNoteHeader, now)
+	}
+}
+
+// createTargetPath resolves the N modal's file input against the vault
+func (m model) createTargetPath() (string, error) {
+	path, err := expandPath(m.creatingFile.Value())
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", ErrEmptyPath
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	base := m.vaultPath
+	if info, err := os.Stat(base); err == nil && !info.IsDir() {
+		base = filepath.Dir(base)
+	}
+	return filepath.Join(base, path), nil
+}
+
+// updateCreate handles keys while the new task modal is open
+func (m model) updateCreate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+[":
+		m.creating = false
+		return m, nil
+
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "tab", "shift+tab":
+		m.creatingOnFile = !m.creatingOnFile
+		if m.creatingOnFile {
+			m.creatingInput.Blur()
+			m.creatingFile.Focus()
+		} else {
+			m.creatingFile.Blur()
+			m.creatingInput.Focus()
+		}
+		return m, nil
+
+	case "enter":
+		if m.creatingOnFile {
+			m.creatingOnFile = false
+			m.creatingFile.Blur()
+			m.creatingInput.Focus()
+			return m, nil
+		}
+
+		description := strings.TrimSpace(expandDueShorthand(m.creatingInput.Value(), time.Now(), m.scan.format))
+		if description == "" {
+			return m, nil
+		}
+
+		if m.addCreatedDate {
+			description = withCreatedDate(description, m.scan.format)
+		}
+
+		path, err := m.createTargetPath()
+		if err == nil && m.creatingHeader != "" {
+			err = ensureNoteHeader(path, m.creatingHeader)
+		}
+		if err == nil {
+			_, err = appendTask(path, description, m.scan.format)
+		}
+		if err != nil {
+			m.err = err
+		} else {
+			m.selfModifiedFiles[path] = time.Now()
+		}
+
+		m.creating = false
+		cmd := m.refreshCmd()
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	if m.creatingOnFile {
+		m.creatingFile, cmd = m.creatingFile.Update(msg)
+	} else {
+		m.creatingInput, cmd = m.creatingInput.Update(msg)
+	}
+	return m, cmd
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -799,17 +1857,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.err = msg.err
 		}
-		m.refresh()
+		m.notice = msg.diff.String()
+		cmd := m.refreshCmd()
+		return m, cmd
+
+	case revealFinishedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		return m, nil
+
+	case copyFinishedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		return m, nil
+
+	case refreshDoneMsg:
+		m.applyRefresh(msg)
+		if m.nextOpen {
+			m.loadNextContext()
+		}
+		return m, nil
+
+	case duplicatesMsg:
+		m.openDuplicates(msg)
 		return m, nil
 
 	case FileChangeMsg:
-		// Skip self-triggered changes (within 500ms)
-		if t, ok := m.selfModifiedFiles[msg.Path]; ok && time.Since(t) < 500*time.Millisecond {
-			delete(m.selfModifiedFiles, msg.Path)
-			if m.watcher != nil {
-				return m, m.watcher.WatchCmd()
+		// Skip self-triggered changes (within 500ms). The entry is kept for the
+		// whole window since one save can surface as several events.
+		if t, ok := m.selfModifiedFiles[msg.Path]; ok {
+			if time.Since(t) < selfModifiedWindow {
+				if m.watcher != nil {
+					return m, m.watcher.WatchCmd()
+				}
+				return m, nil
 			}
-			return m, nil
+			delete(m.selfModifiedFiles, msg.Path)
 		}
 
 		// Invalidate cache and trigger debounced refresh
@@ -825,8 +1910,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case DebouncedRefreshMsg:
-		m.refreshWithCache()
-		return m, nil
+		cmd := m.refreshCmd()
+		return m, cmd
+
+	case pollMsg:
+		// Results for a tab that is no longer active are dropped
+		if msg.vaultPath != m.vaultPath || m.refreshing {
+			return m, m.pollCmd()
+		}
+
+		changed := false
+		for _, path := range msg.changed {
+			if t, ok := m.selfModifiedFiles[path]; ok && time.Since(t) < selfModifiedWindow {
+				continue
+			}
+			m.cache.Invalidate(path)
+			changed = true
+		}
+		if changed {
+			refresh := m.refreshCmd()
+			return m, tea.Batch(refresh, m.pollCmd())
+		}
+		return m, m.pollCmd()
+
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
 
 	case prioritySaveMsg:
 		latest, ok := m.prioritySavePending[msg.key]
@@ -835,23 +1943,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		delete(m.prioritySavePending, msg.key)
 		if err := saveTask(msg.task); err != nil {
-			m.err = err
-		} else {
-			m.selfModifiedFiles[msg.task.FilePath] = time.Now()
+			m.saveFailed(err)
+			cmd := m.refreshCmd()
+			return m, cmd
 		}
+		m.selfModifiedFiles[msg.task.FilePath] = time.Now()
 		return m, nil
 
 	case tea.KeyMsg:
+		m.notice = ""
+
 		if m.aboutOpen {
-			switch msg.String() {
-			case "esc", "ctrl+[", "q", "?":
-				m.aboutOpen = false
-				return m, nil
-			case "ctrl+c":
-				m.quitting = true
-				return m, tea.Quit
-			}
-			return m, nil
+			return m.updateAbout(msg)
 		}
 
 		if m.editing {
@@ -862,21 +1965,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 
 			case "enter":
-				newValue := m.textInput.Value()
-				if m.editingTask != nil && newValue != m.editingTask.Description {
-					m.editingTask.Description = newValue
+				newValue := expandDueShorthand(m.textInput.Value(), time.Now(), m.scan.format)
+				if m.editingTask != nil && newValue != m.editingTask.lineDescription() {
+					previous := *m.editingTask
+					m.editingTask.Description = joinContinuation(newValue, m.editingTask.Continuation)
 					m.editingTask.Modified = true
 					m.editingTask.rebuildRawLine()
+					m.invalidateLines()
 					if err := saveTask(m.editingTask); err != nil {
-						m.err = err
+						*m.editingTask = previous // Match what's on disk
+						m.saveFailed(err)
 					} else {
 						m.selfModifiedFiles[m.editingTask.FilePath] = time.Now()
 					}
 				}
 				m.editing = false
 				m.editingTask = nil
-				m.refresh()
-				return m, nil
+				cmd := m.refreshCmd()
+				return m, cmd
 
 			case "ctrl+c":
 				m.quitting = true
@@ -893,24 +1999,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch msg.String() {
 			case "y", "Y", "enter", "d", "D":
 				if m.deletingTask != nil {
-					m.pushUndo(UndoEntry{
-						Type:        OpDelete,
-						FilePath:    m.deletingTask.FilePath,
-						LineNumber:  m.deletingTask.LineNumber,
-						DeletedLine: m.deletingTask.RawLine,
-					})
-					filePath := m.deletingTask.FilePath
-					if err := deleteTask(m.deletingTask); err != nil {
-						m.err = err
-						m.popUndo() // Rollback on error
-					} else {
-						m.selfModifiedFiles[filePath] = time.Now()
-					}
+					m.deleteWithUndo(m.deletingTask)
 				}
 				m.deleting = false
 				m.deletingTask = nil
-				m.refresh()
-				return m, nil
+				cmd := m.refreshCmd()
+				return m, cmd
 
 			case "n", "N", "q", "esc", "ctrl+[":
 				m.deleting = false
@@ -924,27 +2018,113 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if m.adding {
+		if m.completing != nil {
 			switch msg.String() {
-			case "esc", "ctrl+[":
-				m.adding = false
-				m.addingRef = nil
+			case "y", "Y", "enter", "X":
+				cmd := m.completeSection()
+				return m, cmd
+
+			case "n", "N", "q", "esc", "ctrl+[":
+				m.completing = nil
+				m.completingSection = ""
 				return m, nil
 
-			case "enter":
-				newValue := strings.TrimSpace(m.addingInput.Value())
-				if m.addingRef != nil && newValue != "" {
-					if _, err := addTask(m.addingRef, newValue); err != nil {
-						m.err = err
-					} else {
-						m.selfModifiedFiles[m.addingRef.FilePath] = time.Now()
-					}
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		if m.creating {
+			return m.updateCreate(msg)
+		}
+
+		if m.warningsOpen {
+			switch msg.String() {
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "esc", "q", "W", "enter":
+				m.warningsOpen = false
+			}
+			return m, nil
+		}
+
+		if m.duplicates != nil {
+			switch msg.String() {
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "esc", "q", "=", "enter":
+				m.duplicates = nil
+			}
+			return m, nil
+		}
+
+		if m.nextOpen {
+			switch msg.String() {
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "esc", "q", "ctrl+n":
+				m.nextOpen = false
+			case "x", "enter", " ":
+				if task, _, _ := m.nextAction(); task != nil {
+					m.toggleAndSave(task)
+				}
+			case "j", "down", "tab":
+				m.nextIndex++
+			case "k", "up", "shift+tab":
+				m.nextIndex--
+			}
+			m.loadNextContext()
+			return m, nil
+		}
+
+		if m.copyMenuOpen {
+			key := msg.String()
+			switch key {
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "esc", "q", "Y":
+				m.copyMenuOpen = false
+				return m, nil
+			}
+			for _, opt := range copyOptions {
+				if key == opt.key && len(m.tasks) > 0 {
+					m.copyMenuOpen = false
+					return m, copyToClipboard(opt.format(m.tasks[m.cursor], m.vaultPath))
 				}
+			}
+			return m, nil
+		}
+
+		if m.adding {
+			switch msg.String() {
+			case "esc", "ctrl+[":
 				m.adding = false
 				m.addingRef = nil
-				m.refresh()
 				return m, nil
 
+			case "enter":
+				newValue := strings.TrimSpace(expandDueShorthand(m.addingInput.Value(), time.Now(), m.scan.format))
+				if m.addingRef != nil && newValue != "" {
+					if m.addCreatedDate {
+						newValue = withCreatedDate(newValue, m.scan.format)
+					}
+					if _, err := addTask(m.addingRef, newValue); err != nil {
+						m.err = err
+					} else {
+						m.selfModifiedFiles[m.addingRef.FilePath] = time.Now()
+					}
+				}
+				m.adding = false
+				m.addingRef = nil
+				cmd := m.refreshCmd()
+				return m, cmd
+
 			case "ctrl+c":
 				m.quitting = true
 				return m, tea.Quit
@@ -956,51 +2136,58 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		if msg.String() == "?" {
+		// Typing a search query takes every key, including the help key
+		if m.keys.is(msg.String(), actionHelp) && (!m.searching || m.searchNavigating) {
 			m.aboutOpen = true
 			return m, nil
 		}
 
 		if m.searching {
 			if m.searchNavigating {
-				switch msg.String() {
-				case "esc", "ctrl+[", "/", "q":
+				key := msg.String()
+
+				if key == "ctrl+c" {
+					m.quitting = true
+					return m, tea.Quit
+				}
+
+				if key == "esc" || key == "ctrl+[" || m.keys.is(key, actionSearch) || m.keys.is(key, actionQuit) {
+					m.recordSearch()
 					m.searching = false
 					m.searchNavigating = false
 					m.searchQuery = ""
 					m.filteredTasks = nil
 					m.cursor = 0
 					return m, nil
+				}
 
-				case "backspace":
+				if key == "backspace" {
 					m.searchNavigating = false
 					return m, nil
+				}
 
-				case "up", "k":
+				switch m.keys.action(key) {
+				case actionUp:
 					if m.cursor > 0 {
 						m.cursor--
 					}
 					return m, nil
 
-				case "down", "j":
+				case actionDown:
 					tasks := m.activeTasks()
 					if m.cursor < len(tasks)-1 {
 						m.cursor++
 					}
 					return m, nil
 
-				case "ctrl+c":
-					m.quitting = true
-					return m, tea.Quit
-
-				case "enter", " ", "x":
+				case actionToggle:
 					tasks := m.activeTasks()
 					if len(tasks) > 0 && m.cursor < len(tasks) {
 						m.toggleAndSave(tasks[m.cursor])
 					}
 					return m, nil
 
-				case "e":
+				case actionEdit:
 					tasks := m.activeTasks()
 					if len(tasks) > 0 && m.cursor < len(tasks) {
 						task := tasks[m.cursor]
@@ -1008,31 +2195,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 					return m, nil
 
-				case "d":
+				case actionDelete:
 					tasks := m.activeTasks()
 					if len(tasks) > 0 && m.cursor < len(tasks) {
-						m.deleting = true
-						m.deletingTask = tasks[m.cursor]
+						cmd := m.startDelete(tasks[m.cursor])
+						return m, cmd
 					}
 					return m, nil
 
-				case "a", "n":
+				case actionAdd:
 					tasks := m.activeTasks()
 					if len(tasks) > 0 && m.cursor < len(tasks) {
 						task := tasks[m.cursor]
 						return m, m.startAdd(task)
 					}
 					return m, nil
+				}
 
-				case "u":
-					m.undoLastOperation()
-					return m, nil
+				if key == "u" {
+					cmd := m.undoLastOperation()
+					return m, cmd
 				}
 				return m, nil
 			}
 
 			switch msg.String() {
 			case "esc", "ctrl+[":
+				m.recordSearch()
 				m.searching = false
 				m.searchQuery = ""
 				m.filteredTasks = nil
@@ -1040,6 +2229,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 
 			case "enter":
+				m.recordSearch()
 				if len(m.filteredTasks) > 0 {
 					m.searchNavigating = true
 				} else if m.searchQuery == "" {
@@ -1049,7 +2239,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case "backspace":
 				if len(m.searchQuery) > 0 {
-					m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
+					m.searchQuery = trimLastRune(m.searchQuery)
+					m.historyPos = 0
 					m.filterBySearch()
 				}
 				return m, nil
@@ -1059,12 +2250,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Quit
 
 			case "up":
-				if m.cursor > 0 {
+				// An empty or recalled query steps through history, like a shell
+				if m.searchQuery == "" || m.historyPos > 0 {
+					m.recallSearch(1)
+				} else if m.cursor > 0 {
 					m.cursor--
 				}
 				return m, nil
 
 			case "down":
+				if m.historyPos > 0 {
+					m.recallSearch(-1)
+					return m, nil
+				}
 				tasks := m.activeTasks()
 				if m.cursor < len(tasks)-1 {
 					m.cursor++
@@ -1072,7 +2270,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 
 			default:
-				if len(msg.String()) == 1 {
+				if utf8.RuneCountInString(msg.String()) == 1 {
+					m.historyPos = 0
 					m.searchQuery += msg.String()
 					m.filterBySearch()
 				}
@@ -1080,64 +2279,195 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		switch msg.String() {
-		case "q", "ctrl+c":
+		key := msg.String()
+		action := m.keys.action(key)
+
+		// A leading 0 keeps its priority binding
+		if isDigit(key) && action == "" && (key != "0" || m.countPrefix != "") {
+			m.countPrefix += key
+			return m, nil
+		}
+		count := m.countPrefix
+		m.countPrefix = ""
+
+		// The "more" line only expands; other keys act on the task above it
+		if m.moreSelected {
+			switch action {
+			case actionToggle:
+				m.expandMore()
+				return m, nil
+			case actionUp:
+				m.moreSelected = false
+				return m, nil
+			case actionDown:
+				if m.cursor < len(m.tasks)-1 {
+					m.moreSelected = false
+					m.cursor++
+				}
+				return m, nil
+			}
+			m.moreSelected = false
+		}
+
+		switch action {
+		case actionQuit:
 			m.quitting = true
 			return m, tea.Quit
 
-		case "/":
+		case actionSearch:
 			m.searching = true
 			m.searchQuery = ""
+			m.historyPos = 0
 			m.filteredTasks = nil
 			m.cursor = 0
 
-		case "up", "k":
+		case actionUp:
 			if m.cursor > 0 {
 				m.cursor--
+				m.moreSelected = m.moreSectionAt(m.cursor) != nil
 			}
 
-		case "down", "j":
-			if m.cursor < len(m.tasks)-1 {
+		case actionDown:
+			if m.moreSectionAt(m.cursor) != nil {
+				m.moreSelected = true
+			} else if m.cursor < len(m.tasks)-1 {
 				m.cursor++
 			}
 
-		case "enter", " ", "x":
+		case actionToggle:
 			if len(m.tasks) > 0 {
 				m.toggleAndSave(m.tasks[m.cursor])
 			}
 
+		case actionRefresh:
+			// Clear undo stack so done tasks are hidden
+			m.undoStack = make([]UndoEntry, 0)
+			cmd := m.refreshCmd()
+			return m, cmd
+
+		case actionEdit:
+			if len(m.tasks) > 0 {
+				task := m.tasks[m.cursor]
+				return m, m.startEdit(task)
+			}
+
+		case actionDelete:
+			if len(m.tasks) > 0 {
+				cmd := m.startDelete(m.tasks[m.cursor])
+				return m, cmd
+			}
+
+		case actionAdd:
+			if len(m.tasks) > 0 {
+				task := m.tasks[m.cursor]
+				return m, m.startAdd(task)
+			}
+		}
+
+		// Remapped actions win over the fixed bindings below
+		if action != "" {
+			return m, nil
+		}
+
+		switch key {
+		case "ctrl+c":
+			m.quitting = true
+			return m, tea.Quit
+
+		case "ctrl+e":
+			m.toggleEditorMode()
+
 		case "g":
 			m.cursor = 0
 
+		case "%":
+			if percent, err := strconv.Atoi(count); err == nil {
+				m.jumpPercent(percent)
+			}
+
 		case "G":
 			if len(m.tasks) > 0 {
 				m.cursor = len(m.tasks) - 1
 			}
 
-		case "r":
-			// Clear undo stack so done tasks are hidden
-			m.undoStack = make([]UndoEntry, 0)
-			m.refresh()
+		case "}":
+			m.jumpSection(1)
+
+		case "{":
+			m.jumpSection(-1)
 
 		case "u":
-			m.undoLastOperation()
+			cmd := m.undoLastOperation()
+			return m, cmd
+
+		case "H":
+			m.showDone = !m.showDone
+			m.rebuildSections()
+
+		case "s":
+			m.cycleRuntimeSort()
+
+		case "R":
+			m.reversed = !m.reversed
+			m.rebuildSections()
+
+		case "T":
+			m.toggleTodayView()
+
+		case "F":
+			m.toggleFocus()
 
-		case "e":
+		case "X":
+			m.startCompleteSection()
+
+		case "N":
+			m.startCreate()
+
+		case "D":
+			m.startDailyCapture()
+
+		case "h":
+			m.scrollHorizontal(-hScrollStep)
+
+		case "l":
+			m.scrollHorizontal(hScrollStep)
+
+		case "*":
 			if len(m.tasks) > 0 {
-				task := m.tasks[m.cursor]
-				return m, m.startEdit(task)
+				m.togglePin(m.tasks[m.cursor])
+			}
+
+		case "W":
+			if len(m.parseWarnings) > 0 {
+				m.warningsOpen = true
 			}
 
-		case "d":
+		case "=":
+			return m, m.duplicatesCmd()
+
+		case "ctrl+n":
+			m.nextOpen = true
+			m.nextIndex = 0
+			m.loadNextContext()
+
+		case "f":
 			if len(m.tasks) > 0 {
-				m.deleting = true
-				m.deletingTask = m.tasks[m.cursor]
+				return m, revealInFileManager(m.tasks[m.cursor])
 			}
 
-		case "a", "n":
+		case "Y":
 			if len(m.tasks) > 0 {
-				task := m.tasks[m.cursor]
-				return m, m.startAdd(task)
+				m.copyMenuOpen = true
+			}
+
+		case "ctrl+y":
+			if len(m.tasks) > 0 {
+				return m, copyToClipboard(toMarkdown(m.tasks[m.cursor], true))
+			}
+
+		case ">", "<", "]", "[":
+			if len(m.tasks) > 0 {
+				m.snoozeAndSave(m.tasks[m.cursor], snoozeDays[key])
 			}
 
 		case "+":
@@ -1183,505 +2513,685 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// viewLine represents a renderable line with its associated task index
-type viewLine struct {
-	content   string
-	taskIndex int
-}
-
-func (m model) View() string {
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v\n\nPress q to quit.", m.err)
+// handleMouse moves the cursor on clicks and scrolls, toggling when the checkbox is clicked
+func (m model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	// Clicks don't reach the list under a modal
+	if m.err != nil || m.aboutOpen || m.editing || m.deleting || m.completing != nil || m.adding || m.creating || m.nextOpen ||
+		m.copyMenuOpen || m.warningsOpen || m.duplicates != nil {
+		return m, nil
 	}
 
-	if m.quitting {
-		return "Goodbye!\n"
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
 	}
 
-	if m.aboutOpen {
-		sha := strings.TrimSpace(buildSHA)
-		if sha == "" {
-			sha = "unknown"
-		}
-
-		versionLine := fmt.Sprintf("ot v%s (%s)", strings.TrimSpace(version), sha)
-
-		type helpItem struct {
-			keys string
-			desc string
-		}
-		type helpSection struct {
-			title string
-			items []helpItem
-		}
-
-		sectionsFull := []helpSection{
-			{title: "Navigation", items: []helpItem{
-				{keys: "↑/k", desc: "move up"},
-				{keys: "↓/j", desc: "move down"},
-				{keys: "g", desc: "top"},
-				{keys: "G", desc: "bottom"},
-			}},
-			{title: "Tasks", items: []helpItem{
-				{keys: "enter/space/x", desc: "toggle done"},
-				{keys: "a/n", desc: "add after"},
-				{keys: "e", desc: "edit"},
-				{keys: "d", desc: "delete"},
-				{keys: "u", desc: "undo"},
-				{keys: "r", desc: "refresh"},
-			}},
-			{title: "Priority", items: []helpItem{
-				{keys: "+", desc: "increase"},
-				{keys: "-", desc: "decrease"},
-				{keys: "!", desc: "highest"},
-				{keys: "0", desc: "normal"},
-			}},
-			{title: "Search", items: []helpItem{
-				{keys: "/", desc: "start search"},
-				{keys: "type", desc: "filter"},
-				{keys: "enter", desc: "lock results"},
-				{keys: "↑/↓", desc: "move"},
-				{keys: "backspace", desc: "edit query"},
-				{keys: "esc", desc: "exit"},
-			}},
-			{title: "General", items: []helpItem{
-				{keys: "?", desc: "help"},
-				{keys: "q/ctrl+c", desc: "quit"},
-			}},
-		}
-
-		sectionsCompact := []helpSection{
-			{title: "Navigation", items: []helpItem{
-				{keys: "↑/k", desc: "up"},
-				{keys: "↓/j", desc: "down"},
-				{keys: "g", desc: "top"},
-				{keys: "G", desc: "bottom"},
-			}},
-			{title: "Tasks", items: []helpItem{
-				{keys: "enter/space/x", desc: "toggle"},
-				{keys: "a/n", desc: "add"},
-				{keys: "e", desc: "edit"},
-				{keys: "d", desc: "delete"},
-				{keys: "u", desc: "undo"},
-			}},
-			{title: "Priority", items: []helpItem{
-				{keys: "+", desc: "up"},
-				{keys: "-", desc: "down"},
-				{keys: "!", desc: "top"},
-				{keys: "0", desc: "normal"},
-			}},
-			{title: "Search", items: []helpItem{
-				{keys: "/", desc: "search"},
-				{keys: "esc", desc: "exit"},
-			}},
-			{title: "General", items: []helpItem{
-				{keys: "?", desc: "help"},
-				{keys: "q/ctrl+c", desc: "quit"},
-			}},
-		}
-
-		sectionsTiny := []helpSection{
-			{title: "Navigation", items: []helpItem{
-				{keys: "↑/k", desc: "up"},
-				{keys: "↓/j", desc: "down"},
-			}},
-			{title: "Tasks", items: []helpItem{
-				{keys: "enter/space", desc: "toggle"},
-				{keys: "a", desc: "add"},
-				{keys: "e", desc: "edit"},
-			}},
-			{title: "Search", items: []helpItem{
-				{keys: "/", desc: "search"},
-			}},
-			{title: "General", items: []helpItem{
-				{keys: "?", desc: "help"},
-				{keys: "q", desc: "quit"},
-			}},
-		}
-
-		if m.tabsEnabled && len(m.tabs) > 1 {
-			sectionsFull = append(sectionsFull, helpSection{
-				title: "Tabs",
-				items: []helpItem{
-					{keys: "tab", desc: "next"},
-					{keys: "shift+tab", desc: "prev"},
-				},
-			})
-			sectionsCompact = append(sectionsCompact, helpSection{
-				title: "Tabs",
-				items: []helpItem{
-					{keys: "tab", desc: "next"},
-					{keys: "shift+tab", desc: "prev"},
-				},
-			})
+	tasks := m.activeTasks()
+	m.moreSelected = false
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		if m.cursor > 0 {
+			m.cursor--
 		}
 
-		type helpRenderMode struct {
-			sections       []helpSection
-			showByline     bool
-			showFooter     bool
-			headerGapLines int
-			maxCols        int
-			sectionSpacing int
-			itemsPerLine   int
+	case tea.MouseButtonWheelDown:
+		if m.cursor < len(tasks)-1 {
+			m.cursor++
 		}
 
-		renderHelpBody := func(width int, bodyHeight int, mode helpRenderMode) (string, bool) {
-			if width <= 0 {
-				width = 1
-			}
-			const gap = 4
-			const minColWidth = 32
-			maxCols := min(3, mode.maxCols)
+	case tea.MouseButtonLeft:
+		line, ok := m.lineAtRow(msg.Y)
+		if ok && line.more {
+			m.cursor = line.moreAfter
+			m.expandMore()
+			return m, nil
+		}
+		if !ok || line.taskIndex < 0 || line.taskIndex >= len(tasks) {
+			return m, nil
+		}
 
-			possibleCols := 1
-			for c := maxCols; c >= 2; c-- {
-				if width >= c*minColWidth+gap*(c-1) {
-					possibleCols = c
-					break
-				}
-			}
+		m.cursor = line.taskIndex
 
-			maxKeyWidth := 0
-			for _, sec := range mode.sections {
-				for _, item := range sec.items {
-					maxKeyWidth = max(maxKeyWidth, lipgloss.Width(item.keys))
-				}
-			}
+		if msg.X >= line.prefixWidth && msg.X < line.prefixWidth+checkboxClickWidth {
+			m.toggleAndSave(tasks[line.taskIndex])
+		}
+	}
 
-			type layoutConfig struct {
-				cols           int
-				colWidths      []int
-				itemsPerLine   int
-				sectionSpacing int
-			}
-			type layoutResult struct {
-				cfg         layoutConfig
-				assignments [][]helpSection
-				height      int
-			}
+	return m, nil
+}
 
-			layout := func(cols int, itemsPerLine int, sectionSpacing int) layoutResult {
-				colWidths := make([]int, cols)
-				usable := width - gap*(cols-1)
-				base := max(1, usable/cols)
-				extra := max(0, usable%cols)
-				for i := 0; i < cols; i++ {
-					colWidths[i] = base
-					if i < extra {
-						colWidths[i]++
-					}
-				}
+// viewLine represents a renderable line with its associated task index
+type viewLine struct {
+	content     string
+	taskIndex   int
+	prefixWidth int  // columns before the rendered checkbox
+	more        bool // "… N more" line of a limited section
+	moreAfter   int  // for a more line, the index of the task above it
+	hidden      int  // for a more line, the tasks it stands for
+	// Needed to render a task line again when it gets selected
+	task   *Task
+	indent string
+	query  *Query
+}
 
-				assignments := make([][]helpSection, cols)
-				colHeights := make([]int, cols)
+// lineCache holds the section lines rendered without a selection. Copies of
+// the model share it, so View can fill it even though it can't change the
+// model.
+type lineCache struct {
+	valid bool
+	key   lineCacheKey
+	lines []viewLine
+}
 
-				estimateSecHeight := func(sec helpSection) int {
-					itemLines := (len(sec.items) + itemsPerLine - 1) / itemsPerLine
-					return 1 + itemLines
-				}
+// lineCacheKey is the layout cached lines were rendered for; lines are
+// rendered again when it changes
+type lineCacheKey struct {
+	sections *QuerySection
+	count    int
+	width    int
+	short    bool
+	wrap     bool
+	noColor  bool
+	day      int // Date badges are relative to today
+}
 
-				for _, sec := range mode.sections {
-					best := 0
-					for i := 1; i < cols; i++ {
-						if colHeights[i] < colHeights[best] {
-							best = i
-						}
-					}
-					assignments[best] = append(assignments[best], sec)
-					colHeights[best] += estimateSecHeight(sec)
-					if sectionSpacing > 0 {
-						colHeights[best] += sectionSpacing
-					}
-				}
+// aboutLayout decides whether the help modal is boxed and the rows and
+// columns available to its scrollable content
+func (m model) aboutLayout() (boxed bool, width, height int) {
+	// Prefer a full-screen boxed dialog when there's enough room; otherwise fall back
+	// to a plain, scrollable view (still auto-layouts columns by width).
+	const boxFrameW = 6 // border (2) + horizontal padding (4)
+	const boxFrameH = 4 // border (2) + vertical padding (2)
+	boxed = m.windowWidth >= boxFrameW+40 && m.windowHeight >= boxFrameH+12
+
+	width, height = max(1, m.windowWidth), max(1, m.windowHeight)
+	if boxed {
+		width, height = max(1, m.windowWidth-boxFrameW), max(1, m.windowHeight-boxFrameH)
+	}
 
-				height := 0
-				for i := 0; i < cols; i++ {
-					h := 0
-					for sidx, sec := range assignments[i] {
-						itemLines := (len(sec.items) + itemsPerLine - 1) / itemsPerLine
-						h += 1 + itemLines
-						if sectionSpacing > 0 && sidx != len(assignments[i])-1 {
-							h += sectionSpacing
-						}
-					}
-					height = max(height, h)
-				}
+	// The filter prompt takes a row above the content
+	if m.aboutFiltering || m.aboutFilter != "" {
+		height = max(1, height-1)
+	}
 
-				return layoutResult{
-					cfg: layoutConfig{
-						cols:           cols,
-						colWidths:      colWidths,
-						itemsPerLine:   itemsPerLine,
-						sectionSpacing: sectionSpacing,
-					},
-					assignments: assignments,
-					height:      height,
-				}
-			}
+	return boxed, width, height
+}
 
-			candidateCols := []int{possibleCols}
-			if possibleCols == 3 {
-				candidateCols = []int{3, 2, 1}
-			} else if possibleCols == 2 {
-				candidateCols = []int{2, 1}
-			}
+// aboutContent renders the help modal's content for the given size; it may be
+// taller than height when the bindings don't fit
+func (m model) aboutContent(width, height int) string {
+	sha := strings.TrimSpace(buildSHA)
+	if sha == "" {
+		sha = "unknown"
+	}
+
+	versionLine := fmt.Sprintf("ot v%s (%s)", strings.TrimSpace(version), sha)
+
+	sectionsFull := []helpSection{
+		{title: "Navigation", items: []helpItem{
+			{keys: m.keys.label(actionUp), desc: "move up"},
+			{keys: m.keys.label(actionDown), desc: "move down"},
+			{keys: "g", desc: "top"},
+			{keys: "G", desc: "bottom"},
+			{keys: "N%", desc: "N percent down"},
+			{keys: "{/}", desc: "prev/next section"},
+		}},
+		{title: "Tasks", items: []helpItem{
+			{keys: m.keys.label(actionToggle), desc: "toggle done"},
+			{keys: m.keys.label(actionAdd), desc: "add after"},
+			{keys: "N", desc: "new task in file"},
+			{keys: "D", desc: "add to daily note"},
+			{keys: m.keys.label(actionEdit), desc: "edit"},
+			{keys: "ctrl+e", desc: "editor: " + m.editorModeName()},
+			{keys: m.keys.label(actionDelete), desc: "delete"},
+			{keys: "u", desc: "undo"},
+			{keys: m.keys.label(actionRefresh), desc: "refresh"},
+			{keys: "H", desc: "show/hide done"},
+			{keys: "s", desc: "cycle sort"},
+			{keys: "R", desc: "reverse order"},
+			{keys: "T", desc: "today view"},
+			{keys: "F", desc: "focus section"},
+			{keys: "ctrl+n", desc: "next action"},
+			{keys: "X", desc: "mark section done"},
+			{keys: "*", desc: "pin/unpin"},
+			{keys: "f", desc: "reveal in file manager"},
+			{keys: "Y", desc: "copy menu"},
+			{keys: "ctrl+y", desc: "copy as checkbox"},
+			{keys: "h/l", desc: "scroll line"},
+		}},
+		{title: "Priority", items: []helpItem{
+			{keys: "+", desc: "increase"},
+			{keys: "-", desc: "decrease"},
+			{keys: "!", desc: "highest"},
+			{keys: "0", desc: "normal"},
+		}},
+		{title: "Due date", items: []helpItem{
+			{keys: ">/<", desc: "+1/-1 day"},
+			{keys: "]/[", desc: "+1/-1 week"},
+		}},
+		{title: "Search", items: []helpItem{
+			{keys: m.keys.label(actionSearch), desc: "start search"},
+			{keys: "type", desc: "filter"},
+			{keys: "enter", desc: "lock results"},
+			{keys: "↑/↓", desc: "move"},
+			{keys: "↑/↓ (empty)", desc: "history"},
+			{keys: "backspace", desc: "edit query"},
+			{keys: "esc", desc: "exit"},
+		}},
+		{title: "General", items: []helpItem{
+			{keys: m.keys.label(actionHelp), desc: "help"},
+			{keys: "W", desc: "parse warnings"},
+			{keys: "=", desc: "duplicate tasks"},
+			{keys: m.keys.label(actionQuit), desc: "quit"},
+		}},
+	}
+
+	sectionsCompact := []helpSection{
+		{title: "Navigation", items: []helpItem{
+			{keys: m.keys.label(actionUp), desc: "up"},
+			{keys: m.keys.label(actionDown), desc: "down"},
+			{keys: "g", desc: "top"},
+			{keys: "G", desc: "bottom"},
+		}},
+		{title: "Tasks", items: []helpItem{
+			{keys: m.keys.label(actionToggle), desc: "toggle"},
+			{keys: m.keys.label(actionAdd), desc: "add"},
+			{keys: m.keys.label(actionEdit), desc: "edit"},
+			{keys: m.keys.label(actionDelete), desc: "delete"},
+			{keys: "u", desc: "undo"},
+		}},
+		{title: "Priority", items: []helpItem{
+			{keys: "+", desc: "up"},
+			{keys: "-", desc: "down"},
+			{keys: "!", desc: "top"},
+			{keys: "0", desc: "normal"},
+		}},
+		{title: "Search", items: []helpItem{
+			{keys: m.keys.label(actionSearch), desc: "search"},
+			{keys: "esc", desc: "exit"},
+		}},
+		{title: "General", items: []helpItem{
+			{keys: m.keys.label(actionHelp), desc: "help"},
+			{keys: m.keys.label(actionQuit), desc: "quit"},
+		}},
+	}
+
+	sectionsTiny := []helpSection{
+		{title: "Navigation", items: []helpItem{
+			{keys: m.keys.label(actionUp), desc: "up"},
+			{keys: m.keys.label(actionDown), desc: "down"},
+		}},
+		{title: "Tasks", items: []helpItem{
+			{keys: m.keys.label(actionToggle), desc: "toggle"},
+			{keys: m.keys.label(actionAdd), desc: "add"},
+			{keys: m.keys.label(actionEdit), desc: "edit"},
+		}},
+		{title: "Search", items: []helpItem{
+			{keys: m.keys.label(actionSearch), desc: "search"},
+		}},
+		{title: "General", items: []helpItem{
+			{keys: m.keys.label(actionHelp), desc: "help"},
+			{keys: m.keys.label(actionQuit), desc: "quit"},
+		}},
+	}
+
+	if m.tabsEnabled && len(m.tabs) > 1 {
+		sectionsFull = append(sectionsFull, helpSection{
+			title: "Tabs",
+			items: []helpItem{
+				{keys: "tab", desc: "next"},
+				{keys: "shift+tab", desc: "prev"},
+			},
+		})
+		sectionsCompact = append(sectionsCompact, helpSection{
+			title: "Tabs",
+			items: []helpItem{
+				{keys: "tab", desc: "next"},
+				{keys: "shift+tab", desc: "prev"},
+			},
+		})
+	}
+
+	if m.aboutFilter != "" {
+		sectionsFull = filterHelpSections(sectionsFull, m.aboutFilter)
+		sectionsCompact = filterHelpSections(sectionsCompact, m.aboutFilter)
+		sectionsTiny = filterHelpSections(sectionsTiny, m.aboutFilter)
+	}
+
+	type helpRenderMode struct {
+		sections       []helpSection
+		showByline     bool
+		showFooter     bool
+		headerGapLines int
+		maxCols        int
+		sectionSpacing int
+		itemsPerLine   int
+	}
 
-			candidateItemsPerLine := []int{mode.itemsPerLine}
-			if mode.itemsPerLine == 1 {
-				candidateItemsPerLine = []int{1, 2}
+	renderHelpBody := func(width int, bodyHeight int, mode helpRenderMode) (string, bool) {
+		if width <= 0 {
+			width = 1
+		}
+		const gap = 4
+		const minColWidth = 32
+		maxCols := min(3, mode.maxCols)
+
+		possibleCols := 1
+		for c := maxCols; c >= 2; c-- {
+			if width >= c*minColWidth+gap*(c-1) {
+				possibleCols = c
+				break
 			}
+		}
 
-			candidateSectionSpacing := []int{mode.sectionSpacing}
-			if mode.sectionSpacing > 0 {
-				candidateSectionSpacing = []int{mode.sectionSpacing, 0}
+		maxKeyWidth := 0
+		for _, sec := range mode.sections {
+			for _, item := range sec.items {
+				maxKeyWidth = max(maxKeyWidth, lipgloss.Width(item.keys))
 			}
+		}
 
-			var bestFit *layoutResult
-			var bestAny *layoutResult
+		type layoutConfig struct {
+			cols           int
+			colWidths      []int
+			itemsPerLine   int
+			sectionSpacing int
+		}
+		type layoutResult struct {
+			cfg         layoutConfig
+			assignments [][]helpSection
+			height      int
+		}
 
-			betterReadable := func(a, b layoutResult) bool {
-				// Prefer more spacing, fewer items per line, more columns.
-				if a.cfg.sectionSpacing != b.cfg.sectionSpacing {
-					return a.cfg.sectionSpacing > b.cfg.sectionSpacing
-				}
-				if a.cfg.itemsPerLine != b.cfg.itemsPerLine {
-					return a.cfg.itemsPerLine < b.cfg.itemsPerLine
+		layout := func(cols int, itemsPerLine int, sectionSpacing int) layoutResult {
+			colWidths := make([]int, cols)
+			usable := width - gap*(cols-1)
+			base := max(1, usable/cols)
+			extra := max(0, usable%cols)
+			for i := 0; i < cols; i++ {
+				colWidths[i] = base
+				if i < extra {
+					colWidths[i]++
 				}
-				return a.cfg.cols > b.cfg.cols
 			}
 
-			for _, cols := range candidateCols {
-				for _, itemsPerLine := range candidateItemsPerLine {
-					if itemsPerLine == 2 && width < 60 {
-						continue
-					}
-					for _, sectionSpacing := range candidateSectionSpacing {
-						res := layout(cols, itemsPerLine, sectionSpacing)
+			assignments := make([][]helpSection, cols)
+			colHeights := make([]int, cols)
 
-						if bestAny == nil || res.height < bestAny.height || (res.height == bestAny.height && betterReadable(res, *bestAny)) {
-							copyRes := res
-							bestAny = &copyRes
-						}
+			estimateSecHeight := func(sec helpSection) int {
+				itemLines := (len(sec.items) + itemsPerLine - 1) / itemsPerLine
+				return 1 + itemLines
+			}
 
-						if res.height <= bodyHeight {
-							if bestFit == nil || betterReadable(res, *bestFit) {
-								copyRes := res
-								bestFit = &copyRes
-							}
-						}
+			for _, sec := range mode.sections {
+				best := 0
+				for i := 1; i < cols; i++ {
+					if colHeights[i] < colHeights[best] {
+						best = i
 					}
 				}
+				assignments[best] = append(assignments[best], sec)
+				colHeights[best] += estimateSecHeight(sec)
+				if sectionSpacing > 0 {
+					colHeights[best] += sectionSpacing
+				}
 			}
 
-			chosen := bestFit
-			fits := true
-			if chosen == nil {
-				chosen = bestAny
-				fits = false
+			height := 0
+			for i := 0; i < cols; i++ {
+				h := 0
+				for sidx, sec := range assignments[i] {
+					itemLines := (len(sec.items) + itemsPerLine - 1) / itemsPerLine
+					h += 1 + itemLines
+					if sectionSpacing > 0 && sidx != len(assignments[i])-1 {
+						h += sectionSpacing
+					}
+				}
+				height = max(height, h)
 			}
 
-			minWidth := chosen.cfg.colWidths[0]
-			for _, w := range chosen.cfg.colWidths[1:] {
-				minWidth = min(minWidth, w)
+			return layoutResult{
+				cfg: layoutConfig{
+					cols:           cols,
+					colWidths:      colWidths,
+					itemsPerLine:   itemsPerLine,
+					sectionSpacing: sectionSpacing,
+				},
+				assignments: assignments,
+				height:      height,
 			}
-			keyWidth := min(maxKeyWidth, max(6, minWidth-2))
+		}
 
-			renderColumn := func(colWidth int, secs []helpSection) []string {
-				pad := lipgloss.NewStyle().Width(colWidth)
+		candidateCols := []int{possibleCols}
+		if possibleCols == 3 {
+			candidateCols = []int{3, 2, 1}
+		} else if possibleCols == 2 {
+			candidateCols = []int{2, 1}
+		}
 
-				renderItem := func(keys, desc string, keyW int, descW int) string {
-					k := helpDialogKeyStyle.Width(keyW).Render(keys)
-					d := helpDialogDescStyle.Width(descW).Render(desc)
-					return k + " " + d
-				}
+		candidateItemsPerLine := []int{mode.itemsPerLine}
+		if mode.itemsPerLine == 1 {
+			candidateItemsPerLine = []int{1, 2}
+		}
 
-				var lines []string
-				for si, sec := range secs {
-					lines = append(lines, pad.Render(helpDialogHeaderStyle.Render(sec.title)))
+		candidateSectionSpacing := []int{mode.sectionSpacing}
+		if mode.sectionSpacing > 0 {
+			candidateSectionSpacing = []int{mode.sectionSpacing, 0}
+		}
 
-					if chosen.cfg.itemsPerLine == 1 {
-						descWidth := max(1, colWidth-keyWidth-1)
-						for _, item := range sec.items {
-							lines = append(lines, pad.Render(renderItem(item.keys, item.desc, keyWidth, descWidth)))
-						}
-					} else {
-						const innerGap = 2
-						leftW := (colWidth - innerGap) / 2
-						rightW := colWidth - innerGap - leftW
+		var bestFit *layoutResult
+		var bestAny *layoutResult
 
-						blockKeyW := min(keyWidth, max(4, leftW-2))
-						leftDescW := max(1, leftW-blockKeyW-1)
-						rightKeyW := min(keyWidth, max(4, rightW-2))
-						rightDescW := max(1, rightW-rightKeyW-1)
+		betterReadable := func(a, b layoutResult) bool {
+			// Prefer more spacing, fewer items per line, more columns.
+			if a.cfg.sectionSpacing != b.cfg.sectionSpacing {
+				return a.cfg.sectionSpacing > b.cfg.sectionSpacing
+			}
+			if a.cfg.itemsPerLine != b.cfg.itemsPerLine {
+				return a.cfg.itemsPerLine < b.cfg.itemsPerLine
+			}
+			return a.cfg.cols > b.cfg.cols
+		}
 
-						renderBlock := func(item helpItem, blockW int, kW int, dW int) string {
-							return lipgloss.NewStyle().Width(blockW).Render(renderItem(item.keys, item.desc, kW, dW))
-						}
+		for _, cols := range candidateCols {
+			for _, itemsPerLine := range candidateItemsPerLine {
+				if itemsPerLine == 2 && width < 60 && len(candidateItemsPerLine) > 1 {
+					continue
+				}
+				for _, sectionSpacing := range candidateSectionSpacing {
+					res := layout(cols, itemsPerLine, sectionSpacing)
 
-						for i := 0; i < len(sec.items); i += 2 {
-							left := renderBlock(sec.items[i], leftW, blockKeyW, leftDescW)
-							right := lipgloss.NewStyle().Width(rightW).Render("")
-							if i+1 < len(sec.items) {
-								right = renderBlock(sec.items[i+1], rightW, rightKeyW, rightDescW)
-							}
-							lines = append(lines, pad.Render(left+strings.Repeat(" ", innerGap)+right))
-						}
+					if bestAny == nil || res.height < bestAny.height || (res.height == bestAny.height && betterReadable(res, *bestAny)) {
+						copyRes := res
+						bestAny = &copyRes
 					}
 
-					if chosen.cfg.sectionSpacing > 0 && si != len(secs)-1 {
-						for i := 0; i < chosen.cfg.sectionSpacing; i++ {
-							lines = append(lines, pad.Render(""))
+					if res.height <= bodyHeight {
+						if bestFit == nil || betterReadable(res, *bestFit) {
+							copyRes := res
+							bestFit = &copyRes
 						}
 					}
 				}
-				return lines
-			}
-
-			renderedCols := make([][]string, chosen.cfg.cols)
-			maxLines := 0
-			for i := 0; i < chosen.cfg.cols; i++ {
-				renderedCols[i] = renderColumn(chosen.cfg.colWidths[i], chosen.assignments[i])
-				maxLines = max(maxLines, len(renderedCols[i]))
-			}
-
-			for i := 0; i < chosen.cfg.cols; i++ {
-				pad := lipgloss.NewStyle().Width(chosen.cfg.colWidths[i])
-				for len(renderedCols[i]) < maxLines {
-					renderedCols[i] = append(renderedCols[i], pad.Render(""))
-				}
 			}
+		}
 
-			parts := make([]string, 0, chosen.cfg.cols*2-1)
-			gapStr := strings.Repeat(" ", gap)
-			for i := 0; i < chosen.cfg.cols; i++ {
-				if i > 0 {
-					parts = append(parts, gapStr)
-				}
-				parts = append(parts, strings.Join(renderedCols[i], "\n"))
-			}
+		chosen := bestFit
+		fits := true
+		if chosen == nil {
+			chosen = bestAny
+			fits = false
+		}
 
-			body := lipgloss.JoinHorizontal(lipgloss.Top, parts...)
-			return lipgloss.NewStyle().Width(width).Height(bodyHeight).Render(body), fits
+		minWidth := chosen.cfg.colWidths[0]
+		for _, w := range chosen.cfg.colWidths[1:] {
+			minWidth = min(minWidth, w)
 		}
+		keyWidth := min(maxKeyWidth, max(6, minWidth-2))
 
-		renderHelpOverlay := func(width, height int, withFooter bool) string {
-			width = max(1, width)
-			height = max(1, height)
+		renderColumn := func(colWidth int, secs []helpSection) []string {
+			pad := lipgloss.NewStyle().Width(colWidth)
 
-			centered := lipgloss.NewStyle().Width(width).Align(lipgloss.Center)
+			renderItem := func(keys, desc string, keyW int, descW int) string {
+				k := helpDialogKeyStyle.Width(keyW).Render(keys)
+				d := helpDialogDescStyle.Width(descW).Render(desc)
+				return k + " " + d
+			}
 
-			modes := []helpRenderMode{
-				{
-					sections:       sectionsFull,
-					showByline:     true,
-					showFooter:     withFooter,
-					headerGapLines: 2,
-					maxCols:        3,
-					sectionSpacing: 1,
-					itemsPerLine:   1,
-				},
-				{
-					sections:       sectionsCompact,
-					showByline:     false,
-					showFooter:     withFooter,
-					headerGapLines: 1,
-					maxCols:        3,
-					sectionSpacing: 0,
-					itemsPerLine:   1,
-				},
-				{
-					sections:       sectionsTiny,
-					showByline:     false,
-					showFooter:     withFooter,
-					headerGapLines: 1,
-					maxCols:        3,
-					sectionSpacing: 0,
-					itemsPerLine:   2,
-				},
-				{
-					sections:       sectionsTiny,
-					showByline:     false,
-					showFooter:     false,
-					headerGapLines: 0,
-					maxCols:        2,
-					sectionSpacing: 0,
-					itemsPerLine:   2,
-				},
-			}
+			var lines []string
+			for si, sec := range secs {
+				lines = append(lines, pad.Render(helpDialogHeaderStyle.Render(sec.title)))
 
-			for _, mode := range modes {
-				headerParts := []string{aboutStyle.Render(centered.Render(versionLine))}
-				if mode.showByline {
-					headerParts = append(headerParts, dimTextStyle.Render(centered.Render("by elcuervo")))
-				}
-				if !mode.showFooter && withFooter {
-					headerParts = append(headerParts, dimTextStyle.Render(centered.Render("esc/q/? to close")))
-				}
-				header := strings.Join(headerParts, "\n")
+				if chosen.cfg.itemsPerLine == 1 {
+					descWidth := max(1, colWidth-keyWidth-1)
+					for _, item := range sec.items {
+						lines = append(lines, pad.Render(renderItem(item.keys, item.desc, keyWidth, descWidth)))
+					}
+				} else {
+					const innerGap = 2
+					leftW := (colWidth - innerGap) / 2
+					rightW := colWidth - innerGap - leftW
+
+					blockKeyW := min(keyWidth, max(4, leftW-2))
+					leftDescW := max(1, leftW-blockKeyW-1)
+					rightKeyW := min(keyWidth, max(4, rightW-2))
+					rightDescW := max(1, rightW-rightKeyW-1)
+
+					renderBlock := func(item helpItem, blockW int, kW int, dW int) string {
+						return lipgloss.NewStyle().Width(blockW).Render(renderItem(item.keys, item.desc, kW, dW))
+					}
 
-				footer := ""
-				footerLines := 0
-				if mode.showFooter && withFooter {
-					footer = dimTextStyle.Render(centered.Render("esc/q/? to close"))
-					footerLines = 1
+					for i := 0; i < len(sec.items); i += 2 {
+						left := renderBlock(sec.items[i], leftW, blockKeyW, leftDescW)
+						right := lipgloss.NewStyle().Width(rightW).Render("")
+						if i+1 < len(sec.items) {
+							right = renderBlock(sec.items[i+1], rightW, rightKeyW, rightDescW)
+						}
+						lines = append(lines, pad.Render(left+strings.Repeat(" ", innerGap)+right))
+					}
 				}
 
-				reservedLines := lipgloss.Height(header) + mode.headerGapLines + footerLines
-				if reservedLines >= height {
-					continue
+				if chosen.cfg.sectionSpacing > 0 && si != len(secs)-1 {
+					for i := 0; i < chosen.cfg.sectionSpacing; i++ {
+						lines = append(lines, pad.Render(""))
+					}
 				}
+			}
+			return lines
+		}
 
-				bodyHeight := max(1, height-reservedLines)
-				body, fits := renderHelpBody(width, bodyHeight, mode)
-				if !fits && mode.showFooter {
-					continue
-				}
+		renderedCols := make([][]string, chosen.cfg.cols)
+		maxLines := 0
+		for i := 0; i < chosen.cfg.cols; i++ {
+			renderedCols[i] = renderColumn(chosen.cfg.colWidths[i], chosen.assignments[i])
+			maxLines = max(maxLines, len(renderedCols[i]))
+		}
 
-				gap := strings.Repeat("\n", mode.headerGapLines)
-				if footer != "" {
-					return header + gap + body + "\n" + footer
-				}
-				return header + gap + body
+		for i := 0; i < chosen.cfg.cols; i++ {
+			pad := lipgloss.NewStyle().Width(chosen.cfg.colWidths[i])
+			for len(renderedCols[i]) < maxLines {
+				renderedCols[i] = append(renderedCols[i], pad.Render(""))
 			}
+		}
 
-			// Fallback: absolute minimum.
-			minHeader := aboutStyle.Render(centered.Render(versionLine))
-			minFooter := dimTextStyle.Render(centered.Render("esc/q/? to close"))
-			bodyHeight := max(1, height-lipgloss.Height(minHeader)-1)
-			body, _ := renderHelpBody(width, bodyHeight, helpRenderMode{
+		parts := make([]string, 0, chosen.cfg.cols*2-1)
+		gapStr := strings.Repeat(" ", gap)
+		for i := 0; i < chosen.cfg.cols; i++ {
+			if i > 0 {
+				parts = append(parts, gapStr)
+			}
+			parts = append(parts, strings.Join(renderedCols[i], "\n"))
+		}
+
+		body := lipgloss.JoinHorizontal(lipgloss.Top, parts...)
+		return lipgloss.NewStyle().Width(width).Height(bodyHeight).Render(body), fits
+	}
+
+	// The close and scroll keys follow the keymap
+	closeHint := "esc/" + m.keys.label(actionQuit) + "/" + m.keys.label(actionHelp) + " close • / filter • " +
+		m.keys.label(actionUp) + " " + m.keys.label(actionDown) + " scroll"
+
+	renderHelpOverlay := func(width, height int, withFooter bool) string {
+		width = max(1, width)
+		height = max(1, height)
+
+		centered := lipgloss.NewStyle().Width(width).Align(lipgloss.Center)
+
+		modes := []helpRenderMode{
+			{
+				sections:       sectionsFull,
+				showByline:     true,
+				showFooter:     withFooter,
+				headerGapLines: 2,
+				maxCols:        3,
+				sectionSpacing: 1,
+				itemsPerLine:   1,
+			},
+			{
+				sections:       sectionsCompact,
+				showByline:     false,
+				showFooter:     withFooter,
+				headerGapLines: 1,
+				maxCols:        3,
+				sectionSpacing: 0,
+				itemsPerLine:   1,
+			},
+			{
+				sections:       sectionsTiny,
+				showByline:     false,
+				showFooter:     withFooter,
+				headerGapLines: 1,
+				maxCols:        3,
+				sectionSpacing: 0,
+				itemsPerLine:   2,
+			},
+			{
 				sections:       sectionsTiny,
 				showByline:     false,
 				showFooter:     false,
 				headerGapLines: 0,
-				maxCols:        1,
+				maxCols:        2,
 				sectionSpacing: 0,
 				itemsPerLine:   2,
-			})
-			return minHeader + "\n" + body + "\n" + minFooter
+			},
+		}
+
+		for _, mode := range modes {
+			headerParts := []string{aboutStyle.Render(centered.Render(versionLine))}
+			if mode.showByline {
+				headerParts = append(headerParts, dimTextStyle.Render(centered.Render("by elcuervo")))
+			}
+			if !mode.showFooter && withFooter {
+				headerParts = append(headerParts, dimTextStyle.Render(centered.Render(closeHint)))
+			}
+			header := strings.Join(headerParts, "\n")
+
+			footer := ""
+			footerLines := 0
+			if mode.showFooter && withFooter {
+				footer = dimTextStyle.Render(centered.Render(closeHint))
+				footerLines = 1
+			}
+
+			reservedLines := lipgloss.Height(header) + mode.headerGapLines + footerLines
+			if reservedLines >= height {
+				continue
+			}
+
+			bodyHeight := max(1, height-reservedLines)
+			body, fits := renderHelpBody(width, bodyHeight, mode)
+			if !fits && mode.showFooter {
+				continue
+			}
+
+			gap := strings.Repeat("\n", mode.headerGapLines)
+			if footer != "" {
+				return header + gap + body + "\n" + footer
+			}
+			return header + gap + body
+		}
+
+		// Fallback: absolute minimum.
+		minHeader := aboutStyle.Render(centered.Render(versionLine))
+		minFooter := dimTextStyle.Render(centered.Render(closeHint))
+		bodyHeight := max(1, height-lipgloss.Height(minHeader)-1)
+		body, _ := renderHelpBody(width, bodyHeight, helpRenderMode{
+			sections:       sectionsTiny,
+			showByline:     false,
+			showFooter:     false,
+			headerGapLines: 0,
+			maxCols:        1,
+			sectionSpacing: 0,
+			itemsPerLine:   2,
+		})
+		return minHeader + "\n" + body + "\n" + minFooter
+	}
+
+	return renderHelpOverlay(width, height, true)
+}
+
+// aboutMaxScroll is how far the help modal can scroll
+func (m model) aboutMaxScroll() int {
+	_, width, height := m.aboutLayout()
+	return max(0, lipgloss.Height(m.aboutContent(width, height))-height)
+}
+
+// aboutView renders the help modal scrolled to aboutScroll, with the filter
+// prompt on top while filtering
+func (m model) aboutView() string {
+	boxed, width, height := m.aboutLayout()
+
+	lines := strings.Split(m.aboutContent(width, height), "\n")
+	offset := min(m.aboutScroll, max(0, len(lines)-height))
+	lines = lines[offset:min(len(lines), offset+height)]
+	content := strings.Join(lines, "\n")
+
+	if m.aboutFiltering || m.aboutFilter != "" {
+		filterLine := searchStyle.Render("/") + searchInputStyle.Render(m.aboutFilter)
+		if m.aboutFiltering {
+			filterLine += searchStyle.Render("_")
 		}
+		content = filterLine + "\n" + content
+		height++
+	}
+
+	if !boxed {
+		return content
+	}
+
+	content = lipgloss.NewStyle().Width(width).Height(height).Render(content)
+	box := aboutBoxStyle.Render(content)
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+}
+
+type helpItem struct {
+	keys string
+	desc string
+}
 
-		// Prefer a full-screen boxed dialog when there's enough room; otherwise fall back
-		// to a plain, scrollable viewport (still auto-layouts columns by width).
-		const boxFrameW = 6 // border (2) + horizontal padding (4)
-		const boxFrameH = 4 // border (2) + vertical padding (2)
-		useBox := m.windowWidth >= boxFrameW+40 && m.windowHeight >= boxFrameH+12
+type helpSection struct {
+	title string
+	items []helpItem
+}
+
+// filterHelpSections keeps bindings whose keys or description contain filter
+func filterHelpSections(sections []helpSection, filter string) []helpSection {
+	filter = strings.ToLower(filter)
 
-		if useBox {
-			innerW := max(1, m.windowWidth-boxFrameW)
-			innerH := max(1, m.windowHeight-boxFrameH)
-			content := renderHelpOverlay(innerW, innerH, true)
-			content = lipgloss.NewStyle().Width(innerW).Height(innerH).Render(content)
-			box := aboutBoxStyle.Render(content)
-			return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+	var filtered []helpSection
+	for _, sec := range sections {
+		var items []helpItem
+		for _, item := range sec.items {
+			if strings.Contains(strings.ToLower(item.desc), filter) || strings.Contains(strings.ToLower(item.keys), filter) {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			filtered = append(filtered, helpSection{title: sec.title, items: items})
 		}
+	}
+	return filtered
+}
 
-		vp := m.viewport
-		vp.Width = max(1, m.windowWidth)
-		vp.Height = max(1, m.windowHeight)
-		vp.YOffset = 0
-		vp.SetContent(renderHelpOverlay(vp.Width, vp.Height, true))
-		return vp.View()
+func (m model) View() string {
+	if noColor {
+		return ansi.Strip(m.view())
+	}
+	return m.view()
+}
+
+// view renders the screen for View
+func (m model) view() string {
+	if m.err != nil {
+		return fmt.Sprintf("Error: %v\n\nPress %s to quit.", m.err, m.keys.label(actionQuit))
+	}
+
+	if m.quitting {
+		return "Goodbye!\n"
+	}
+
+	if m.aboutOpen {
+		return m.aboutView()
 	}
 
 	if m.editing && m.editingTask != nil {
@@ -1708,8 +3218,8 @@ func (m model) View() string {
 	if m.deleting && m.deletingTask != nil {
 		titleLine := dangerStyle.Render("⚠ Delete Task")
 
-		taskPreview := renderTask(m.deletingTask.Done, m.deletingTask.Description)
-		questionLine := helpStyle.Render("This action cannot be undone.")
+		taskPreview := renderCheckboxLine(m.deletingTask.Done, m.deletingTask.Description, m.checkboxes)
+		questionLine := helpStyle.Render("Press u afterwards to undo the delete.")
 
 		contentWidth := int(float64(m.windowWidth) * 0.8)
 		if contentWidth < 40 {
@@ -1730,6 +3240,26 @@ func (m model) View() string {
 		return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
 	}
 
+	if m.completing != nil {
+		titleLine := confirmStyle.Render("✓ Mark Section Done")
+
+		name := m.completingSection
+		if name == "" {
+			name = "this section"
+		}
+		countLine := fmt.Sprintf("%d open task(s) in %s", len(m.completing), name)
+		questionLine := helpStyle.Render("A single u reopens the whole section.")
+
+		contentWidth := max(40, int(float64(m.windowWidth)*0.8))
+		centered := lipgloss.NewStyle().Width(contentWidth).Align(lipgloss.Center)
+
+		buttons := buttonNeutralStyle.Render("y Mark done") + "  " + buttonNeutralStyle.Render("n Cancel")
+
+		content := centered.Render(titleLine) + "\n\n" + centered.Render(countLine) + "\n\n" + centered.Render(questionLine) + "\n\n" + centered.Render(buttons)
+
+		return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, aboutBoxStyle.Render(content))
+	}
+
 	if m.adding && m.addingRef != nil {
 		titleLine := confirmStyle.Render("+ Add Task")
 
@@ -1748,66 +3278,55 @@ func (m model) View() string {
 		return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
 	}
 
-	// Build mode label if searching
-	modeLabel := ""
-	if m.searching {
-		if m.searchNavigating {
-			modeLabel = resultsModeStyle.Render("results")
-		} else {
-			modeLabel = searchModeStyle.Render("search")
-		}
+	if m.warningsOpen {
+		return m.warningsView()
 	}
 
-	// Render header line
-	titlePrefix := titleStyle.Render("ot")
-	var titleLine string
-
-	if m.tabsEnabled && len(m.tabs) > 1 {
-		arrow := barColor.Render(" → ")
-		titleLine = titlePrefix + arrow + m.renderTabBar()
-	} else {
-		arrow := barColor.Render(" → ")
-		titleLine = titlePrefix + arrow + titleNameStyle.Render(m.titleName)
+	if m.duplicates != nil {
+		return m.duplicatesView()
 	}
 
-	headerLines := []string{titleLine}
-
-	windowHeight := m.windowHeight
-	if windowHeight <= 0 {
-		windowHeight = defaultWindowHeight
+	if m.copyMenuOpen {
+		return m.copyMenuView()
 	}
 
-	headerHeight := 1
-	footerMinHeight := 1
-	if windowHeight < headerHeight+footerMinHeight+1 {
-		footerMinHeight = max(1, windowHeight-headerHeight-1)
+	if m.nextOpen {
+		return m.nextActionView()
 	}
 
-	targetContent := int(math.Round(float64(windowHeight) * 0.80))
-	available := windowHeight - headerHeight - footerMinHeight
-	if available < 1 {
-		available = 1
-	}
-	contentHeight := max(targetContent, available)
-	if contentHeight > windowHeight-headerHeight-footerMinHeight {
-		contentHeight = windowHeight - headerHeight - footerMinHeight
-	}
-	if contentHeight < 1 {
-		contentHeight = 1
+	if m.creating {
+		titleLine := confirmStyle.Render("+ New Task")
+
+		m.creatingFile.Width = m.inputWidth() - 8
+		m.creatingInput.Width = m.inputWidth() - 6
+
+		fileLine := fileStyle.Render("File: ") + m.creatingFile.View()
+		inputLine := "[ ] " + m.creatingInput.View()
+
+		helpLine := "enter save • tab switch field • esc cancel"
+
+		createContent := titleLine + "\n" + fileLine + "\n\n" + inputLine
+		createHelp := helpStyle.Render(helpLine)
+		box := aboutBoxStyle.Render(createContent + "\n\n" + createHelp)
+
+		return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
 	}
 
-	footerHeight := windowHeight - headerHeight - contentHeight
-	if footerHeight < footerMinHeight {
-		footerHeight = footerMinHeight
-		contentHeight = windowHeight - headerHeight - footerHeight
-		if contentHeight < 1 {
-			contentHeight = 1
+	// Build mode label if searching
+	modeLabel := ""
+	if m.searching {
+		if m.searchNavigating {
+			modeLabel = resultsModeStyle.Render("results")
+		} else {
+			modeLabel = searchModeStyle.Render("search")
 		}
 	}
 
-	headerView := headerBarStyle.Width(m.windowWidth).Render(strings.Join(headerLines, "\n"))
+	_, contentHeight, footerHeight := m.layoutHeights()
 
-	searchLine := helpBarKeyStyle.Render("/") + helpBarDescStyle.Render(" search")
+	headerView := m.headerView()
+
+	searchLine := helpBarKeyStyle.Render(m.keys.label(actionSearch)) + helpBarDescStyle.Render(" search")
 	if m.searching {
 		searchLabel := searchStyle.Render("/")
 		searchInput := searchInputStyle.Render(m.searchQuery)
@@ -1822,12 +3341,18 @@ func (m model) View() string {
 		lines := []viewLine{
 			{content: "No tasks found.", taskIndex: -1},
 		}
+		if !m.searching {
+			lines = append(lines, m.emptyStateLines()...)
+		}
 		viewportView, _, _, _ := m.buildViewport(lines, 0, contentHeight)
 		footerLine := m.renderHelpBar("")
+		if warning := m.warningLine(); warning != "" {
+			footerLine = m.renderFooterSplit(warning, "")
+		}
 		if m.searching {
 			footerLine = m.renderFooterSplit(searchLine, modeLabel)
 		}
-		footerView := buildFooterView([]string{footerLine}, footerHeight)
+		footerView := m.footerView(footerLine, footerHeight)
 		return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
 	}
 
@@ -1843,161 +3368,696 @@ func (m model) View() string {
 			if m.searching {
 				footerLine = m.renderFooterSplit(searchLine, modeLabel)
 			}
-			footerView := buildFooterView([]string{footerLine}, footerHeight)
+			footerView := m.footerView(footerLine, footerHeight)
 			return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
 		}
 
-		{
-			var lines []viewLine
+		lines, cursorLineIdx := m.listLines()
+		viewportView, _, _, _ := m.buildViewport(lines, cursorLineIdx, contentHeight)
+		footerLine := m.renderHelpBar(fmt.Sprintf("%d matches", len(tasks)))
+		if m.searching {
+			footerLine = m.renderFooterSplit(searchLine, modeLabel)
+		}
+		footerView := m.footerView(footerLine, footerHeight)
+		return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
+	}
 
-			query := strings.ToLower(m.searchQuery)
+	lines, cursorLineIdx := m.listLines()
+	viewportView, startLine, endLine, totalRenderedLines := m.buildViewport(lines, cursorLineIdx, contentHeight)
+	var scrollInfo string
+	if totalRenderedLines > contentHeight {
+		scrollInfo = fmt.Sprintf("%d-%d of %d", startLine+1, endLine, len(lines))
+	}
+	footerLine := m.renderHelpBar(scrollInfo)
+	if warning := m.warningLine(); warning != "" {
+		footerLine = m.renderFooterSplit(warning, helpBarInfoStyle.Render(scrollInfo))
+	}
+	if m.searching {
+		footerLine = m.renderFooterSplit(searchLine, modeLabel)
+	}
+	footerView := m.footerView(footerLine, footerHeight)
+	return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
+}
 
-			for i, task := range tasks {
-				cursor := " "
-				if m.cursor == i {
-					cursor = cursorStyle.Render(cursorCharacter)
-				}
+// headerView renders the title bar: the title or tabs and the active view
+// options
+func (m model) headerView() string {
+	titlePrefix := titleStyle.Render("ot")
+	var titleLine string
 
-				sectionName := m.taskToSection[task]
-				groupName := m.taskToGroup[task]
-				descLower := strings.ToLower(task.Description)
-
-				var matchInfo string
-				if strings.Contains(descLower, query) {
-					matchInfo = ""
-				} else if strings.Contains(strings.ToLower(sectionName), query) {
-					matchInfo = matchStyle.Render(fmt.Sprintf("→%s ", sectionName))
-				} else if strings.Contains(strings.ToLower(groupName), query) {
-					matchInfo = matchStyle.Render(fmt.Sprintf("→%s ", groupName))
-				}
+	if m.tabsEnabled && len(m.tabs) > 1 {
+		arrow := barColor.Render(" → ")
+		titleLine = titlePrefix + arrow + m.renderTabBar()
+	} else {
+		arrow := barColor.Render(" → ")
+		titleLine = titlePrefix + arrow + titleNameStyle.Render(m.titleName)
+	}
 
-				sectionInfo := ""
-				if sectionName != "" && matchInfo == "" {
-					sectionInfo = countStyle.Render(fmt.Sprintf("[%s] ", sectionName))
-				}
-				fileInfo := fileStyle.Render(fmt.Sprintf(" (%s:%d)", relPath(m.vaultPath, task.FilePath), task.LineNumber))
+	if m.showDone {
+		titleLine += dimTextStyle.Render(" +done")
+	}
 
-				line := renderTask(task.Done, task.Description)
+	if m.runtimeSort != "" {
+		titleLine += dimTextStyle.Render(" sort:" + m.runtimeSort)
+	}
 
-				if m.cursor == i {
-					line = selectedStyle.Render(line)
-				}
+	if m.reversed {
+		titleLine += dimTextStyle.Render(" reversed")
+	}
 
-				lines = append(lines, viewLine{
-					content:   fmt.Sprintf("%s%s%s%s%s", cursor, matchInfo, sectionInfo, line, fileInfo),
-					taskIndex: i,
-				})
-			}
+	if m.todayView {
+		titleLine += dimTextStyle.Render(" today")
+	}
 
-			viewportView, _, _, _ := m.buildViewport(lines, m.cursor, contentHeight)
-			footerLine := m.renderHelpBar(fmt.Sprintf("%d matches", len(tasks)))
-			if m.searching {
-				footerLine = m.renderFooterSplit(searchLine, modeLabel)
-			}
-			footerView := buildFooterView([]string{footerLine}, footerHeight)
-			return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
+	if m.focusSection != "" {
+		titleLine += dimTextStyle.Render(" focus:" + m.focusSection)
+	}
+
+	if m.refreshing {
+		titleLine += dimTextStyle.Render(" refreshing…")
+	}
+
+	headerLines := []string{titleLine}
+
+	return headerBarStyle.Width(m.windowWidth).Render(strings.Join(headerLines, "\n"))
+}
+
+// warningLine is the footer warning: the last error message, or a count of
+// files that failed to parse
+func (m model) warningLine() string {
+	if m.notice != "" {
+		return dimTextStyle.Render(m.notice)
+	}
+	if m.warning != "" {
+		return warningStyle.Render(m.warning)
+	}
+	if n := len(m.parseWarnings); n > 0 {
+		files := "files"
+		if n == 1 {
+			files = "file"
 		}
+		return warningStyle.Render(fmt.Sprintf("⚠ %d %s failed to parse", n, files)) + dimTextStyle.Render(" (W)")
 	}
+	return ""
+}
 
-	{
-		var lines []viewLine
-		taskIndex := 0
+// warningsView lists the files that failed to parse in a modal
+func (m model) warningsView() string {
+	titleLine := warningStyle.Render("⚠ Parse warnings")
 
-		for _, section := range m.sections {
-			if len(section.Tasks) == 0 {
-				continue
-			}
+	// Box borders and padding, title, blank lines and help line
+	maxItems := max(1, m.windowHeight-8)
 
-			if section.Name != "" {
-				count := len(section.Tasks)
-				countText := countStyle.Render(fmt.Sprintf(" (%d)", count))
-				lines = append(lines, viewLine{
-					content:   sectionStyle.Render(fmt.Sprintf("# %s", section.Name)) + countText,
-					taskIndex: -1,
-				})
+	var items []string
+	for i, w := range m.parseWarnings {
+		if i == maxItems {
+			items = append(items, dimTextStyle.Render(fmt.Sprintf("… %d more", len(m.parseWarnings)-maxItems)))
+			break
+		}
+		line := fileStyle.Render(relPath(m.vaultPath, w.File)) + " " + w.Err.Error()
+		items = append(items, truncateToWidth(line, m.inputWidth()))
+	}
+
+	helpLine := helpStyle.Render("esc/q/W close")
+	box := aboutBoxStyle.Render(titleLine + "\n\n" + strings.Join(items, "\n") + "\n\n" + helpLine)
+
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+}
+
+// duplicatesMsg carries the duplicated tasks found for the = view
+type duplicatesMsg struct {
+	vaultPath string
+	groups    []DuplicateGroup
+	err       error
+}
+
+// duplicatesCmd finds duplicated tasks across the vault in the background,
+// loading every task first if only the matches are known. The view opens
+// when the result arrives as a duplicatesMsg.
+func (m *model) duplicatesCmd() tea.Cmd {
+	tasks, vaultPath, opts, cache := m.allTasks, m.vaultPath, m.scan, m.cache
+
+	return func() tea.Msg {
+		msg := duplicatesMsg{vaultPath: vaultPath}
+		if tasks == nil {
+			scan := scanAndParse(context.Background(), vaultPath, opts, cache, nil)
+			if scan.Error != nil {
+				msg.err = scan.Error
+				return msg
 			}
+			tasks = scan.Tasks
+		}
+		msg.groups = findDuplicates(tasks)
+		return msg
+	}
+}
 
-			firstGroup := true
+// openDuplicates shows the duplicates found by duplicatesCmd
+func (m *model) openDuplicates(msg duplicatesMsg) {
+	// Results for a tab that is no longer active
+	if msg.vaultPath != m.vaultPath {
+		return
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		return
+	}
 
-			for _, group := range section.Groups {
-				if len(group.Tasks) == 0 {
-					continue
-				}
+	m.duplicates = msg.groups
+	if m.duplicates == nil {
+		m.duplicates = []DuplicateGroup{}
+	}
+}
+
+// trimLastRune drops the last character of s, keeping multibyte runes whole
+func trimLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+// isDigit reports whether key is a single 0-9 key
+func isDigit(key string) bool {
+	return len(key) == 1 && key[0] >= '0' && key[0] <= '9'
+}
 
-				if section.Query.GroupBy != "" && group.Name != "" {
-					if !firstGroup {
+// jumpPercent moves the cursor to the task percent of the way down the list,
+// rounding up like vim's N%
+func (m *model) jumpPercent(percent int) {
+	if len(m.tasks) == 0 {
+		return
+	}
+	percent = min(percent, 100)
+	m.cursor = max(0, (percent*len(m.tasks)+99)/100-1)
+	m.moreSelected = false
+}
+
+// nextAction is the task shown in the next action view, with its position
+// among the open tasks of all sections and their count
+func (m model) nextAction() (*Task, int, int) {
+	var tasks []*Task
+	for _, section := range m.sections {
+		tasks = append(tasks, section.Tasks...)
+	}
+
+	actions := nextActions(tasks)
+	if len(actions) == 0 {
+		return nil, 0, 0
+	}
+	i := (m.nextIndex%len(actions) + len(actions)) % len(actions)
+	return actions[i], i, len(actions)
+}
+
+// loadNextContext reads and renders the note lines around the current next
+// action once, when it changes, so drawing the view never reads the file
+func (m *model) loadNextContext() {
+	task, _, _ := m.nextAction()
+	if task == m.nextTask {
+		return
+	}
+
+	m.nextTask, m.nextContext = task, ""
+	if task == nil {
+		return
+	}
+	if context, err := noteContext(task, noteContextLines); err == nil && context != "" {
+		m.nextContext = renderMarkdown(context)
+	}
+}
+
+// nextActionView shows the next action alone with the lines of its note
+// around it
+func (m model) nextActionView() string {
+	titleLine := confirmStyle.Render("→ Next Action")
+	width := max(40, int(float64(m.windowWidth)*0.8))
+
+	task, i, total := m.nextAction()
+	if task == nil {
+		body := dimTextStyle.Render("Nothing left to do")
+		box := aboutBoxStyle.Render(titleLine + "\n\n" + body + "\n\n" + helpStyle.Render("esc close"))
+		return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+	}
+
+	taskLine := truncateToWidth(renderCheckboxLine(task.Done, task.Description, m.checkboxes)+dateBadges(task, time.Now()), width)
+	location := fileStyle.Render(fmt.Sprintf("%s:%d", displayPath(m.vaultPath, task.FilePath, m.fullPaths), task.LineNumber))
+	position := countStyle.Render(fmt.Sprintf(" • %d of %d", i+1, total))
+	body := taskLine + "\n" + location + position
+
+	// Box borders and padding, title, task, location, blank lines and help line
+	if maxLines := m.windowHeight - 10; maxLines > 0 && task == m.nextTask && m.nextContext != "" {
+		lines := strings.Split(m.nextContext, "\n")
+		lines = lines[:min(len(lines), maxLines)]
+		for j, line := range lines {
+			lines[j] = truncateToWidth(line, width)
+		}
+		body += "\n\n" + strings.Join(lines, "\n")
+	}
+
+	helpLine := helpStyle.Render("x done • j/k skip • esc close")
+	box := aboutBoxStyle.Render(titleLine + "\n\n" + body + "\n\n" + helpLine)
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+}
+
+// duplicatesView lists tasks found at more than one location in a modal
+func (m model) duplicatesView() string {
+	titleLine := warningStyle.Render("≡ Duplicate tasks")
+
+	// Box borders and padding, title, blank lines and help line
+	maxItems := max(1, m.windowHeight-8)
+
+	var items []string
+	if len(m.duplicates) == 0 {
+		items = append(items, dimTextStyle.Render("No duplicate tasks found"))
+	}
+	for _, group := range m.duplicates {
+		if len(items)+1+len(group.Tasks) > maxItems {
+			items = append(items, dimTextStyle.Render("… more"))
+			break
+		}
+		items = append(items, truncateToWidth(group.Text+countStyle.Render(fmt.Sprintf(" (%d)", len(group.Tasks))), m.inputWidth()))
+		for _, task := range group.Tasks {
+			location := fmt.Sprintf("%s:%d", displayPath(m.vaultPath, task.FilePath, m.fullPaths), task.LineNumber)
+			items = append(items, truncateToWidth("  "+fileStyle.Render(location), m.inputWidth()))
+		}
+	}
+
+	helpLine := helpStyle.Render("esc/q/= close")
+	box := aboutBoxStyle.Render(titleLine + "\n\n" + strings.Join(items, "\n") + "\n\n" + helpLine)
+
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+}
+
+// emptyStateLines explain an empty list: what each query asks for, how many
+// tasks it matched, and what to try next
+func (m model) emptyStateLines() []viewLine {
+	lines := []viewLine{{content: "", taskIndex: -1}}
+
+	for _, query := range m.queries {
+		summary := query.Summary()
+		if query.Name != "" {
+			summary = query.Name + ": " + summary
+		}
+		if m.allTasks != nil {
+			summary += fmt.Sprintf(" • 0 of %d tasks matched", len(m.allTasks))
+		}
+		lines = append(lines, viewLine{content: dimTextStyle.Render(summary), taskIndex: -1})
+	}
+
+	hint := m.keys.label(actionRefresh) + " refresh • " + m.keys.label(actionHelp) + " help"
+	lines = append(lines,
+		viewLine{content: "", taskIndex: -1},
+		viewLine{content: dimTextStyle.Render(hint), taskIndex: -1},
+	)
+
+	return lines
+}
+
+// copyMenuView lists the copy options for the selected task in a modal
+func (m model) copyMenuView() string {
+	titleLine := confirmStyle.Render("Copy")
+
+	var items []string
+	for _, opt := range copyOptions {
+		items = append(items, helpDialogKeyStyle.Render(opt.key)+" "+helpDialogDescStyle.Render(opt.label))
+	}
+
+	helpLine := helpStyle.Render("esc/q/Y close")
+	box := aboutBoxStyle.Render(titleLine + "\n\n" + strings.Join(items, "\n") + "\n\n" + helpLine)
+
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, box)
+}
+
+// layoutHeights splits the window into header, task list and footer heights.
+// The header and footer get exactly the lines they render and the list the
+// rest, so optional chrome like the status bar never clips the last row.
+func (m model) layoutHeights() (headerHeight, contentHeight, footerHeight int) {
+	windowHeight := m.windowHeight
+	if windowHeight <= 0 {
+		windowHeight = defaultWindowHeight
+	}
+
+	headerHeight, footerHeight = m.chromeHeights()
+
+	contentHeight = windowHeight - headerHeight - footerHeight
+	if contentHeight < 1 {
+		// Tiny windows keep a list row and the help line, dropping the rest
+		contentHeight = 1
+		footerHeight = max(1, windowHeight-headerHeight-contentHeight)
+	}
+
+	return headerHeight, contentHeight, footerHeight
+}
+
+// chromeHeights measures the lines rendered above and below the task list:
+// the title bar, then the help or search line and the optional status bar
+func (m model) chromeHeights() (header, footer int) {
+	header = lipgloss.Height(m.headerView())
+	footer = lipgloss.Height(m.renderHelpBar(""))
+	if m.statusBar {
+		footer += lipgloss.Height(m.statusLine())
+	}
+	return header, footer
+}
+
+// listLines builds the task list for the current mode and returns the index
+// of the line holding the cursor
+func (m model) listLines() ([]viewLine, int) {
+	if m.searching && m.searchQuery != "" {
+		return m.searchLines(m.activeTasks()), m.cursor
+	}
+
+	lines := m.sectionLines()
+	cursorLineIdx := 0
+
+	for i, line := range lines {
+		if m.moreSelected && line.more && line.moreAfter == m.cursor {
+			cursorLineIdx = i
+			break
+		}
+		if !m.moreSelected && line.taskIndex == m.cursor {
+			cursorLineIdx = i
+			break
+		}
+	}
+
+	return lines, cursorLineIdx
+}
+
+// searchLines renders one line per search result
+func (m model) searchLines(tasks []*Task) []viewLine {
+	var lines []viewLine
+
+	query := strings.ToLower(m.searchQuery)
+
+	for i, task := range tasks {
+		cursor := " "
+		if m.cursor == i {
+			cursor = cursorStyle.Render(cursorCharacter)
+		}
+
+		sectionName := m.taskToSection[task]
+		groupName := m.taskToGroup[task]
+		descLower := strings.ToLower(task.Description)
+
+		var matchInfo string
+		if strings.Contains(descLower, query) {
+			matchInfo = ""
+		} else if strings.Contains(strings.ToLower(sectionName), query) {
+			matchInfo = matchStyle.Render(fmt.Sprintf("→%s ", sectionName))
+		} else if strings.Contains(strings.ToLower(groupName), query) {
+			matchInfo = matchStyle.Render(fmt.Sprintf("→%s ", groupName))
+		}
+
+		sectionInfo := ""
+		if sectionName != "" && matchInfo == "" {
+			sectionInfo = countStyle.Render(fmt.Sprintf("[%s] ", sectionName))
+		}
+		prefix := cursor + matchInfo + sectionInfo
+
+		content := renderTask(task, m.renderContext(task, prefix, m.cursor == i, nil))
+
+		lines = append(lines, viewLine{
+			content:     content,
+			taskIndex:   i,
+			prefixWidth: lipgloss.Width(prefix),
+		})
+	}
+
+	return lines
+}
+
+// sectionLines renders all sections with their group headers and tasks.
+// Cursor movement reuses the cached lines and only renders the selected one.
+func (m model) sectionLines() []viewLine {
+	lines := slices.Clone(m.cachedSectionLines())
+
+	for i, line := range lines {
+		if m.moreSelected && line.more && line.moreAfter == m.cursor {
+			lines[i].content = moreLineContent(line.hidden, true)
+			break
+		}
+		if !m.moreSelected && line.task != nil && line.taskIndex == m.cursor {
+			lines[i].content = m.taskLineContent(line, true)
+			break
+		}
+	}
+
+	return lines
+}
+
+// cachedSectionLines returns the section lines without a selection,
+// rendering them only when the cache is missing, invalidated or stale
+func (m model) cachedSectionLines() []viewLine {
+	if m.lines == nil {
+		return m.renderSectionLines()
+	}
+
+	key := lineCacheKey{
+		width:   m.windowWidth,
+		short:   m.short,
+		wrap:    m.wrap,
+		noColor: noColor,
+		count:   len(m.sections),
+		day:     time.Now().YearDay(),
+	}
+	if len(m.sections) > 0 {
+		key.sections = &m.sections[0]
+	}
+
+	if !m.lines.valid || m.lines.key != key {
+		m.lines.lines = m.renderSectionLines()
+		m.lines.key = key
+		m.lines.valid = true
+	}
+
+	return m.lines.lines
+}
+
+// invalidateLines drops the cached section lines after tasks changed in
+// place, e.g. toggled, edited or reprioritized
+func (m *model) invalidateLines() {
+	if m.lines != nil {
+		m.lines.valid = false
+	}
+}
+
+// taskLineContent renders the task of line, with the cursor when selected
+func (m model) taskLineContent(line viewLine, selected bool) string {
+	cursor := " "
+	if selected {
+		cursor = cursorStyle.Render(cursorCharacter)
+	}
+	return renderTask(line.task, m.renderContext(line.task, line.indent+cursor, selected, line.query))
+}
+
+// moreLineContent renders the "… N more" line of a limited section
+func moreLineContent(hidden int, selected bool) string {
+	cursor := " "
+	if selected {
+		cursor = cursorStyle.Render(cursorCharacter)
+	}
+	return cursor + countStyle.Render(fmt.Sprintf("… %d more", hidden))
+}
+
+// renderSectionLines renders every section line with nothing selected
+func (m model) renderSectionLines() []viewLine {
+	var lines []viewLine
+	taskIndex := 0
+
+	for _, section := range m.sections {
+		if len(section.Tasks) == 0 {
+			continue
+		}
+
+		if section.Name != "" {
+			count := len(section.Tasks)
+			countText := countStyle.Render(fmt.Sprintf(" (%d)", count))
+			lines = append(lines, viewLine{
+				content:   sectionStyle.Render(fmt.Sprintf("# %s", section.Name)) + countText,
+				taskIndex: -1,
+			})
+		}
+
+		firstGroup := true
+
+		for gi, group := range section.Groups {
+			if len(group.Tasks) == 0 {
+				continue
+			}
+
+			if len(section.Query.GroupBy) > 0 {
+				for _, header := range groupHeaders(section.Groups, gi) {
+					// A blank line separates the outermost groups
+					if header.Level == 0 && !firstGroup {
 						lines = append(lines, viewLine{
 							content:   "",
 							taskIndex: -1,
 						})
 					}
 
-					count := len(group.Tasks)
-					countText := countStyle.Render(fmt.Sprintf(" (%d)", count))
+					countText := countStyle.Render(fmt.Sprintf(" (%d)", header.Count))
+					title := strings.Repeat("  ", header.Level+1) + strings.Repeat("#", header.Level+2) + " " + header.Name
 					lines = append(lines, viewLine{
-						content:   groupStyle.Render(fmt.Sprintf("  ## %s", group.Name)) + countText,
+						content:   groupStyle.Render(title) + countText,
 						taskIndex: -1,
 					})
 
 					firstGroup = false
 				}
+			}
 
-				for _, task := range group.Tasks {
-					indent := ""
-					if section.Query.GroupBy != "" && group.Name != "" {
-						indent = "  "
-					}
+			depths := nestingDepths(group.Tasks)
 
-					cursor := " "
-					if m.cursor == taskIndex {
-						cursor = cursorStyle.Render(cursorCharacter)
-					}
+			for i, task := range group.Tasks {
+				indent := strings.Repeat("  ", groupDepth(group)+depths[i])
 
-					fileInfo := ""
+				line := viewLine{
+					taskIndex:   taskIndex,
+					prefixWidth: lipgloss.Width(indent) + 1,
+					task:        task,
+					indent:      indent,
+					query:       section.Query,
+				}
+				line.content = m.taskLineContent(line, false)
+				lines = append(lines, line)
 
-					if section.Query.GroupBy != "filename" {
-						fileInfo = fileStyle.Render(fmt.Sprintf(" (%s:%d)", relPath(m.vaultPath, task.FilePath), task.LineNumber))
-					} else {
-						fileInfo = fileStyle.Render(fmt.Sprintf(" (:%d)", task.LineNumber))
-					}
+				taskIndex++
+			}
+		}
 
-					line := renderTask(task.Done, task.Description)
+		if section.Hidden > 0 {
+			lines = append(lines, viewLine{
+				content:   moreLineContent(section.Hidden, false),
+				taskIndex: -1,
+				more:      true,
+				moreAfter: taskIndex - 1,
+				hidden:    section.Hidden,
+			})
+		}
+	}
 
-					if m.cursor == taskIndex {
-						line = selectedStyle.Render(line)
-					}
+	return lines
+}
 
-					lines = append(lines, viewLine{
-						content:   fmt.Sprintf("%s%s%s%s", indent, cursor, line, fileInfo),
-						taskIndex: taskIndex,
-					})
+// nestingDepths returns how deep each task is nested under the ancestors
+// listed right above it, so subtasks render under their parent only when they
+// follow it. A subtask sorted away from its parent renders flat.
+func nestingDepths(tasks []*Task) []int {
+	depths := make([]int, len(tasks))
+	var chain []*Task
 
-					taskIndex++
-				}
-			}
+	for i, task := range tasks {
+		for len(chain) > 0 && !isAncestor(chain[len(chain)-1], task) {
+			chain = chain[:len(chain)-1]
 		}
+		depths[i] = len(chain)
+		chain = append(chain, task)
+	}
 
-		cursorLineIdx := 0
+	return depths
+}
 
-		for i, line := range lines {
-			if line.taskIndex == m.cursor {
-				cursorLineIdx = i
-				break
-			}
+// isAncestor reports whether ancestor is one of task's parents
+func isAncestor(ancestor, task *Task) bool {
+	for parent := task.Parent; parent != nil; parent = parent.Parent {
+		if parent == ancestor {
+			return true
 		}
+	}
+	return false
+}
 
-		viewportView, startLine, endLine, totalRenderedLines := m.buildViewport(lines, cursorLineIdx, contentHeight)
-		var scrollInfo string
-		if totalRenderedLines > contentHeight {
-			scrollInfo = fmt.Sprintf("%d-%d of %d", startLine+1, endLine, len(lines))
+// lineAtRow maps a screen row to the list line rendered there
+func (m model) lineAtRow(y int) (viewLine, bool) {
+	headerHeight, contentHeight, _ := m.layoutHeights()
+	if contentHeight < minVisibleHeight {
+		contentHeight = minVisibleHeight
+	}
+
+	row := y - headerHeight
+	if row < 0 || row >= contentHeight || len(m.tasks) == 0 {
+		return viewLine{}, false
+	}
+
+	lines, cursorLineIdx := m.listLines()
+	if len(lines) == 0 {
+		return viewLine{}, false
+	}
+
+	lineHeights, totalRenderedLines := viewLineHeights(lines)
+
+	startLine := 0
+	if totalRenderedLines > contentHeight {
+		startLine, _ = calculateVisibleRange(cursorLineIdx, lineHeights, contentHeight)
+	}
+
+	for i := startLine; i < len(lines); i++ {
+		if row < lineHeights[i] {
+			return lines[i], true
 		}
-		footerLine := m.renderHelpBar(scrollInfo)
-		if m.searching {
-			footerLine = m.renderFooterSplit(searchLine, modeLabel)
+		row -= lineHeights[i]
+	}
+
+	return viewLine{}, false
+}
+
+// renderContext describes a task row of the list. query is the task's
+// section query, nil in search results.
+func (m model) renderContext(task *Task, prefix string, selected bool, query *Query) renderContext {
+	ctx := renderContext{
+		prefix:   prefix,
+		location: fmt.Sprintf("%s:%d", displayPath(m.vaultPath, task.FilePath, m.fullPaths), task.LineNumber),
+		selected: selected,
+		short:    m.short,
+		wrap:     m.wrap,
+		width:    m.windowWidth,
+		now:      time.Now(),
+		glyphs:   m.checkboxes,
+	}
+
+	if query != nil {
+		ctx.short = ctx.short || query.Short
+		ctx.wrap = ctx.wrap || query.Wrap
+		ctx.hide = query.Hide
+		// The group header already names the file
+		if slices.Contains(query.GroupBy, "filename") {
+			ctx.location = fmt.Sprintf(":%d", task.LineNumber)
 		}
-		footerView := buildFooterView([]string{footerLine}, footerHeight)
-		return lipgloss.JoinVertical(lipgloss.Left, headerView, viewportView, footerView)
 	}
+
+	if task == m.hOffsetTask {
+		ctx.scroll = m.hOffset
+	}
+
+	return ctx
+}
+
+// scrollHorizontal moves the selected line by delta cells, restarting from
+// the left when the selection changed since the last scroll
+func (m *model) scrollHorizontal(delta int) {
+	tasks := m.activeTasks()
+	if len(tasks) == 0 || m.cursor >= len(tasks) {
+		return
+	}
+
+	if task := tasks[m.cursor]; task != m.hOffsetTask {
+		m.hOffsetTask = task
+		m.hOffset = 0
+	}
+
+	m.hOffset = max(0, m.hOffset+delta)
+}
+
+// viewLineHeights returns the rendered height of each line and their sum
+func viewLineHeights(lines []viewLine) ([]int, int) {
+	heights := make([]int, len(lines))
+	total := 0
+
+	for i, line := range lines {
+		heights[i] = 1 + strings.Count(line.content, "\n")
+		total += heights[i]
+	}
+
+	return heights, total
 }
 
 // calculateVisibleRange returns start/end indices for visible lines
@@ -2009,12 +4069,16 @@ func calculateVisibleRange(cursorLineIdx int, lineHeights []int, visibleHeight i
 	}
 
 	cursorPos := 0
+	cursorHeight := 1
 	totalHeight := 0
 
 	for i, h := range lineHeights {
 		if i < cursorLineIdx {
 			cursorPos += h
 		}
+		if i == cursorLineIdx {
+			cursorHeight = h
+		}
 		totalHeight += h
 	}
 
@@ -2022,7 +4086,8 @@ func calculateVisibleRange(cursorLineIdx int, lineHeights []int, visibleHeight i
 		return 0, totalLines
 	}
 
-	startRow := cursorPos - (visibleHeight - 1)
+	// Keep the whole cursor line in view, even when it wraps
+	startRow := cursorPos + cursorHeight - visibleHeight
 	if startRow < 0 {
 		startRow = 0
 	}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +19,22 @@ type FileChangeMsg struct {
 	Deleted bool
 }
 
+// defaultRefreshDebounce is how long file changes must settle before a
+// refresh, unless refresh_debounce_ms is set
+const defaultRefreshDebounce = 300 * time.Millisecond
+
+// ErrInvalidDebounce is returned for a refresh_debounce_ms that isn't positive
+var ErrInvalidDebounce = errors.New("refresh_debounce_ms must be positive")
+
+// ErrInvalidPollInterval is returned for a negative poll_interval
+var ErrInvalidPollInterval = errors.New("poll_interval must not be negative")
+
+// pollMsg carries the cached files found changed by a poll
+type pollMsg struct {
+	vaultPath string
+	changed   []string
+}
+
 // DebouncedRefreshMsg signals that enough time has passed to trigger a refresh
 type DebouncedRefreshMsg struct{}
 
@@ -24,15 +42,21 @@ type DebouncedRefreshMsg struct{}
 type Watcher struct {
 	watcher   *fsnotify.Watcher
 	vaultPath string
+	queryFile string
+	queryDir  string // Extra directory watched only for the query file
+	opts      scanOptions
 }
 
-// NewWatcher creates a new file watcher for the given vault path
-func NewWatcher(vaultPath string) (*Watcher, error) {
+// NewWatcher creates a new file watcher for the given vault path and,
+// when set, the query file (which may live outside the vault)
+func NewWatcher(vaultPath string, queryFile string, opts scanOptions) (*Watcher, error) {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
+	watcher := &Watcher{watcher: w, vaultPath: vaultPath, opts: opts}
+
 	info, err := os.Stat(vaultPath)
 	if err == nil && !info.IsDir() {
 		// Watch the file itself and its parent directory to catch updates and renames.
@@ -41,22 +65,104 @@ func NewWatcher(vaultPath string) (*Watcher, error) {
 		if parent != "" && parent != "." {
 			_ = w.Add(parent)
 		}
-		return &Watcher{watcher: w, vaultPath: vaultPath}, nil
+	} else {
+		watcher.addTree(vaultPath)
+	}
+
+	watcher.watchQueryFile(queryFile)
+
+	return watcher, nil
+}
+
+// watchQueryFile watches the query file's directory unless it's already
+// covered by the vault watch
+func (w *Watcher) watchQueryFile(queryFile string) {
+	if queryFile == "" {
+		return
+	}
+
+	w.queryFile = filepath.Clean(queryFile)
+	dir := filepath.Dir(w.queryFile)
+
+	for _, path := range w.watcher.WatchList() {
+		if path == dir {
+			return
+		}
+	}
+
+	if err := w.watcher.Add(dir); err == nil {
+		w.queryDir = dir
+	}
+}
+
+// fileChange converts an event into a FileChangeMsg, giving the query file
+// precedence so it's reported once even when inside the vault
+func (w *Watcher) fileChange(event fsnotify.Event) (FileChangeMsg, bool) {
+	path := filepath.Clean(event.Name)
+
+	if w.queryFile != "" && path == w.queryFile {
+		if event.Op == fsnotify.Chmod {
+			return FileChangeMsg{}, false
+		}
+		deleted := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+		return FileChangeMsg{Path: w.queryFile, Deleted: deleted}, true
+	}
+
+	// Other files next to an out-of-vault query file are not ours
+	if w.queryDir != "" && filepath.Dir(path) == w.queryDir {
+		return FileChangeMsg{}, false
 	}
 
-	// Walk vault and add all directories (skip hidden ones)
-	filepath.Walk(vaultPath, func(path string, info os.FileInfo, err error) error {
+	return fileChangeFromEvent(event, w.opts)
+}
+
+// addTree walks root and watches every directory in it (skip hidden ones)
+func (w *Watcher) addTree(root string) {
+	walkTree(root, w.opts, func(path string, info os.FileInfo, err error) error {
 		if err != nil || !info.IsDir() {
 			return nil
 		}
-		if strings.HasPrefix(info.Name(), ".") && path != vaultPath {
+		if strings.HasPrefix(info.Name(), ".") && path != w.vaultPath {
 			return filepath.SkipDir
 		}
-		w.Add(path)
+		w.watcher.Add(path)
 		return nil
 	})
+}
 
-	return &Watcher{watcher: w, vaultPath: vaultPath}, nil
+// handleDirEvent keeps the watch list in sync when directories are created or
+// removed, reporting whether the event referred to a directory
+func (w *Watcher) handleDirEvent(event fsnotify.Event) bool {
+	// Only the query file matters in its out-of-vault directory
+	if w.queryDir != "" && filepath.Dir(filepath.Clean(event.Name)) == w.queryDir {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		info, err := os.Stat(event.Name)
+		if err != nil || !info.IsDir() {
+			return false
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			return true
+		}
+		w.addTree(event.Name)
+		return true
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		removed := false
+		prefix := event.Name + string(filepath.Separator)
+		for _, path := range w.watcher.WatchList() {
+			if path == event.Name || strings.HasPrefix(path, prefix) {
+				w.watcher.Remove(path)
+				removed = true
+			}
+		}
+		return removed
+	}
+
+	return false
 }
 
 // WatchCmd returns a BubbleTea command that listens for file changes
@@ -69,13 +175,18 @@ func (w *Watcher) WatchCmd() tea.Cmd {
 					return nil
 				}
 
-				// Only care about .md files
-				if !strings.HasSuffix(strings.ToLower(event.Name), ".md") {
-					continue
+				if w.handleDirEvent(event) {
+					if strings.HasPrefix(filepath.Base(event.Name), ".") {
+						continue
+					}
+					// Files may have moved in or out along with the directory
+					deleted := !event.Has(fsnotify.Create)
+					return FileChangeMsg{Path: event.Name, Deleted: deleted}
 				}
 
-				deleted := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
-				return FileChangeMsg{Path: event.Name, Deleted: deleted}
+				if msg, ok := w.fileChange(event); ok {
+					return msg
+				}
 
 			case _, ok := <-w.watcher.Errors:
 				if !ok {
@@ -87,6 +198,31 @@ func (w *Watcher) WatchCmd() tea.Cmd {
 	}
 }
 
+// fileChangeFromEvent converts a raw fsnotify event into a FileChangeMsg,
+// reporting false for events that shouldn't trigger a refresh or aren't on
+// a file opts scans
+func fileChangeFromEvent(event fsnotify.Event, opts scanOptions) (FileChangeMsg, bool) {
+	name := strings.ToLower(event.Name)
+
+	// Temp files from atomic saves surface as a create on the real file
+	if strings.HasSuffix(name, ".tmp") {
+		return FileChangeMsg{}, false
+	}
+
+	// Only care about task files
+	if !opts.hasTaskExtension(name) {
+		return FileChangeMsg{}, false
+	}
+
+	// Permission changes don't affect content
+	if event.Op == fsnotify.Chmod {
+		return FileChangeMsg{}, false
+	}
+
+	deleted := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+	return FileChangeMsg{Path: event.Name, Deleted: deleted}, true
+}
+
 // Close stops the watcher
 func (w *Watcher) Close() error {
 	return w.watcher.Close()
@@ -105,6 +241,27 @@ func NewDebouncer(d time.Duration) *Debouncer {
 	return &Debouncer{duration: d}
 }
 
+// refreshDebounce converts the refresh_debounce_ms option to a duration.
+// Unset (0) and invalid values use defaultRefreshDebounce.
+func refreshDebounce(ms int) (time.Duration, error) {
+	if ms == 0 {
+		return defaultRefreshDebounce, nil
+	}
+	if ms < 0 {
+		return defaultRefreshDebounce, fmt.Errorf("%w: %d", ErrInvalidDebounce, ms)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// pollInterval converts the poll_interval option, in seconds, to a duration.
+// Unset (0) and invalid values turn polling off.
+func pollInterval(seconds int) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidPollInterval, seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
 // SetProgram sets the BubbleTea program to send messages to
 func (d *Debouncer) SetProgram(p *tea.Program) {
 	d.program = p